@@ -0,0 +1,268 @@
+// Package archive exports, imports and copies backend data.
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/params"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+)
+
+// children contains nested resource names by parent resource.
+var children = map[string][]string{
+	"":           {"buckets"},
+	"bucket":     {"groups", "collections"},
+	"collection": {"records"},
+}
+
+// Entry is a single archived object.
+type Entry struct {
+	Path        riposo.Path          `json:"path"`
+	Data        *schema.Object       `json:"data"`
+	ModTime     riposo.Epoch         `json:"last_modified"`
+	Deleted     bool                 `json:"deleted,omitempty"`
+	Permissions schema.PermissionSet `json:"permissions,omitempty"`
+}
+
+// Object returns the normalised object.
+func (e *Entry) Object() *schema.Object {
+	obj := new(schema.Object)
+	if e.Data != nil && !e.Deleted {
+		obj.Extra = e.Data.Extra
+	}
+	obj.ID = e.Path.ObjectID()
+	obj.ModTime = e.ModTime
+	obj.Deleted = e.Deleted
+	obj.Norm()
+	return obj
+}
+
+func (e *Entry) validate() error {
+	if e.Path == "" || e.Path.IsNode() || e.Path.ObjectID() == "" {
+		return fmt.Errorf("invalid path %q", e.Path)
+	}
+	return nil
+}
+
+// --------------------------------------------------------------------
+
+// Export writes root and all nested objects, including tombstones, to w. Root
+// may either be an object path, a node path or blank to export everything.
+func Export(w io.Writer, txn *api.Txn, root riposo.Path) (int, error) {
+	ex := &exporter{txn: txn, enc: json.NewEncoder(w)}
+
+	switch {
+	case root == "":
+		err := ex.walk(root)
+		return ex.n, err
+	case root.IsNode():
+		err := ex.node(root)
+		return ex.n, err
+	}
+
+	obj, err := txn.Store.Get(root, false)
+	if err != nil {
+		return 0, err
+	}
+	err = ex.write(root.WithObjectID("*"), []*schema.Object{obj})
+	return ex.n, err
+}
+
+type exporter struct {
+	txn *api.Txn
+	enc *json.Encoder
+	n   int
+}
+
+func (ex *exporter) walk(parent riposo.Path) error {
+	name := ""
+	if parent != "" {
+		name = parent.ResourceName()
+	}
+
+	for _, child := range children[name] {
+		if err := ex.node(riposo.JoinPath(parent.String(), child)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ex *exporter) node(path riposo.Path) error {
+	objs, err := ex.txn.Store.ListAll(path, storage.ListOptions{
+		Include: storage.IncludeAll,
+		Sort:    []params.SortOrder{{Field: "last_modified"}},
+	})
+	if err != nil {
+		return err
+	}
+	if len(objs) == 0 {
+		return nil
+	}
+
+	return ex.write(path, objs)
+}
+
+func (ex *exporter) write(node riposo.Path, objs []*schema.Object) error {
+	paths := make([]riposo.Path, 0, len(objs))
+	for _, obj := range objs {
+		paths = append(paths, node.WithObjectID(obj.ID))
+	}
+
+	sets, err := ex.txn.Perms.GetAllPermissions(paths)
+	if err != nil {
+		return err
+	}
+
+	for i, obj := range objs {
+		entry := &Entry{
+			Path:    paths[i],
+			Data:    obj,
+			ModTime: obj.ModTime,
+			Deleted: obj.Deleted,
+		}
+		if !obj.Deleted {
+			entry.Permissions = sets[i]
+		}
+		if err := ex.enc.Encode(entry); err != nil {
+			return err
+		}
+		ex.n++
+	}
+
+	for i, obj := range objs {
+		if !obj.Deleted {
+			if err := ex.walk(paths[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// --------------------------------------------------------------------
+
+var errNoRestore = errors.New("storage backend does not support preserving timestamps")
+
+// ImportOptions configure imports.
+type ImportOptions struct {
+	// PreserveTimestamps retains the original last_modified epochs.
+	PreserveTimestamps bool
+	// SkipExisting skips objects that already exist instead of failing.
+	SkipExisting bool
+}
+
+// ImportStats contain import statistics.
+type ImportStats struct {
+	Created int
+	Skipped int
+}
+
+// Import reads entries from r and recreates them within txn.
+func Import(r io.Reader, txn *api.Txn, opt *ImportOptions) (*ImportStats, error) {
+	if opt == nil {
+		opt = new(ImportOptions)
+	}
+
+	var restorer storage.Restorer
+	if opt.PreserveTimestamps {
+		rs, ok := txn.Store.(storage.Restorer)
+		if !ok {
+			return nil, errNoRestore
+		}
+		restorer = rs
+	}
+
+	stats := new(ImportStats)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return stats, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := entry.validate(); err != nil {
+			return stats, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		created, err := importEntry(txn, restorer, &entry, opt)
+		if err != nil {
+			return stats, fmt.Errorf("line %d: %s: %w", line, entry.Path, err)
+		}
+		if created {
+			stats.Created++
+		} else {
+			stats.Skipped++
+		}
+	}
+	return stats, scanner.Err()
+}
+
+func importEntry(txn *api.Txn, restorer storage.Restorer, entry *Entry, opt *ImportOptions) (bool, error) {
+	if exists, err := txn.Store.Exists(entry.Path); err != nil {
+		return false, err
+	} else if exists && (opt.SkipExisting || entry.Deleted) {
+		return false, nil
+	} else if exists {
+		return false, storage.ErrObjectExists
+	}
+
+	node := entry.Path.WithObjectID("*")
+	obj := entry.Object()
+
+	// store object
+	if restorer != nil {
+		if err := restorer.Restore(node, obj); err != nil {
+			return false, err
+		}
+	} else {
+		if err := txn.Store.Create(node, obj); err != nil {
+			return false, err
+		}
+		if obj.Deleted {
+			if _, err := txn.Store.Delete(entry.Path); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// tombstones have no permissions
+	if obj.Deleted {
+		return true, nil
+	}
+
+	// store permissions
+	if len(entry.Permissions) != 0 {
+		if err := txn.Perms.CreatePermissions(entry.Path, entry.Permissions); err != nil {
+			return false, err
+		}
+	}
+
+	// rebuild group principals
+	if entry.Path.ResourceName() == "group" {
+		var group struct {
+			Members []string `json:"members"`
+		}
+		if err := obj.DecodeExtra(&group); err != nil {
+			return false, err
+		}
+		if len(group.Members) != 0 {
+			if err := txn.Perms.AddUserPrincipal(entry.Path.String(), group.Members); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
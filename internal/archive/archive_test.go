@@ -0,0 +1,156 @@
+package archive_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/riposo/riposo/internal/archive"
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+)
+
+var _ = Describe("Export", func() {
+	var txn *api.Txn
+
+	BeforeEach(func() {
+		txn = mock.Txn()
+		seed(txn)
+	})
+
+	AfterEach(func() {
+		Expect(txn.Rollback()).To(Succeed())
+	})
+
+	It("exports everything", func() {
+		buf := new(bytes.Buffer)
+		Expect(archive.Export(buf, txn, "")).To(Equal(6))
+		Expect(paths(buf)).To(Equal([]string{
+			"/buckets/foo",
+			"/buckets/bar",
+			"/buckets/foo/groups/g1",
+			"/buckets/foo/collections/c1",
+			"/buckets/foo/collections/c1/records/r1",
+			"/buckets/foo/collections/c1/records/r2",
+		}))
+	})
+
+	It("exports sub-trees", func() {
+		buf := new(bytes.Buffer)
+		Expect(archive.Export(buf, txn, "/buckets/foo/collections/c1")).To(Equal(3))
+		Expect(strings.Split(strings.TrimSpace(buf.String()), "\n")).To(Equal([]string{
+			`{"path":"/buckets/foo/collections/c1","data":{"id":"c1","last_modified":1515151515677},"last_modified":1515151515677,"permissions":{"read":["system.Everyone"]}}`,
+			`{"path":"/buckets/foo/collections/c1/records/r1","data":{"id":"r1","last_modified":1515151515677,"n":1},"last_modified":1515151515677}`,
+			`{"path":"/buckets/foo/collections/c1/records/r2","data":{"id":"r2","last_modified":1515151515679,"deleted":true},"last_modified":1515151515679,"deleted":true}`,
+		}))
+
+		buf.Reset()
+		Expect(archive.Export(buf, txn, "/buckets/foo/collections/c1/records/*")).To(Equal(2))
+
+		buf.Reset()
+		_, err := archive.Export(buf, txn, "/buckets/foo/collections/c2")
+		Expect(err).To(MatchError(storage.ErrNotFound))
+	})
+})
+
+var _ = Describe("Import", func() {
+	var src, txn *api.Txn
+	var data *bytes.Buffer
+
+	BeforeEach(func() {
+		src = mock.Txn()
+		seed(src)
+
+		data = new(bytes.Buffer)
+		Expect(archive.Export(data, src, "")).To(Equal(6))
+
+		txn = mock.Txn()
+	})
+
+	AfterEach(func() {
+		Expect(src.Rollback()).To(Succeed())
+		Expect(txn.Rollback()).To(Succeed())
+	})
+
+	It("imports", func() {
+		Expect(archive.Import(data, txn, nil)).To(Equal(&archive.ImportStats{Created: 6}))
+		Expect(txn.Store.Exists("/buckets/foo/collections/c1/records/r1")).To(BeTrue())
+		Expect(txn.Store.Exists("/buckets/foo/collections/c1/records/r2")).To(BeFalse())
+		Expect(txn.Store.CountAll("/buckets/foo/collections/c1/records/*", storage.CountOptions{})).To(Equal(int64(1)))
+		Expect(txn.Store.ListAll("/buckets/foo/collections/c1/records/*", storage.ListOptions{Include: storage.IncludeAll})).To(HaveLen(2))
+
+		Expect(txn.Perms.GetPermissions("/buckets/foo")).To(Equal(schema.PermissionSet{"write": {"account:alice"}}))
+		Expect(txn.Perms.GetUserPrincipals("account:bob")).To(ContainElement("/buckets/foo/groups/g1"))
+	})
+
+	It("preserves timestamps", func() {
+		Expect(archive.Import(data, txn, &archive.ImportOptions{PreserveTimestamps: true})).To(Equal(&archive.ImportStats{Created: 6}))
+
+		for _, path := range []riposo.Path{"/buckets/foo", "/buckets/foo/groups/g1", "/buckets/foo/collections/c1/records/r1"} {
+			exp, err := src.Store.Get(path, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(txn.Store.Get(path, false)).To(Equal(exp))
+		}
+	})
+
+	It("fails on existing objects", func() {
+		Expect(txn.Store.Create("/buckets/*", &schema.Object{ID: "bar"})).To(Succeed())
+
+		stats, err := archive.Import(data, txn, nil)
+		Expect(err).To(MatchError(`line 2: /buckets/bar: object already exists`))
+		Expect(stats).To(Equal(&archive.ImportStats{Created: 1}))
+	})
+
+	It("skips existing objects", func() {
+		Expect(txn.Store.Create("/buckets/*", &schema.Object{ID: "bar"})).To(Succeed())
+		Expect(archive.Import(data, txn, &archive.ImportOptions{SkipExisting: true})).To(Equal(&archive.ImportStats{Created: 5, Skipped: 1}))
+	})
+
+	It("rejects invalid input", func() {
+		_, err := archive.Import(strings.NewReader(`{"path":"/buckets/*"}`), txn, nil)
+		Expect(err).To(MatchError(`line 1: invalid path "/buckets/*"`))
+
+		_, err = archive.Import(strings.NewReader("\n{"), txn, nil)
+		Expect(err).To(MatchError(`line 2: unexpected end of JSON input`))
+	})
+})
+
+func seed(txn *api.Txn) {
+	for _, ent := range []struct {
+		Path  riposo.Path
+		Obj   *schema.Object
+		Perms schema.PermissionSet
+	}{
+		{"/buckets/*", &schema.Object{ID: "foo"}, schema.PermissionSet{"write": {"account:alice"}}},
+		{"/buckets/*", &schema.Object{ID: "bar"}, nil},
+		{"/buckets/foo/groups/*", &schema.Object{ID: "g1", Extra: []byte(`{"members":["account:bob"]}`)}, nil},
+		{"/buckets/foo/collections/*", &schema.Object{ID: "c1"}, schema.PermissionSet{"read": {riposo.Everyone}}},
+		{"/buckets/foo/collections/c1/records/*", &schema.Object{ID: "r1", Extra: []byte(`{"n":1}`)}, nil},
+		{"/buckets/foo/collections/c1/records/*", &schema.Object{ID: "r2", Extra: []byte(`{"n":2}`)}, nil},
+	} {
+		Expect(txn.Store.Create(ent.Path, ent.Obj)).To(Succeed())
+		if ent.Perms != nil {
+			Expect(txn.Perms.CreatePermissions(ent.Path.WithObjectID(ent.Obj.ID), ent.Perms)).To(Succeed())
+		}
+	}
+	Expect(txn.Store.Delete("/buckets/foo/collections/c1/records/r2")).To(HaveField("ID", "r2"))
+}
+
+func paths(buf *bytes.Buffer) []string {
+	var res []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		res = append(res, strings.SplitN(strings.TrimPrefix(line, `{"path":"`), `"`, 2)[0])
+	}
+	return res
+}
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "internal/archive")
+}
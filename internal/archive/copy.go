@@ -0,0 +1,339 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/riposo/riposo/pkg/conn/cache"
+	"github.com/riposo/riposo/pkg/conn/permission"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/params"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+)
+
+var errNoScan = errors.New("source backend does not support scanning")
+
+// readOnly options are used for transactions on the source.
+var readOnly = &riposo.TxOptions{ReadOnly: true}
+
+// CopyOptions configure copies.
+type CopyOptions struct {
+	// PageSize is the number of entries copied per transaction.
+	// Default: 1000
+	PageSize int
+}
+
+func (o *CopyOptions) norm() *CopyOptions {
+	var oo CopyOptions
+	if o != nil {
+		oo = *o
+	}
+	if oo.PageSize < 1 {
+		oo.PageSize = 1000
+	}
+	return &oo
+}
+
+// CopyStats contain copy statistics.
+type CopyStats struct {
+	Copied  int
+	Skipped int
+}
+
+// --------------------------------------------------------------------
+
+// CopyStorage copies all objects, including tombstones, from src to dst and
+// retains their original timestamps. Every page of objects is committed
+// separately and objects which already exist in dst are skipped, which allows
+// to resume interrupted copies. Object counts are verified on completion.
+func CopyStorage(ctx context.Context, src, dst storage.Backend, opt *CopyOptions) (*CopyStats, error) {
+	sc := &storageCopier{ctx: ctx, src: src, dst: dst, opt: opt.norm()}
+	if err := sc.walk(""); err != nil {
+		return &sc.stats, err
+	}
+	return &sc.stats, sc.verify()
+}
+
+type storageCopier struct {
+	ctx      context.Context
+	src, dst storage.Backend
+	opt      *CopyOptions
+	nodes    []riposo.Path
+	stats    CopyStats
+}
+
+func (c *storageCopier) walk(parent riposo.Path) error {
+	name := ""
+	if parent != "" {
+		name = parent.ResourceName()
+	}
+
+	for _, child := range children[name] {
+		if err := c.node(riposo.JoinPath(parent.String(), child)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *storageCopier) node(node riposo.Path) error {
+	c.nodes = append(c.nodes, node)
+
+	var last *schema.Object
+	for {
+		objs, err := c.page(node, last)
+		if err != nil {
+			return err
+		}
+
+		for _, obj := range objs {
+			if !obj.Deleted {
+				if err := c.walk(node.WithObjectID(obj.ID)); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(objs) < c.opt.PageSize {
+			return nil
+		}
+		last = objs[len(objs)-1]
+	}
+}
+
+func (c *storageCopier) page(node riposo.Path, last *schema.Object) ([]*schema.Object, error) {
+	opt := storage.ListOptions{
+		Include: storage.IncludeAll,
+		Sort:    []params.SortOrder{{Field: "id"}},
+		Limit:   c.opt.PageSize,
+	}
+	if last != nil {
+		opt.Pagination = params.ConditionSet{{
+			{Field: "id", Operator: params.OperatorGT, Values: []schema.Value{last.Get("id")}},
+		}}
+	}
+
+	// fetch page from source
+	stx, err := c.src.Begin(c.ctx, readOnly)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := stx.ListAll(node, opt)
+	_ = stx.Rollback()
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 {
+		return nil, nil
+	}
+
+	// restore in destination
+	dtx, err := c.dst.Begin(c.ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, ok := dtx.(storage.Restorer)
+	if !ok {
+		_ = dtx.Rollback()
+		return nil, errNoRestore
+	}
+
+	var copied, skipped int
+	for _, obj := range objs {
+		path := node.WithObjectID(obj.ID)
+		if exists, err := dtx.Exists(path); err != nil {
+			_ = dtx.Rollback()
+			return nil, err
+		} else if exists {
+			skipped++
+			continue
+		}
+
+		if err := rs.Restore(node, obj.Copy()); err != nil {
+			_ = dtx.Rollback()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		copied++
+	}
+
+	if err := dtx.Commit(); err != nil {
+		return nil, err
+	}
+	c.stats.Copied += copied
+	c.stats.Skipped += skipped
+	return objs, nil
+}
+
+func (c *storageCopier) verify() error {
+	stx, err := c.src.Begin(c.ctx, readOnly)
+	if err != nil {
+		return err
+	}
+	defer stx.Rollback()
+
+	dtx, err := c.dst.Begin(c.ctx, readOnly)
+	if err != nil {
+		return err
+	}
+	defer dtx.Rollback()
+
+	for _, node := range c.nodes {
+		want, err := stx.CountAll(node, storage.CountOptions{})
+		if err != nil {
+			return err
+		}
+
+		got, err := dtx.CountAll(node, storage.CountOptions{})
+		if err != nil {
+			return err
+		}
+
+		if got != want {
+			return fmt.Errorf("count mismatch on %s: %d in source, %d in destination", node, want, got)
+		}
+	}
+	return nil
+}
+
+// --------------------------------------------------------------------
+
+// CopyPermissions copies all user principals and permissions from src to dst.
+// Entries are merged with existing permissions in dst, which allows to resume
+// interrupted copies.
+func CopyPermissions(ctx context.Context, src, dst permission.Backend, opt *CopyOptions) (*CopyStats, error) {
+	stx, err := src.Begin(ctx, readOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer stx.Rollback()
+
+	sc, ok := stx.(permission.Scanner)
+	if !ok {
+		return nil, errNoScan
+	}
+
+	bw := &batchWriter{
+		begin: func() (txn, error) { return dst.Begin(ctx, nil) },
+		limit: opt.norm().PageSize,
+	}
+	defer bw.Rollback()
+
+	if err := sc.ScanUserPrincipals(func(userID string, principals []string) error {
+		return bw.Do(func(tx txn) error {
+			for _, principal := range principals {
+				if err := tx.(permission.Transaction).AddUserPrincipal(principal, []string{userID}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}); err != nil {
+		return &bw.stats, err
+	}
+
+	if err := sc.ScanPermissions(func(path riposo.Path, set schema.PermissionSet) error {
+		return bw.Do(func(tx txn) error {
+			return tx.(permission.Transaction).CreatePermissions(path, set)
+		})
+	}); err != nil {
+		return &bw.stats, err
+	}
+
+	return &bw.stats, bw.Commit()
+}
+
+// CopyCache copies all keys that have not expired from src to dst.
+func CopyCache(ctx context.Context, src, dst cache.Backend, opt *CopyOptions) (*CopyStats, error) {
+	stx, err := src.Begin(ctx, readOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer stx.Rollback()
+
+	sc, ok := stx.(cache.Scanner)
+	if !ok {
+		return nil, errNoScan
+	}
+
+	bw := &batchWriter{
+		begin: func() (txn, error) { return dst.Begin(ctx, nil) },
+		limit: opt.norm().PageSize,
+	}
+	defer bw.Rollback()
+
+	if err := sc.Scan(func(key string, val []byte, exp time.Time) error {
+		return bw.Do(func(tx txn) error {
+			return tx.(cache.Transaction).Set(key, val, exp)
+		})
+	}); err != nil {
+		return &bw.stats, err
+	}
+
+	return &bw.stats, bw.Commit()
+}
+
+// --------------------------------------------------------------------
+
+type txn interface {
+	Commit() error
+	Rollback() error
+}
+
+// batchWriter applies writes to destination transactions and commits them
+// in batches.
+type batchWriter struct {
+	begin func() (txn, error)
+	limit int
+	tx    txn
+	n     int
+	stats CopyStats
+}
+
+// Do applies a write.
+func (w *batchWriter) Do(fn func(txn) error) error {
+	if w.tx == nil {
+		tx, err := w.begin()
+		if err != nil {
+			return err
+		}
+		w.tx, w.n = tx, 0
+	}
+
+	if err := fn(w.tx); err != nil {
+		return err
+	}
+
+	if w.n++; w.n >= w.limit {
+		if err := w.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Commit commits pending writes.
+func (w *batchWriter) Commit() error {
+	if w.tx == nil {
+		return nil
+	}
+
+	tx := w.tx
+	w.tx = nil
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	w.stats.Copied += w.n
+	return nil
+}
+
+// Rollback discards pending writes.
+func (w *batchWriter) Rollback() {
+	if w.tx != nil {
+		_ = w.tx.Rollback()
+		w.tx = nil
+	}
+}
@@ -0,0 +1,103 @@
+package archive_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/riposo/riposo/internal/archive"
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/schema"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+)
+
+var _ = Describe("Copy", func() {
+	var src, dst *conn.Set
+	var ctx = context.Background()
+	var opt = &archive.CopyOptions{PageSize: 2}
+
+	BeforeEach(func() {
+		src = mock.Conns(nil)
+		dst = mock.Conns(nil)
+
+		txn, err := api.NewTxn(ctx, src, mock.Helpers(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		seed(txn)
+		Expect(txn.Store.Create("/buckets/foo/collections/c1/records/*", &schema.Object{ID: "r3"})).To(Succeed())
+		Expect(txn.Perms.AddUserPrincipal("/buckets/foo/groups/g1", []string{"account:bob"})).To(Succeed())
+		Expect(txn.Cache.Set("key", []byte("val"), time.Now().Add(time.Hour))).To(Succeed())
+		Expect(txn.Commit()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(src.Close()).To(Succeed())
+		Expect(dst.Close()).To(Succeed())
+	})
+
+	It("copies storage", func() {
+		Expect(archive.CopyStorage(ctx, src.Store(), dst.Store(), opt)).To(Equal(&archive.CopyStats{Copied: 7}))
+
+		stx, err := src.Store().Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer stx.Rollback()
+
+		dtx, err := dst.Store().Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer dtx.Rollback()
+
+		opt := storage.ListOptions{Include: storage.IncludeAll}
+		exp, err := stx.ListAll("/buckets/foo/collections/c1/records/*", opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exp).To(HaveLen(3))
+		Expect(dtx.ListAll("/buckets/foo/collections/c1/records/*", opt)).To(ConsistOf(exp))
+
+		obj, err := stx.Get("/buckets/foo", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dtx.Get("/buckets/foo", false)).To(Equal(obj))
+	})
+
+	It("resumes interrupted copies", func() {
+		dtx, err := dst.Store().Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dtx.(storage.Restorer).Restore("/buckets/*", &schema.Object{ID: "bar", ModTime: 1515151515677})).To(Succeed())
+		Expect(dtx.Commit()).To(Succeed())
+
+		Expect(archive.CopyStorage(ctx, src.Store(), dst.Store(), opt)).To(Equal(&archive.CopyStats{Copied: 6, Skipped: 1}))
+		Expect(archive.CopyStorage(ctx, src.Store(), dst.Store(), opt)).To(Equal(&archive.CopyStats{Copied: 1, Skipped: 6}))
+	})
+
+	It("verifies counts", func() {
+		dtx, err := dst.Store().Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dtx.Create("/buckets/foo/groups/*", &schema.Object{ID: "g2"})).To(Succeed())
+		Expect(dtx.Commit()).To(Succeed())
+
+		_, err = archive.CopyStorage(ctx, src.Store(), dst.Store(), opt)
+		Expect(err).To(MatchError(`count mismatch on /buckets/foo/groups/*: 1 in source, 2 in destination`))
+	})
+
+	It("copies permissions", func() {
+		Expect(archive.CopyPermissions(ctx, src.Perms(), dst.Perms(), opt)).To(Equal(&archive.CopyStats{Copied: 3}))
+
+		ptx, err := dst.Perms().Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer ptx.Rollback()
+
+		Expect(ptx.GetUserPrincipals("account:bob")).To(ContainElement("/buckets/foo/groups/g1"))
+		Expect(ptx.GetPermissions("/buckets/foo")).To(Equal(schema.PermissionSet{"write": {"account:alice"}}))
+	})
+
+	It("copies cache", func() {
+		Expect(archive.CopyCache(ctx, src.Cache(), dst.Cache(), opt)).To(Equal(&archive.CopyStats{Copied: 1}))
+
+		tx, err := dst.Cache().Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Get("key")).To(Equal([]byte("val")))
+	})
+})
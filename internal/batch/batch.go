@@ -24,32 +24,122 @@ func Handler(namespace string, mux http.Handler) http.Handler {
 			return
 		}
 
-		// unset the batch route context
-		ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
-
-		// parse/collect sub-requests
-		sub := make([]*http.Request, 0, len(req.Requests))
-		for pos, part := range req.Requests {
+		// normalize/validate sub-requests
+		for pos := range req.Requests {
+			part := &req.Requests[pos]
 			part.Norm(namespace, req.Defaults)
 
-			sreq, err := part.httpRequest(ctx, r.Header)
-			if err != nil {
+			if err := part.validate(pos); err != nil {
 				api.Render(w, schema.InvalidBody("requests."+strconv.Itoa(pos), err.Error()))
 				return
 			}
-			sub = append(sub, sreq)
 		}
 
+		// unset the batch route context
+		ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
+
 		// record/collect responses
 		rec := poolRecorder()
 		defer releaseRecorder(rec)
 
-		res := &Response{Responses: make([]ResponsePart, 0, len(req.Requests))}
-		for _, sr := range sub {
+		serve(ctx, w, r, mux, rec, namespace, &req)
+	})
+}
+
+// serve runs sub-requests sequentially within a batch savepoint. In atomic
+// mode, the whole batch is rolled back if any of the sub-requests fails.
+// Otherwise, each sub-request runs within its own savepoint and failed
+// sub-requests are rolled back individually.
+func serve(ctx context.Context, w http.ResponseWriter, r *http.Request, mux http.Handler, rec *ResponseRecorder, namespace string, req *Request) {
+	const name = "batch"
+
+	txn := api.GetTxn(r)
+	if err := savepoint(txn, name); err != nil {
+		api.Render(w, err)
+		return
+	}
+
+	// prefetch objects and permissions for runs of single-object GETs
+	var restore func()
+	var runEnd int
+	defer func() {
+		if restore != nil {
+			restore()
+		}
+	}()
+
+	res := &Response{Responses: make([]ResponsePart, 0, len(req.Requests))}
+	for pos, part := range req.Requests {
+		if restore != nil && pos >= runEnd {
+			restore()
+			restore = nil
+		}
+		if txn != nil && pos >= runEnd {
+			if paths := getRun(req.Requests[pos:]); len(paths) > 1 {
+				restore, _ = prefetch(txn, paths) // on error, fall back on regular processing
+				runEnd = pos + len(paths)
+			}
+		}
+
+		sr, err := part.httpRequest(ctx, r.Header, resolver(res.Responses))
+		if err != nil {
+			_ = release(txn, name, true)
+			api.Render(w, schema.InvalidBody("requests."+strconv.Itoa(pos), err.Error()))
+			return
+		}
+
+		if req.Atomic {
 			mux.ServeHTTP(rec, sr)
+			code := rec.code
 			rec.appendTo(res, namespace+sr.URL.String())
+
+			// abort on first failure, respond with failed status
+			if code >= http.StatusBadRequest {
+				res.StatusCode = code
+				break
+			}
+			continue
 		}
 
-		api.Render(w, res)
-	})
+		subName := name + "_" + strconv.Itoa(pos)
+		if err := savepoint(txn, subName); err != nil {
+			api.Render(w, err)
+			return
+		}
+
+		mux.ServeHTTP(rec, sr)
+		failed := rec.code >= http.StatusBadRequest
+		rec.appendTo(res, namespace+sr.URL.String())
+
+		if err := release(txn, subName, failed); err != nil {
+			api.Render(w, err)
+			return
+		}
+	}
+
+	if err := release(txn, name, res.StatusCode != 0); err != nil {
+		api.Render(w, err)
+		return
+	}
+
+	api.Render(w, res)
+}
+
+func savepoint(txn *api.Txn, name string) error {
+	if txn == nil {
+		return nil
+	}
+	return txn.Savepoint(name)
+}
+
+func release(txn *api.Txn, name string, rollback bool) error {
+	if txn == nil {
+		return nil
+	}
+	if rollback {
+		if err := txn.RollbackTo(name); err != nil {
+			return err
+		}
+	}
+	return txn.Release(name)
 }
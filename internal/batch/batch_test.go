@@ -6,10 +6,15 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn/cache"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/riposo"
 	"github.com/riposo/riposo/pkg/schema"
 
 	. "github.com/bsm/ginkgo/v2"
@@ -225,6 +230,105 @@ var _ = Describe("Handler", func() {
 		}`))
 	})
 
+	It("resolves references", func() {
+		r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{
+			"requests": [
+				{ "method": "POST", "path": "/books", "body": { "id": 451, "title": "Fahrenheit" } },
+				{ "method": "GET", "path": "/books/{{ responses.0.body.data.id }}" },
+				{
+					"method": "POST",
+					"path": "/books",
+					"body": {
+						"ref": "{{responses.0.body.data}}",
+						"title": "Re: {{responses.1.body.data.title}} (\"{{responses.1.status}}\")"
+					}
+				}
+			]
+		}`))
+		r.SetBasicAuth("alice", "")
+		subject.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.Bytes()).To(MatchJSON(`{
+			"responses": [
+				{
+					"status": 200,
+					"path": "/v1/books",
+					"body": {"data": {"id": 451, "title": "Fahrenheit"}},
+					"headers": {"Content-Type": "application/json; charset=utf-8", "X-Custom": "batch"}
+				},
+				{
+					"status": 200,
+					"path": "/v1/books/451",
+					"body": {"data": {"id": 451, "title": "Fahrenheit 451", "author": "Ray Bradbury"}},
+					"headers": {"Content-Type": "application/json; charset=utf-8", "X-Custom": "batch"}
+				},
+				{
+					"status": 200,
+					"path": "/v1/books",
+					"body": {"data": {
+						"ref": {"id": 451, "title": "Fahrenheit"},
+						"title": "Re: Fahrenheit 451 (\"200\")"
+					}},
+					"headers": {"Content-Type": "application/json; charset=utf-8", "X-Custom": "batch"}
+				}
+			]
+		}`))
+	})
+
+	It("only resolves response references once", func() {
+		r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{
+			"requests": [
+				{ "method": "POST", "path": "/books", "body": { "id": 1, "title": "\u007b{responses.0.status}}" } },
+				{ "method": "POST", "path": "/books", "body": { "ref": "re: {{responses.0.body.data.title}}" } },
+				{ "method": "POST", "path": "/books", "body": { "ref": "{{responses.1.body.data.ref}}", "tpl": "{{name}}" } }
+			]
+		}`))
+		r.SetBasicAuth("alice", "")
+		subject.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring(`"body":{"data":{"ref":"re: {{responses.0.status}}","tpl":"{{name}}"}}`))
+	})
+
+	It("rejects invalid references", func() {
+		r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{
+			"requests": [
+				{ "method": "GET", "path": "/books/{{responses.0.body.data.id}}" }
+			]
+		}`))
+		r.SetBasicAuth("alice", "")
+		subject.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+		Expect(w.Body.Bytes()).To(MatchJSON(`{
+			"code": 400,
+			"errno": 107,
+			"error": "Invalid parameters",
+			"message": "requests.0 in body: invalid reference \"{{responses.0.body.data.id}}\"",
+			"details": [{ "name": "requests.0", "location": "body", "description": "invalid reference \"{{responses.0.body.data.id}}\"" }]
+		}`))
+
+		r = httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{
+			"requests": [
+				{ "method": "GET", "path": "/books" },
+				{ "method": "GET", "path": "/books/{{responses.0.body.data.id}}" }
+			]
+		}`))
+		r.SetBasicAuth("alice", "")
+		w = httptest.NewRecorder()
+		subject.ServeHTTP(w, r)
+
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+		Expect(w.Body.Bytes()).To(MatchJSON(`{
+			"code": 400,
+			"errno": 107,
+			"error": "Invalid parameters",
+			"message": "requests.1 in body: unresolved reference \"{{responses.0.body.data.id}}\"",
+			"details": [{ "name": "requests.1", "location": "body", "description": "unresolved reference \"{{responses.0.body.data.id}}\"" }]
+		}`))
+	})
+
 	It("delegates some headers", func() {
 		r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{
 			"requests": [
@@ -248,6 +352,143 @@ var _ = Describe("Handler", func() {
 			]
 	}`))
 	})
+
+	Describe("with transaction", func() {
+		var txn *api.Txn
+
+		BeforeEach(func() {
+			txn = mock.Txn()
+			subject = Handler("/v1", mockTxnMux)
+		})
+
+		AfterEach(func() {
+			Expect(txn.Rollback()).To(Succeed())
+		})
+
+		serve := func(body string) {
+			r := mock.Request(txn, http.MethodPost, "/batch", strings.NewReader(body))
+			subject.ServeHTTP(w, r)
+		}
+
+		It("rolls back failed sub-requests", func() {
+			serve(`{
+				"requests": [
+					{ "method": "PUT", "path": "/keys/a", "body": 1 },
+					{ "method": "PUT", "path": "/keys/b?fail=true", "body": 2 },
+					{ "method": "PUT", "path": "/keys/c", "body": 3 }
+				]
+			}`)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring(`"status":400`))
+
+			Expect(txn.Cache.Get("a")).To(Equal([]byte("1")))
+			_, err := txn.Cache.Get("b")
+			Expect(err).To(MatchError(cache.ErrNotFound))
+			Expect(txn.Cache.Get("c")).To(Equal([]byte("3")))
+		})
+
+		It("coalesces single-object GETs", func() {
+			rts := api.NewRoutes(nil)
+			rts.Resource("/buckets", nil)
+			subject = Handler("/v1", rts.Mux())
+
+			txn.User = mock.User("account:alice")
+			Expect(txn.Store.Create("/buckets/*", &schema.Object{ID: "a", Extra: []byte(`{"x":1}`)})).To(Succeed())
+			Expect(txn.Store.Create("/buckets/*", &schema.Object{ID: "b"})).To(Succeed())
+			Expect(txn.Perms.CreatePermissions("/buckets/a", schema.PermissionSet{"read": {"account:alice"}})).To(Succeed())
+			Expect(txn.Perms.CreatePermissions("/buckets/c", schema.PermissionSet{"write": {"account:alice"}})).To(Succeed())
+
+			paths := []string{"/buckets/a", "/buckets/b", "/buckets/c"}
+
+			// serve individually
+			var individual []json.RawMessage
+			for _, path := range paths {
+				w = httptest.NewRecorder()
+				serve(`{"requests": [{ "method": "GET", "path": "` + path + `" }]}`)
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				var res struct{ Responses []json.RawMessage }
+				Expect(json.Unmarshal(w.Body.Bytes(), &res)).To(Succeed())
+				Expect(res.Responses).To(HaveLen(1))
+				individual = append(individual, res.Responses[0])
+			}
+
+			// serve coalesced
+			store := &countingStore{Transaction: txn.Store}
+			txn.Store = store
+
+			w = httptest.NewRecorder()
+			serve(`{
+				"requests": [
+					{ "method": "GET", "path": "/buckets/a" },
+					{ "method": "GET", "path": "/buckets/b" },
+					{ "method": "GET", "path": "/buckets/c" }
+				]
+			}`)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(store.reads).To(Equal(0))
+			Expect(txn.Store).To(Equal(store))
+
+			var res struct{ Responses []json.RawMessage }
+			Expect(json.Unmarshal(w.Body.Bytes(), &res)).To(Succeed())
+			Expect(res.Responses).To(Equal(individual))
+			Expect(string(res.Responses[0])).To(ContainSubstring(`"status":200`))
+			Expect(string(res.Responses[1])).To(ContainSubstring(`"status":403`))
+			Expect(string(res.Responses[2])).To(ContainSubstring(`"status":404`))
+		})
+
+		It("supports atomic mode", func() {
+			serve(`{
+				"atomic": true,
+				"requests": [
+					{ "method": "PUT", "path": "/keys/a", "body": 1 },
+					{ "method": "PUT", "path": "/keys/b", "body": 2 }
+				]
+			}`)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(txn.Cache.Get("a")).To(Equal([]byte("1")))
+			Expect(txn.Cache.Get("b")).To(Equal([]byte("2")))
+		})
+
+		It("rolls back the whole batch in atomic mode", func() {
+			serve(`{
+				"atomic": true,
+				"requests": [
+					{ "method": "PUT", "path": "/keys/a", "body": 1 },
+					{ "method": "PUT", "path": "/keys/b?fail=true", "body": 2 },
+					{ "method": "PUT", "path": "/keys/c", "body": 3 }
+				]
+			}`)
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(w.Body.Bytes()).To(MatchJSON(`{
+				"responses": [
+					{
+						"status": 200,
+						"path": "/v1/keys/a",
+						"body": {"data": 1},
+						"headers": {"Content-Type": "application/json; charset=utf-8"}
+					},
+					{
+						"status": 400,
+						"path": "/v1/keys/b?fail=true",
+						"body": {
+							"code": 400,
+							"errno": 107,
+							"error": "Invalid parameters",
+							"message": "querystring: fail",
+							"details": [{"location": "querystring", "description": "fail"}]
+						},
+						"headers": {"Content-Type": "application/json; charset=utf-8"}
+					}
+				]
+			}`))
+
+			for _, key := range []string{"a", "b", "c"} {
+				_, err := txn.Cache.Get(key)
+				Expect(err).To(MatchError(cache.ErrNotFound))
+			}
+		})
+	})
 })
 
 // --------------------------------------------------------------------
@@ -318,6 +559,44 @@ var mockMux = func() *chi.Mux {
 	return m
 }()
 
+var mockTxnMux = func() *chi.Mux {
+	m := chi.NewMux()
+	m.Put("/keys/{key}", func(w http.ResponseWriter, r *http.Request) {
+		var data json.RawMessage
+		if err := api.Parse(r, &data); err != nil {
+			api.Render(w, err)
+			return
+		}
+
+		txn := api.GetTxn(r)
+		if err := txn.Cache.Set(chi.URLParam(r, "key"), data, time.Now().Add(time.Hour)); err != nil {
+			api.Render(w, err)
+			return
+		}
+		if r.URL.Query().Get("fail") == "true" {
+			api.Render(w, schema.InvalidQuery("fail"))
+			return
+		}
+		api.Render(w, json.RawMessage(`{"data": `+string(data)+`}`))
+	})
+	return m
+}()
+
+type countingStore struct {
+	storage.Transaction
+	reads int
+}
+
+func (s *countingStore) Exists(path riposo.Path) (bool, error) {
+	s.reads++
+	return s.Transaction.Exists(path)
+}
+
+func (s *countingStore) Get(path riposo.Path, lock bool) (*schema.Object, error) {
+	s.reads++
+	return s.Transaction.Get(path, lock)
+}
+
 func TestSuite(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "internal/batch")
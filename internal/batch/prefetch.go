@@ -0,0 +1,265 @@
+package batch
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn/permission"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+	"github.com/riposo/riposo/pkg/util"
+)
+
+// objectPath returns the object path of single-object GET requests.
+func (p *RequestPart) objectPath() (riposo.Path, bool) {
+	if !strings.EqualFold(p.Method, http.MethodGet) || refPattern.MatchString(p.Path) {
+		return "", false
+	}
+
+	u, err := url.Parse(p.Path)
+	if err != nil {
+		return "", false
+	}
+
+	path := riposo.NormPath(u.Path)
+	if path == "" || path.IsNode() {
+		return "", false
+	}
+	return path, true
+}
+
+// getRun returns the object paths of a leading run of single-object GET
+// requests.
+func getRun(parts []RequestPart) []riposo.Path {
+	var paths []riposo.Path
+	for i := range parts {
+		path, ok := parts[i].objectPath()
+		if !ok {
+			break
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// prefetch fetches objects and permissions of the given paths, including all
+// parents, and wraps the storage and permission transactions to serve them.
+// The returned function restores the original transactions.
+func prefetch(txn *api.Txn, paths []riposo.Path) (func(), error) {
+	store, perms := txn.Store, txn.Perms
+
+	// collect object paths and permission paths
+	seen := util.NewSet()
+	objPaths := make([]riposo.Path, 0, 2*len(paths))
+	permPaths := make([]riposo.Path, 0, 3*len(paths))
+	for _, path := range paths {
+		path.Traverse(func(part riposo.Path) bool {
+			if seen.Has(part.String()) {
+				return false
+			}
+			seen.Add(part.String())
+
+			if part != "" {
+				objPaths = append(objPaths, part)
+			}
+			permPaths = append(permPaths, part)
+			return true
+		})
+	}
+
+	// fetch objects
+	objs, err := store.GetBatch(objPaths, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch permissions
+	sets, err := perms.GetAllPermissions(permPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := &prefetchStore{Transaction: store, objs: make(map[riposo.Path]*schema.Object, len(objPaths))}
+	for i, path := range objPaths {
+		ps.objs[path] = objs[i]
+	}
+
+	pp := &prefetchPerms{Transaction: perms, sets: make(map[riposo.Path]schema.PermissionSet, len(permPaths))}
+	for i, path := range permPaths {
+		pp.sets[path] = sets[i]
+	}
+
+	txn.Store, txn.Perms = ps, pp
+	return func() { txn.Store, txn.Perms = store, perms }, nil
+}
+
+// --------------------------------------------------------------------
+
+// prefetchStore serves prefetched objects and falls back on the wrapped
+// transaction for everything else. Prefetched objects are discarded on write.
+type prefetchStore struct {
+	storage.Transaction
+	objs map[riposo.Path]*schema.Object // nil values indicate missing objects
+}
+
+// Exists implements storage.Transaction interface.
+func (s *prefetchStore) Exists(path riposo.Path) (bool, error) {
+	if obj, ok := s.objs[path]; ok {
+		return obj != nil, nil
+	}
+	return s.Transaction.Exists(path)
+}
+
+// Get implements storage.Transaction interface.
+func (s *prefetchStore) Get(path riposo.Path, lock bool) (*schema.Object, error) {
+	if obj, ok := s.objs[path]; ok && !lock {
+		if obj == nil {
+			return nil, storage.ErrNotFound
+		}
+		return obj.Copy(), nil
+	}
+	return s.Transaction.Get(path, lock)
+}
+
+// Flush implements storage.Transaction interface.
+func (s *prefetchStore) Flush() error {
+	s.objs = nil
+	return s.Transaction.Flush()
+}
+
+// Purge implements storage.Transaction interface.
+func (s *prefetchStore) Purge(olderThan riposo.Epoch) (int64, error) {
+	s.objs = nil
+	return s.Transaction.Purge(olderThan)
+}
+
+// DeleteAll implements storage.Transaction interface.
+func (s *prefetchStore) DeleteAll(paths []riposo.Path) (riposo.Epoch, []riposo.Path, error) {
+	s.objs = nil
+	return s.Transaction.DeleteAll(paths)
+}
+
+// Create implements storage.Transaction interface.
+func (s *prefetchStore) Create(path riposo.Path, obj *schema.Object) error {
+	s.objs = nil
+	return s.Transaction.Create(path, obj)
+}
+
+// Update implements storage.Transaction interface.
+func (s *prefetchStore) Update(path riposo.Path, obj *schema.Object) error {
+	s.objs = nil
+	return s.Transaction.Update(path, obj)
+}
+
+// Delete implements storage.Transaction interface.
+func (s *prefetchStore) Delete(path riposo.Path) (*schema.Object, error) {
+	s.objs = nil
+	return s.Transaction.Delete(path)
+}
+
+// --------------------------------------------------------------------
+
+// prefetchPerms serves prefetched permissions and falls back on the wrapped
+// transaction for everything else. Prefetched permissions are discarded on
+// write.
+type prefetchPerms struct {
+	permission.Transaction
+	sets map[riposo.Path]schema.PermissionSet
+}
+
+// GetACEPrincipals implements permission.Transaction interface.
+func (p *prefetchPerms) GetACEPrincipals(ent permission.ACE) ([]string, error) {
+	if set, ok := p.sets[ent.Path]; ok {
+		if principals, ok := set[ent.Perm]; ok {
+			return append([]string(nil), principals...), nil
+		}
+		return nil, nil
+	}
+	return p.Transaction.GetACEPrincipals(ent)
+}
+
+// GetAllACEPrincipals implements permission.Transaction interface.
+func (p *prefetchPerms) GetAllACEPrincipals(ents []permission.ACE) ([]string, error) {
+	if len(ents) == 0 {
+		return nil, nil
+	}
+
+	res := util.NewSet()
+	for _, ent := range ents {
+		set, ok := p.sets[ent.Path]
+		if !ok || ent.Path.IsNode() {
+			return p.Transaction.GetAllACEPrincipals(ents)
+		}
+		res.MergeSlice(set[ent.Perm])
+	}
+	return res.Slice(), nil
+}
+
+// GetPermissions implements permission.Transaction interface.
+func (p *prefetchPerms) GetPermissions(path riposo.Path) (schema.PermissionSet, error) {
+	if set, ok := p.sets[path]; ok {
+		perms := make(schema.PermissionSet, len(set))
+		for perm, principals := range set {
+			perms[perm] = append([]string(nil), principals...)
+		}
+		return perms, nil
+	}
+	return p.Transaction.GetPermissions(path)
+}
+
+// Flush implements permission.Transaction interface.
+func (p *prefetchPerms) Flush() error {
+	p.sets = nil
+	return p.Transaction.Flush()
+}
+
+// AddUserPrincipal implements permission.Transaction interface.
+func (p *prefetchPerms) AddUserPrincipal(principal string, userIDs []string) error {
+	p.sets = nil
+	return p.Transaction.AddUserPrincipal(principal, userIDs)
+}
+
+// RemoveUserPrincipal implements permission.Transaction interface.
+func (p *prefetchPerms) RemoveUserPrincipal(principal string, userIDs []string) error {
+	p.sets = nil
+	return p.Transaction.RemoveUserPrincipal(principal, userIDs)
+}
+
+// PurgeUserPrincipals implements permission.Transaction interface.
+func (p *prefetchPerms) PurgeUserPrincipals(principals []string) error {
+	p.sets = nil
+	return p.Transaction.PurgeUserPrincipals(principals)
+}
+
+// AddACEPrincipal implements permission.Transaction interface.
+func (p *prefetchPerms) AddACEPrincipal(principal string, ent permission.ACE) error {
+	p.sets = nil
+	return p.Transaction.AddACEPrincipal(principal, ent)
+}
+
+// RemoveACEPrincipal implements permission.Transaction interface.
+func (p *prefetchPerms) RemoveACEPrincipal(principal string, ent permission.ACE) error {
+	p.sets = nil
+	return p.Transaction.RemoveACEPrincipal(principal, ent)
+}
+
+// CreatePermissions implements permission.Transaction interface.
+func (p *prefetchPerms) CreatePermissions(path riposo.Path, set schema.PermissionSet) error {
+	p.sets = nil
+	return p.Transaction.CreatePermissions(path, set)
+}
+
+// MergePermissions implements permission.Transaction interface.
+func (p *prefetchPerms) MergePermissions(path riposo.Path, set schema.PermissionSet) error {
+	p.sets = nil
+	return p.Transaction.MergePermissions(path, set)
+}
+
+// DeletePermissions implements permission.Transaction interface.
+func (p *prefetchPerms) DeletePermissions(paths []riposo.Path) error {
+	p.sets = nil
+	return p.Transaction.DeletePermissions(paths)
+}
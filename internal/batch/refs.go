@@ -0,0 +1,125 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// refExpr matches {{responses.N...}} placeholders, other {{...}} sequences are
+// left untouched.
+const refExpr = `\{\{\s*(responses\.\d+[^{}\s]*)\s*\}\}`
+
+var (
+	refPattern     = regexp.MustCompile(refExpr)
+	bodyRefPattern = regexp.MustCompile(`"` + refExpr + `"|` + refExpr)
+)
+
+// reference points to a value of a previous response,
+// e.g. {{responses.0.body.data.id}}.
+type reference struct {
+	Index int
+	Path  string
+}
+
+func parseReference(s string) (ref reference, ok bool) {
+	parts := strings.SplitN(s, ".", 3)
+	if len(parts) != 3 || parts[0] != "responses" || parts[2] == "" {
+		return
+	}
+
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 {
+		return
+	}
+	return reference{Index: index, Path: parts[2]}, true
+}
+
+// validateReferences ensures that all references within s are valid and only
+// point to responses prior to pos.
+func validateReferences(s string, pos int) error {
+	for _, m := range refPattern.FindAllStringSubmatch(s, -1) {
+		if ref, ok := parseReference(m[1]); !ok || ref.Index >= pos {
+			return fmt.Errorf("invalid reference %q", m[0])
+		}
+	}
+	return nil
+}
+
+// resolver resolves references using previous responses.
+type resolver []ResponsePart
+
+func (r resolver) lookup(placeholder, s string) (gjson.Result, error) {
+	ref, ok := parseReference(s)
+	if !ok || ref.Index >= len(r) {
+		return gjson.Result{}, fmt.Errorf("invalid reference %q", placeholder)
+	}
+
+	data, err := json.Marshal(r[ref.Index])
+	if err != nil {
+		return gjson.Result{}, fmt.Errorf("unresolved reference %q", placeholder)
+	}
+
+	res := gjson.GetBytes(data, ref.Path)
+	if !res.Exists() {
+		return gjson.Result{}, fmt.Errorf("unresolved reference %q", placeholder)
+	}
+	return res, nil
+}
+
+// Path replaces references within a path.
+func (r resolver) Path(path string) (string, error) {
+	var err error
+	path = refPattern.ReplaceAllStringFunc(path, func(m string) string {
+		res, lerr := r.lookup(m, refPattern.FindStringSubmatch(m)[1])
+		if lerr != nil {
+			if err == nil {
+				err = lerr
+			}
+			return m
+		}
+		return url.PathEscape(res.String())
+	})
+	return path, err
+}
+
+// Body replaces references within a JSON body. String values which consist of
+// a single reference are replaced by the referenced (raw) value, references
+// embedded in strings are replaced by string representations. Inserted values
+// are never expanded again.
+func (r resolver) Body(body []byte) ([]byte, error) {
+	var err error
+	body = bodyRefPattern.ReplaceAllFunc(body, func(m []byte) []byte {
+		sm := bodyRefPattern.FindSubmatch(m)
+		if sm[1] != nil {
+			res, lerr := r.lookup(string(m[1:len(m)-1]), string(sm[1]))
+			if lerr != nil {
+				if err == nil {
+					err = lerr
+				}
+				return m
+			}
+			return []byte(res.Raw)
+		}
+
+		res, lerr := r.lookup(string(m), string(sm[2]))
+		if lerr != nil {
+			if err == nil {
+				err = lerr
+			}
+			return m
+		}
+
+		str, _ := json.Marshal(res.String())
+		return str[1 : len(str)-1]
+	})
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
@@ -24,6 +24,9 @@ var skipHeaders = map[string]struct{}{
 type Request struct {
 	Defaults *RequestPart
 	Requests []RequestPart
+
+	// Atomic rolls back the whole batch if any sub-request fails.
+	Atomic bool
 }
 
 func (r *Request) containsRecursive() bool {
@@ -82,18 +85,45 @@ func (p *RequestPart) isRecursive() bool {
 	return strings.HasPrefix(p.Path, "/batch")
 }
 
-func (p *RequestPart) httpRequest(ctx context.Context, parent http.Header) (*http.Request, error) {
+// validate validates the part at position pos.
+func (p *RequestPart) validate(pos int) error {
 	switch strings.ToUpper(p.Method) {
 	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
 		// OK
 	default:
-		return nil, fmt.Errorf("invalid method %q", p.Method)
+		return fmt.Errorf("invalid method %q", p.Method)
+	}
+
+	if err := validateReferences(p.Path, pos); err != nil {
+		return err
+	}
+	if err := validateReferences(string(p.Body), pos); err != nil {
+		return err
+	}
+
+	// validate static paths early
+	if !refPattern.MatchString(p.Path) {
+		if _, err := url.Parse(p.Path); err != nil {
+			return fmt.Errorf("invalid path %q", p.Path)
+		}
+	}
+	return nil
+}
+
+func (p *RequestPart) httpRequest(ctx context.Context, parent http.Header, rs resolver) (*http.Request, error) {
+	path, err := rs.Path(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	body, err := rs.Body(p.Body)
+	if err != nil {
+		return nil, err
 	}
 
-	hr, err := http.NewRequestWithContext(ctx, p.Method, p.Path, bytes.NewReader(p.Body))
+	hr, err := http.NewRequestWithContext(ctx, p.Method, path, bytes.NewReader(body))
 	if err != nil {
 		if uerr := new(url.Error); errors.As(err, &uerr) {
-			return nil, fmt.Errorf("invalid path %q", p.Path)
+			return nil, fmt.Errorf("invalid path %q", path)
 		}
 		return nil, err
 	}
@@ -7,7 +7,16 @@ import (
 
 // Response is a batch response.
 type Response struct {
-	Responses []ResponsePart `json:"responses"`
+	Responses  []ResponsePart `json:"responses"`
+	StatusCode int            `json:"-"`
+}
+
+// HTTPStatus returns the http status code.
+func (r *Response) HTTPStatus() int {
+	if r.StatusCode != 0 {
+		return r.StatusCode
+	}
+	return http.StatusOK
 }
 
 // ResponsePart contains sub-response information of a batch response.
@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/google/subcommands"
+	"github.com/riposo/riposo/internal/archive"
+	"github.com/riposo/riposo/internal/config"
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn"
+	"github.com/riposo/riposo/pkg/riposo"
+)
+
+// Export inits a new sub-command.
+func Export() subcommands.Command { return new(exportCmd) }
+
+// Import inits a new sub-command.
+func Import() subcommands.Command { return new(importCmd) }
+
+type backendFlags struct {
+	configFile    string
+	storageURL    string
+	permissionURL string
+}
+
+func (c *backendFlags) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.configFile, "config", "", "Optional YAML config file")
+	f.StringVar(&c.storageURL, "storage", "", "Storage backend URL, overrides config")
+	f.StringVar(&c.permissionURL, "permission", "", "Permission backend URL, overrides config")
+}
+
+// begin connects to the backends and starts a new transaction.
+func (c *backendFlags) begin(ctx context.Context) (*api.Txn, *conn.Set, error) {
+	cfg, err := config.Parse(c.configFile, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.storageURL != "" {
+		cfg.Storage.URL = c.storageURL
+	}
+	if c.permissionURL != "" {
+		cfg.Permission.URL = c.permissionURL
+	}
+
+	hlp, err := cfg.InitHelpers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cns, err := conn.Connect(ctx, cfg.Storage.URL, cfg.Permission.URL, cfg.Cache.URL, hlp, cfg.ConnOptions())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	txn, err := api.NewTxn(ctx, cns, hlp, nil)
+	if err != nil {
+		_ = cns.Close()
+		return nil, nil, err
+	}
+	return txn, cns, nil
+}
+
+// --------------------------------------------------------------------
+
+type exportCmd struct {
+	backendFlags
+	output string
+}
+
+func (*exportCmd) Name() string     { return "export" }
+func (*exportCmd) Synopsis() string { return "Export objects as NDJSON." }
+func (*exportCmd) Usage() string {
+	return "export [-output FILE] [PATH]:\n  Export objects within PATH (default: all buckets) as NDJSON.\n"
+}
+func (c *exportCmd) SetFlags(f *flag.FlagSet) {
+	c.backendFlags.SetFlags(f)
+	f.StringVar(&c.output, "output", "", "Output file, defaults to STDOUT")
+}
+
+func (c *exportCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() > 1 {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+	return exitStatus(c.run(ctx, riposo.NormPath(f.Arg(0))))
+}
+
+func (c *exportCmd) run(ctx context.Context, root riposo.Path) error {
+	txn, cns, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer cns.Close()
+	defer txn.Rollback()
+
+	var w io.Writer = os.Stdout
+	if c.output != "" {
+		file, err := os.Create(c.output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		w = file
+	}
+
+	buf := bufio.NewWriter(w)
+	n, err := archive.Export(buf, txn, root)
+	if err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	fprintf(os.Stderr, "exported %d objects", n)
+	return nil
+}
+
+// --------------------------------------------------------------------
+
+type importCmd struct {
+	backendFlags
+	input  string
+	dryRun bool
+	opt    archive.ImportOptions
+}
+
+func (*importCmd) Name() string     { return "import" }
+func (*importCmd) Synopsis() string { return "Import objects from NDJSON." }
+func (*importCmd) Usage() string {
+	return "import [-input FILE] [-preserve-timestamps] [-skip-existing] [-dry-run]:\n  Import objects from NDJSON.\n"
+}
+func (c *importCmd) SetFlags(f *flag.FlagSet) {
+	c.backendFlags.SetFlags(f)
+	f.StringVar(&c.input, "input", "", "Input file, defaults to STDIN")
+	f.BoolVar(&c.opt.PreserveTimestamps, "preserve-timestamps", false, "Retain original last_modified timestamps")
+	f.BoolVar(&c.opt.SkipExisting, "skip-existing", false, "Skip existing objects instead of failing")
+	f.BoolVar(&c.dryRun, "dry-run", false, "Validate the import and roll back")
+}
+
+func (c *importCmd) Execute(ctx context.Context, _ *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	return exitStatus(c.run(ctx))
+}
+
+func (c *importCmd) run(ctx context.Context) error {
+	var r io.Reader = os.Stdin
+	if c.input != "" {
+		file, err := os.Open(c.input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		r = file
+	}
+
+	txn, cns, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer cns.Close()
+
+	stats, err := archive.Import(r, txn, &c.opt)
+	if err != nil {
+		_ = txn.Rollback()
+		return err
+	}
+
+	if c.dryRun {
+		if err := txn.Rollback(); err != nil {
+			return err
+		}
+		fprintf(os.Stderr, "would import %d objects, skip %d (dry run)", stats.Created, stats.Skipped)
+		return nil
+	}
+
+	if err := txn.Commit(); err != nil {
+		return err
+	}
+	fprintf(os.Stderr, "imported %d objects, skipped %d", stats.Created, stats.Skipped)
+	return nil
+}
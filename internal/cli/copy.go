@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/google/subcommands"
+	"github.com/riposo/riposo/internal/archive"
+	"github.com/riposo/riposo/internal/config"
+	"github.com/riposo/riposo/pkg/conn/cache"
+	"github.com/riposo/riposo/pkg/conn/permission"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/riposo"
+)
+
+// Copy inits a new sub-command.
+func Copy() subcommands.Command { return new(copyCmd) }
+
+type copyCmd struct {
+	configFile             string
+	fromStorage, toStorage string
+	fromPerms, toPerms     string
+	fromCache, toCache     string
+	opt                    archive.CopyOptions
+}
+
+func (*copyCmd) Name() string     { return "copy" }
+func (*copyCmd) Synopsis() string { return "Copy data between backends." }
+func (*copyCmd) Usage() string {
+	return "copy [-from-storage URL -to-storage URL] [-from-permission URL -to-permission URL] [-from-cache URL -to-cache URL]:\n  Copy data between backends.\n"
+}
+func (c *copyCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.configFile, "config", "", "Optional YAML config file")
+	f.StringVar(&c.fromStorage, "from-storage", "", "Source storage backend URL")
+	f.StringVar(&c.toStorage, "to-storage", "", "Destination storage backend URL")
+	f.StringVar(&c.fromPerms, "from-permission", "", "Source permission backend URL")
+	f.StringVar(&c.toPerms, "to-permission", "", "Destination permission backend URL")
+	f.StringVar(&c.fromCache, "from-cache", "", "Source cache backend URL")
+	f.StringVar(&c.toCache, "to-cache", "", "Destination cache backend URL")
+	f.IntVar(&c.opt.PageSize, "page-size", 1000, "Number of entries to copy per transaction")
+}
+
+func (c *copyCmd) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if (c.fromStorage == "") != (c.toStorage == "") ||
+		(c.fromPerms == "") != (c.toPerms == "") ||
+		(c.fromCache == "") != (c.toCache == "") ||
+		(c.fromStorage == "" && c.fromPerms == "" && c.fromCache == "") {
+		f.Usage()
+		return subcommands.ExitUsageError
+	}
+
+	cfg, err := config.Parse(c.configFile, nil)
+	if err != nil {
+		failure("invalid configuration: " + err.Error())
+		return subcommands.ExitUsageError
+	}
+
+	hlp, err := cfg.InitHelpers()
+	if err != nil {
+		return exitStatus(err)
+	}
+
+	return exitStatus(c.run(ctx, hlp))
+}
+
+func (c *copyCmd) run(ctx context.Context, hlp riposo.Helpers) error {
+	if c.fromStorage != "" {
+		if err := c.copyStorage(ctx, hlp); err != nil {
+			return err
+		}
+	}
+	if c.fromPerms != "" {
+		if err := c.copyPerms(ctx, hlp); err != nil {
+			return err
+		}
+	}
+	if c.fromCache != "" {
+		if err := c.copyCache(ctx, hlp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *copyCmd) copyStorage(ctx context.Context, hlp riposo.Helpers) error {
+	src, err := storage.Connect(ctx, c.fromStorage, hlp)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := storage.Connect(ctx, c.toStorage, hlp)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	stats, err := archive.CopyStorage(ctx, src, dst, &c.opt)
+	return reportCopy("storage", stats, err)
+}
+
+func (c *copyCmd) copyPerms(ctx context.Context, hlp riposo.Helpers) error {
+	src, err := permission.Connect(ctx, c.fromPerms, hlp)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := permission.Connect(ctx, c.toPerms, hlp)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	stats, err := archive.CopyPermissions(ctx, src, dst, &c.opt)
+	return reportCopy("permission", stats, err)
+}
+
+func (c *copyCmd) copyCache(ctx context.Context, hlp riposo.Helpers) error {
+	src, err := cache.Connect(ctx, c.fromCache, hlp)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := cache.Connect(ctx, c.toCache, hlp)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	stats, err := archive.CopyCache(ctx, src, dst, &c.opt)
+	return reportCopy("cache", stats, err)
+}
+
+func reportCopy(name string, stats *archive.CopyStats, err error) error {
+	if stats != nil {
+		fprintf(os.Stderr, "%s: copied %d, skipped %d", name, stats.Copied, stats.Skipped)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
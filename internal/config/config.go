@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn"
 	"github.com/riposo/riposo/pkg/identity"
 	"github.com/riposo/riposo/pkg/plugin"
 	"github.com/riposo/riposo/pkg/riposo"
@@ -24,14 +26,17 @@ type Config struct {
 	}
 
 	Storage struct {
-		URL string `default:"memory:"`
+		URL      string `default:"memory:"`
+		Wrappers []string
 	}
 	Permission struct {
 		URL      string `default:"memory:"`
+		Wrappers []string
 		Defaults map[string][]string
 	}
 	Cache struct {
-		URL string `default:"memory:"`
+		URL      string `default:"memory:"`
+		Wrappers []string
 	}
 
 	Batch struct {
@@ -48,6 +53,7 @@ type Config struct {
 	Pagination struct {
 		TokenValidity time.Duration `default:"10m" yaml:"token_validity"`
 		MaxLimit      int           `default:"10000" yaml:"max_limit"`
+		Secret        string
 	}
 
 	Backoff struct {
@@ -61,12 +67,32 @@ type Config struct {
 		ReadTimeout     time.Duration `default:"60s" yaml:"read_timeout"`
 		WriteTimeout    time.Duration `default:"60s" yaml:"write_timeout"`
 		ShutdownTimeout time.Duration `default:"5s" yaml:"shutdown_timeout"`
+		RequestTimeout  time.Duration `yaml:"request_timeout"`
 	}
 
 	Temp struct {
 		Dir string
 	}
 
+	Attachments struct {
+		Enabled bool
+		URL     string // defaults to a directory within temp.dir
+		MaxSize int64  `default:"10485760" yaml:"max_size"`
+	}
+	Signer struct {
+		Enabled     bool
+		Key         string   // path to a PEM-encoded ECDSA P-384 private key
+		Chain       string   // path to a PEM-encoded certificate chain
+		X5U         string   `yaml:"x5u"` // public URL of the certificate chain
+		Collections []string // collections to re-sign after each change
+	}
+
+	// Admin endpoints, for testing and maintenance only.
+	Admin struct {
+		Enabled    bool
+		Principals []string
+	}
+
 	Plugins []string
 	Rules   []*yaml.Node
 
@@ -97,6 +123,24 @@ func Parse(configFile string, env Env) (*Config, error) {
 	return &c, nil
 }
 
+// Reload re-parses the config from environment and returns
+// a new copy.
+func (c *Config) Reload() (*Config, error) {
+	if c.parseFunc == nil {
+		return nil, fmt.Errorf("config parser is not initialised")
+	}
+
+	var r Config
+	r.Project.Version = riposo.Version
+	r.Capabilities = c.Capabilities
+
+	if err := c.parseFunc(&r); err != nil {
+		return nil, err
+	}
+	r.parseFunc = c.parseFunc
+	return &r, nil
+}
+
 // APIConfig returns an API config.
 func (c *Config) APIConfig() *api.Config {
 	return &api.Config{
@@ -104,10 +148,20 @@ func (c *Config) APIConfig() *api.Config {
 		Pagination: (struct {
 			TokenValidity time.Duration
 			MaxLimit      int
+			Secret        string
 		})(c.Pagination),
 	}
 }
 
+// ConnOptions returns connection options.
+func (c *Config) ConnOptions() *conn.Options {
+	return &conn.Options{
+		StorageWrappers:    c.Storage.Wrappers,
+		PermissionWrappers: c.Permission.Wrappers,
+		CacheWrappers:      c.Cache.Wrappers,
+	}
+}
+
 // InitHelpers inits helpers.
 func (c *Config) InitHelpers() (riposo.Helpers, error) {
 	if c.parseFunc == nil {
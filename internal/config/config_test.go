@@ -20,7 +20,9 @@ var _ = Describe("Config", func() {
 		Expect(conf.Pagination.MaxLimit).To(Equal(10_000))
 		Expect(conf.Server.Address).To(Equal(":8888"))
 		Expect(conf.Server.ShutdownTimeout).To(Equal(5 * time.Second))
+		Expect(conf.Server.RequestTimeout).To(BeZero())
 		Expect(conf.EOS.Time).To(BeZero())
+		Expect(conf.Admin.Enabled).To(BeFalse())
 	})
 
 	It("parse env", func() {
@@ -58,6 +60,32 @@ var _ = Describe("Config", func() {
 		}))
 		Expect(conf.Rules).To(HaveLen(2))
 		Expect(conf.EOS.Time).To(BeTemporally("==", time.Date(2042, 12, 24, 17, 29, 37, 0, time.UTC)))
+		Expect(conf.Admin.Enabled).To(BeTrue())
+		Expect(conf.Admin.Principals).To(Equal([]string{"account:admin"}))
+	})
+
+	It("reloads", func() {
+		env := MapEnv{
+			"RIPOSO_SERVER_ADDRESS": ":8899",
+		}
+
+		conf, err := Parse("testdata/config.yml", env)
+		Expect(err).NotTo(HaveOccurred())
+
+		env["RIPOSO_EOS_MESSAGE"] = "Bye!"
+		env["RIPOSO_ADMIN_PRINCIPALS"] = "account:admin,account:root"
+
+		next, err := conf.Reload()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next).NotTo(BeIdenticalTo(conf))
+		Expect(next.Server.Address).To(Equal(":8899"))
+		Expect(next.EOS.Message).To(Equal("Bye!"))
+		Expect(next.Admin.Principals).To(Equal([]string{"account:admin", "account:root"}))
+		Expect(next.Capabilities).To(BeIdenticalTo(conf.Capabilities))
+		Expect(conf.EOS.Message).To(BeEmpty())
+
+		_, err = new(Config).Reload()
+		Expect(err).To(MatchError("config parser is not initialised"))
 	})
 
 	It("prioritises env", func() {
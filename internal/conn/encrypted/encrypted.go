@@ -0,0 +1,5 @@
+package encrypted
+
+import (
+	_ "github.com/riposo/riposo/internal/conn/encrypted/storage" // storage backend
+)
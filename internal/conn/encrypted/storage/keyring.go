@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Keyring holds AES keys by key ID. The last key of a keyring is used to
+// encrypt, all keys can be used to decrypt.
+type Keyring struct {
+	keys   map[string]cipher.AEAD
+	active string
+}
+
+// LoadKeyring loads a keyring from a file.
+func LoadKeyring(name string) (*Keyring, error) {
+	if name == "" {
+		return nil, errors.New("no keyring file configured")
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseKeyring(f)
+}
+
+// ParseKeyring parses a keyring. Keyrings contain one key per line, each
+// consisting of a key ID and a base64-encoded 16, 24 or 32 byte key separated
+// by whitespace. Blank lines and lines starting with # are ignored.
+func ParseKeyring(r io.Reader) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid keyring entry on line %d", n)
+		}
+
+		keyID := fields[0]
+		if _, ok := k.keys[keyID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q on line %d", keyID, n)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q on line %d: %w", keyID, n, err)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q on line %d: %w", keyID, n, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		k.keys[keyID] = aead
+		k.active = keyID
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(k.keys) == 0 {
+		return nil, errors.New("keyring contains no keys")
+	}
+	return k, nil
+}
+
+// Encrypt encrypts plaintext with the active key and returns the key ID and
+// the nonce-prefixed ciphertext. The ciphertext is bound to the key ID and to
+// aad, which must be passed to Decrypt again.
+func (k *Keyring) Encrypt(plaintext, aad []byte) (string, []byte, error) {
+	aead := k.keys[k.active]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return k.active, aead.Seal(nonce, nonce, plaintext, additionalData(k.active, aad)), nil
+}
+
+// Decrypt decrypts a nonce-prefixed ciphertext with the given key and aad.
+func (k *Keyring) Decrypt(keyID string, ciphertext, aad []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", keyID)
+	}
+
+	size := aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return aead.Open(nil, ciphertext[:size], ciphertext[size:], additionalData(keyID, aad))
+}
+
+func additionalData(keyID string, aad []byte) []byte {
+	data := make([]byte, 0, len(keyID)+1+len(aad))
+	data = append(data, keyID...)
+	data = append(data, 0)
+	return append(data, aad...)
+}
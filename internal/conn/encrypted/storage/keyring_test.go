@@ -0,0 +1,61 @@
+package storage_test
+
+import (
+	"strings"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/internal/conn/encrypted/storage"
+)
+
+var _ = Describe("Keyring", func() {
+	parse := func(s string) (*Keyring, error) {
+		return ParseKeyring(strings.NewReader(s))
+	}
+
+	It("parses", func() {
+		ring, err := parse("# comment\n\nk1 " + key1 + "\nk2 " + key2 + "\n")
+		Expect(err).NotTo(HaveOccurred())
+
+		keyID, ciphertext, err := ring.Encrypt([]byte("data"), []byte("aad"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keyID).To(Equal("k2"))
+		Expect(ciphertext).NotTo(ContainSubstring("data"))
+
+		plaintext, err := ring.Decrypt(keyID, ciphertext, []byte("aad"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(plaintext)).To(Equal("data"))
+	})
+
+	It("validates", func() {
+		_, err := parse("")
+		Expect(err).To(MatchError("keyring contains no keys"))
+
+		_, err = parse("k1")
+		Expect(err).To(MatchError("invalid keyring entry on line 1"))
+
+		_, err = parse("k1 " + key1 + "\nk1 " + key2)
+		Expect(err).To(MatchError(`duplicate key ID "k1" on line 2`))
+
+		_, err = parse("k1 bm90IGEga2V5")
+		Expect(err).To(MatchError(`invalid key "k1" on line 1: crypto/aes: invalid key size 9`))
+	})
+
+	It("rejects unknown keys and tampered data", func() {
+		ring, err := parse("k1 " + key1)
+		Expect(err).NotTo(HaveOccurred())
+
+		keyID, ciphertext, err := ring.Encrypt([]byte("data"), []byte("aad"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = ring.Decrypt("k2", ciphertext, []byte("aad"))
+		Expect(err).To(MatchError(`unknown key ID "k2"`))
+
+		_, err = ring.Decrypt(keyID, ciphertext, []byte("other"))
+		Expect(err).To(HaveOccurred())
+
+		ciphertext[len(ciphertext)-1] ^= 1
+		_, err = ring.Decrypt(keyID, ciphertext, []byte("aad"))
+		Expect(err).To(HaveOccurred())
+	})
+})
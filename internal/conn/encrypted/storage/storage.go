@@ -0,0 +1,353 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/params"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+	"github.com/riposo/riposo/pkg/util"
+	"github.com/tidwall/gjson"
+)
+
+// Scheme is the scheme prefix of encrypted backends.
+const Scheme = "encrypted"
+
+// envelopeField is the field which holds the encrypted data.
+const envelopeField = "_encrypted"
+
+func init() {
+	storage.Register(Scheme, func(ctx context.Context, u *url.URL, hlp riposo.Helpers) (storage.Backend, error) {
+		_, scheme, ok := strings.Cut(u.Scheme, "+")
+		if !ok || scheme == "" {
+			return nil, fmt.Errorf("missing wrapped storage type in %q, e.g. %s+postgres", u.Scheme, Scheme)
+		}
+
+		var cfg struct {
+			Storage struct {
+				Encryption Config
+			}
+		}
+		if err := hlp.ParseConfig(&cfg); err != nil {
+			return nil, err
+		}
+
+		ring, err := LoadKeyring(cfg.Storage.Encryption.Keyring)
+		if err != nil {
+			return nil, err
+		}
+
+		inner := *u
+		inner.Scheme = scheme
+		sub, err := storage.Connect(ctx, inner.String(), hlp)
+		if err != nil {
+			return nil, err
+		}
+		return New(sub, hlp, ring, cfg.Storage.Encryption.Plaintext), nil
+	})
+}
+
+// Config configures encryption.
+type Config struct {
+	// Keyring is the path to the keyring file.
+	Keyring string
+	// Plaintext fields are stored unencrypted and can be used for filtering
+	// and sorting. Only top-level fields are supported.
+	Plaintext []string
+}
+
+type backend struct {
+	storage.Passthrough
+	hlp   riposo.Helpers
+	ring  *Keyring
+	plain util.Set
+}
+
+// New wraps a backend and encrypts all but the plaintext fields of stored
+// objects. Encrypted data is bound to the path of its object.
+func New(sub storage.Backend, hlp riposo.Helpers, ring *Keyring, plaintext []string) storage.Backend {
+	plain := util.NewSet(plaintext...)
+	plain.Remove(envelopeField)
+	return &backend{Passthrough: storage.Passthrough{Backend: sub}, hlp: hlp, ring: ring, plain: plain}
+}
+
+// Begin implements storage.Backend interface.
+func (b *backend) Begin(ctx context.Context, opt *riposo.TxOptions) (storage.Transaction, error) {
+	tx, err := b.Backend.Begin(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{PassthroughTransaction: storage.PassthroughTransaction{Transaction: tx}, b: b}, nil
+}
+
+type envelope struct {
+	Key  string `json:"key"`
+	Data []byte `json:"data"`
+}
+
+// objectAAD returns the additional data which binds an envelope to the namespace
+// and ID of its object.
+func objectAAD(ns, id string) []byte {
+	return []byte(ns + "\x00" + id)
+}
+
+// seal returns a copy of obj with ID and encrypted extra fields.
+func (b *backend) seal(ns, id string, obj *schema.Object) (*schema.Object, error) {
+	enc := &schema.Object{ID: id, ModTime: obj.ModTime, Deleted: obj.Deleted}
+	if len(obj.Extra) < 3 {
+		enc.Extra = append(enc.Extra, obj.Extra...)
+		return enc, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(obj.Extra, &fields); err != nil {
+		return nil, err
+	}
+
+	secret := make(map[string]json.RawMessage, len(fields))
+	for key, val := range fields {
+		if !b.plain.Has(key) {
+			secret[key] = val
+			delete(fields, key)
+		}
+	}
+
+	if len(secret) != 0 {
+		plaintext, err := json.Marshal(secret)
+		if err != nil {
+			return nil, err
+		}
+
+		keyID, ciphertext, err := b.ring.Encrypt(plaintext, objectAAD(ns, id))
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(envelope{Key: keyID, Data: ciphertext})
+		if err != nil {
+			return nil, err
+		}
+		fields[envelopeField] = data
+	}
+
+	if err := enc.EncodeExtra(fields); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+// open decrypts the extra fields of obj in namespace ns in place. Objects
+// without encrypted fields are left untouched.
+func (b *backend) open(ns string, obj *schema.Object) error {
+	if obj == nil || !gjson.GetBytes(obj.Extra, envelopeField).IsObject() {
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(obj.Extra, &fields); err != nil {
+		return err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(fields[envelopeField], &env); err != nil {
+		return err
+	}
+	delete(fields, envelopeField)
+
+	plaintext, err := b.ring.Decrypt(env.Key, env.Data, objectAAD(ns, obj.ID))
+	if err != nil {
+		return fmt.Errorf("decrypt %s: %w", obj.ID, err)
+	}
+	if err := json.Unmarshal(plaintext, &fields); err != nil {
+		return err
+	}
+
+	obj.Extra = nil // do not overwrite shared buffers
+	return obj.EncodeExtra(fields)
+}
+
+// checkField returns an error if field is encrypted.
+func (b *backend) checkField(field string) error {
+	switch field {
+	case "id", "last_modified", params.ScoreField:
+		return nil
+	}
+
+	top, _, _ := strings.Cut(field, ".")
+	if !b.plain.Has(top) {
+		return schema.InvalidQuery(fmt.Sprintf("field %q is encrypted and cannot be used to filter, sort or aggregate", field))
+	}
+	return nil
+}
+
+func (b *backend) checkCondition(cond params.Condition) error {
+	for _, f := range cond {
+		switch {
+		case f.IsNested():
+			if err := b.checkCondition(f.Nested); err != nil {
+				return err
+			}
+		case f.IsSearch() && f.Field == "":
+			return schema.InvalidQuery("full-text search across encrypted fields is not supported")
+		default:
+			if err := b.checkField(f.Field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// --------------------------------------------------------------------
+
+type transaction struct {
+	storage.PassthroughTransaction
+	b *backend
+}
+
+// ListAll implements storage.Transaction interface.
+func (t *transaction) ListAll(path riposo.Path, opt storage.ListOptions) ([]*schema.Object, error) {
+	if err := t.b.checkCondition(opt.Condition); err != nil {
+		return nil, err
+	}
+	for _, cond := range opt.Pagination {
+		if err := t.b.checkCondition(cond); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range opt.Sort {
+		if err := t.b.checkField(s.Field); err != nil {
+			return nil, err
+		}
+	}
+
+	objs, err := t.Transaction.ListAll(path, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, _ := path.Split()
+	for _, obj := range objs {
+		if err := t.b.open(ns, obj); err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+// CountAll implements storage.Transaction interface.
+func (t *transaction) CountAll(path riposo.Path, opt storage.CountOptions) (int64, error) {
+	if err := t.b.checkCondition(opt.Condition); err != nil {
+		return 0, err
+	}
+	return t.Transaction.CountAll(path, opt)
+}
+
+// Aggregate implements storage.Aggregator interface.
+func (t *transaction) Aggregate(path riposo.Path, opt storage.AggregateOptions) ([]storage.AggregateGroup, error) {
+	if err := t.b.checkCondition(opt.Condition); err != nil {
+		return nil, err
+	}
+	if field := opt.Aggregation.Field; field != "" {
+		if err := t.b.checkField(field); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range opt.Aggregation.GroupBy {
+		if err := t.b.checkField(field); err != nil {
+			return nil, err
+		}
+	}
+	return t.PassthroughTransaction.Aggregate(path, opt)
+}
+
+// Get implements storage.Transaction interface.
+func (t *transaction) Get(path riposo.Path, lock bool) (*schema.Object, error) {
+	obj, err := t.Transaction.Get(path, lock)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, _ := path.Split()
+	if err := t.b.open(ns, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// GetBatch implements storage.Transaction interface.
+func (t *transaction) GetBatch(paths []riposo.Path, lock bool) ([]*schema.Object, error) {
+	objs, err := t.Transaction.GetBatch(paths, lock)
+	if err != nil {
+		return nil, err
+	}
+	for i, obj := range objs {
+		ns, _ := paths[i].Split()
+		if err := t.b.open(ns, obj); err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+// Create implements storage.Transaction interface.
+func (t *transaction) Create(path riposo.Path, obj *schema.Object) error {
+	id := obj.ID
+	if id == "" {
+		id = t.b.hlp.NextID()
+	}
+
+	ns, _ := path.Split()
+	enc, err := t.b.seal(ns, id, obj)
+	if err != nil {
+		return err
+	}
+	if err := t.Transaction.Create(path, enc); err != nil {
+		return err
+	}
+	obj.ID, obj.ModTime, obj.Deleted = enc.ID, enc.ModTime, enc.Deleted
+	return nil
+}
+
+// Update implements storage.Transaction interface.
+func (t *transaction) Update(path riposo.Path, obj *schema.Object) error {
+	ns, id := path.Split()
+	enc, err := t.b.seal(ns, id, obj)
+	if err != nil {
+		return err
+	}
+	if err := t.Transaction.Update(path, enc); err != nil {
+		return err
+	}
+	obj.ID, obj.ModTime, obj.Deleted = enc.ID, enc.ModTime, enc.Deleted
+	return nil
+}
+
+// Delete implements storage.Transaction interface.
+func (t *transaction) Delete(path riposo.Path) (*schema.Object, error) {
+	obj, err := t.Transaction.Delete(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, _ := path.Split()
+	if err := t.b.open(ns, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Restore implements storage.Restorer interface.
+func (t *transaction) Restore(path riposo.Path, obj *schema.Object) error {
+	ns, _ := path.Split()
+	enc, err := t.b.seal(ns, obj.ID, obj)
+	if err != nil {
+		return err
+	}
+	return t.PassthroughTransaction.Restore(path, enc)
+}
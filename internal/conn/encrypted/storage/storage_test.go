@@ -0,0 +1,170 @@
+package storage_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/benbjohnson/clock"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/params"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/internal/conn/encrypted/storage"
+	mems "github.com/riposo/riposo/internal/conn/memory/storage"
+)
+
+const (
+	key1 = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+	key2 = "ZmVkY2JhOTg3NjU0MzIxMA=="
+)
+
+var _ = Describe("Backend", func() {
+	var subject, sub storage.Backend
+	var ring *Keyring
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		var err error
+		ring, err = ParseKeyring(strings.NewReader("k1 " + key1))
+		Expect(err).NotTo(HaveOccurred())
+
+		sub = mems.New(clock.New(), mock.Helpers())
+		subject = New(sub, mock.Helpers(), ring, []string{"title"})
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+	})
+
+	create := func(b storage.Backend, obj *schema.Object) {
+		tx, err := b.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Create("/buckets/*", obj)).To(Succeed())
+		Expect(tx.Commit()).To(Succeed())
+	}
+
+	get := func(b storage.Backend, objID string) *schema.Object {
+		tx, err := b.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		obj, err := tx.Get(riposo.Path("/buckets/"+objID), false)
+		Expect(err).NotTo(HaveOccurred())
+		return obj
+	}
+
+	It("encrypts objects at rest", func() {
+		obj := &schema.Object{Extra: []byte(`{"title":"Hello","secret":"s3cr3t"}`)}
+		create(subject, obj)
+		Expect(obj.ID).NotTo(BeEmpty())
+		Expect(obj.ModTime).NotTo(BeZero())
+		Expect(obj.Extra).To(MatchJSON(`{"title":"Hello","secret":"s3cr3t"}`))
+
+		raw := get(sub, obj.ID)
+		Expect(raw.Get("title").String()).To(Equal("Hello"))
+		Expect(raw.Get("_encrypted.key").String()).To(Equal("k1"))
+		Expect(string(raw.Extra)).NotTo(ContainSubstring("s3cr3t"))
+
+		Expect(get(subject, obj.ID).Extra).To(MatchJSON(`{"title":"Hello","secret":"s3cr3t"}`))
+	})
+
+	It("decrypts lists and batches", func() {
+		create(subject, &schema.Object{ID: "a", Extra: []byte(`{"title":"A","n":1}`)})
+		create(subject, &schema.Object{ID: "b", Extra: []byte(`{"title":"B","n":2}`)})
+
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		objs, err := tx.ListAll("/buckets/*", storage.ListOptions{
+			Condition: params.Condition{params.ParseFilter("title", "B")},
+			Sort:      []params.SortOrder{{Field: "title"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objs).To(HaveLen(1))
+		Expect(objs[0].Extra).To(MatchJSON(`{"title":"B","n":2}`))
+
+		objs, err = tx.GetBatch([]riposo.Path{"/buckets/a", "/buckets/b"}, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(objs).To(HaveLen(2))
+		Expect(objs[0].Extra).To(MatchJSON(`{"title":"A","n":1}`))
+		Expect(objs[1].Extra).To(MatchJSON(`{"title":"B","n":2}`))
+	})
+
+	It("binds encrypted data to objects", func() {
+		create(subject, &schema.Object{ID: "a", Extra: []byte(`{"secret":"a"}`)})
+		create(subject, &schema.Object{ID: "b", Extra: []byte(`{"secret":"b"}`)})
+
+		raw := get(sub, "a")
+		tx, err := sub.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Update("/buckets/b", &schema.Object{ID: "b", Extra: raw.Extra})).To(Succeed())
+		Expect(tx.Commit()).To(Succeed())
+
+		tx, err = subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		_, err = tx.Get("/buckets/b", false)
+		Expect(err).To(MatchError(ContainSubstring("decrypt b")))
+		Expect(tx.Get("/buckets/a", false)).To(HaveField("Extra", MatchJSON(`{"secret":"a"}`)))
+	})
+
+	It("reads unencrypted objects", func() {
+		create(sub, &schema.Object{ID: "plain", Extra: []byte(`{"secret":"s"}`)})
+		Expect(get(subject, "plain").Extra).To(MatchJSON(`{"secret":"s"}`))
+	})
+
+	It("supports key rotation", func() {
+		create(subject, &schema.Object{ID: "old", Extra: []byte(`{"n":1}`)})
+
+		rotated, err := ParseKeyring(strings.NewReader("k1 " + key1 + "\nk2 " + key2))
+		Expect(err).NotTo(HaveOccurred())
+		subject = New(sub, mock.Helpers(), rotated, nil)
+
+		create(subject, &schema.Object{ID: "new", Extra: []byte(`{"n":2}`)})
+		Expect(get(sub, "new").Get("_encrypted.key").String()).To(Equal("k2"))
+		Expect(get(subject, "old").Extra).To(MatchJSON(`{"n":1}`))
+		Expect(get(subject, "new").Extra).To(MatchJSON(`{"n":2}`))
+	})
+
+	It("rejects filters on encrypted fields", func() {
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		_, err = tx.ListAll("/buckets/*", storage.ListOptions{
+			Condition: params.Condition{params.ParseFilter("secret", "x")},
+		})
+		Expect(err).To(MatchError(ContainSubstring(`field "secret" is encrypted`)))
+
+		_, err = tx.ListAll("/buckets/*", storage.ListOptions{
+			Sort: []params.SortOrder{{Field: "secret.nested"}},
+		})
+		Expect(err).To(MatchError(ContainSubstring(`field "secret.nested" is encrypted`)))
+
+		_, err = tx.CountAll("/buckets/*", storage.CountOptions{
+			Condition: params.Condition{params.SearchFilter("x")},
+		})
+		Expect(err).To(MatchError(ContainSubstring("full-text search across encrypted fields is not supported")))
+
+		_, err = tx.CountAll("/buckets/*", storage.CountOptions{
+			Condition: params.Condition{params.ParseFilter("id", "x"), params.ParseFilter("title", "x")},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "internal/conn/encrypted/storage")
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"net/url"
+	"sort"
 	"sync"
 	"time"
 
@@ -37,7 +38,7 @@ func (it *item) Expired(now time.Time) bool {
 
 type backend struct {
 	keys map[string]*item
-	mu   sync.Mutex
+	mu   sync.RWMutex
 
 	closer chan struct{}
 }
@@ -57,10 +58,21 @@ func (*backend) Ping(_ context.Context) error {
 	return nil
 }
 
-// Begin implements cache.Backend interface.
-func (b *backend) Begin(_ context.Context) (cache.Transaction, error) {
+// Begin implements cache.Backend interface. Writable transactions are
+// serialized, read-only transactions may run concurrently. Writes are buffered
+// and only applied on commit.
+func (b *backend) Begin(ctx context.Context, opt *riposo.TxOptions) (cache.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if opt.IsReadOnly() {
+		b.mu.RLock()
+		return &transaction{b: b, ctx: ctx, readOnly: true}, nil
+	}
+
 	b.mu.Lock()
-	return &transaction{b: b}, nil
+	return &transaction{b: b, ctx: ctx}, nil
 }
 
 // Close implements cache.Backend interface.
@@ -104,11 +116,19 @@ func (b *backend) reapExpired(now time.Time) {
 // --------------------------------------------------------------------
 
 type transaction struct {
-	b *backend
+	b   *backend
+	ctx context.Context
+
+	writes map[string]*item // nil items mark deleted keys
+	spts   []savepoint
 
-	xkeys map[string]*item
+	done, flushed, readOnly bool
+}
 
-	done, flushed bool
+type savepoint struct {
+	name    string
+	writes  map[string]*item
+	flushed bool
 }
 
 // Commit implements cache.Transaction interface.
@@ -117,8 +137,18 @@ func (t *transaction) Commit() error {
 		return cache.ErrTxDone
 	}
 	t.done = true
-	defer t.b.mu.Unlock()
+	defer t.unlock()
 
+	if t.flushed {
+		t.b.keys = make(map[string]*item, len(t.writes))
+	}
+	for k, v := range t.writes {
+		if v == nil {
+			delete(t.b.keys, k)
+		} else {
+			t.b.keys[k] = v
+		}
+	}
 	return nil
 }
 
@@ -128,45 +158,106 @@ func (t *transaction) Rollback() error {
 		return cache.ErrTxDone
 	}
 	t.done = true
-	defer t.b.mu.Unlock()
+	t.unlock()
 
-	if t.flushed {
-		t.b.keys = t.xkeys
-		return nil
+	return nil
+}
+
+// Savepoint implements cache.Transaction interface.
+func (t *transaction) Savepoint(name string) error {
+	if err := t.check(); err != nil {
+		return err
 	}
 
-	for k, v := range t.xkeys {
-		if v == nil {
-			delete(t.b.keys, k)
-		} else {
-			t.b.keys[k] = v
-		}
+	t.spts = append(t.spts, savepoint{
+		name:    name,
+		writes:  copyItems(t.writes),
+		flushed: t.flushed,
+	})
+	return nil
+}
+
+// RollbackTo implements cache.Transaction interface.
+func (t *transaction) RollbackTo(name string) error {
+	if err := t.check(); err != nil {
+		return err
 	}
+
+	pos := t.findSavepoint(name)
+	if pos < 0 {
+		return cache.ErrNoSavepoint
+	}
+
+	// restore state, keep a copy in the savepoint to allow subsequent rollbacks
+	sp := t.spts[pos]
+	t.writes = copyItems(sp.writes)
+	t.flushed = sp.flushed
+	t.spts = t.spts[:pos+1]
 	return nil
 }
 
-// Flush implements cache.Transaction interface.
-func (t *transaction) Flush() error {
+// Release implements cache.Transaction interface.
+func (t *transaction) Release(name string) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	pos := t.findSavepoint(name)
+	if pos < 0 {
+		return cache.ErrNoSavepoint
+	}
+
+	t.spts = t.spts[:pos]
+	return nil
+}
+
+func (t *transaction) unlock() {
+	if t.readOnly {
+		t.b.mu.RUnlock()
+	} else {
+		t.b.mu.Unlock()
+	}
+}
+
+// check returns an error if the transaction can no longer be used.
+func (t *transaction) check() error {
 	if t.done {
 		return cache.ErrTxDone
 	}
+	return t.ctx.Err()
+}
 
-	if !t.flushed {
-		t.flushed = true
-		t.xkeys = t.b.keys
+func (t *transaction) findSavepoint(name string) int {
+	for i := len(t.spts) - 1; i >= 0; i-- {
+		if t.spts[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Flush implements cache.Transaction interface.
+func (t *transaction) Flush() error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return cache.ErrReadOnly
 	}
-	t.b.keys = make(map[string]*item)
+
+	t.flushed = true
+	t.writes = nil
 	return nil
 }
 
 // Get implements cache.Transaction interface.
 func (t *transaction) Get(key string) ([]byte, error) {
-	if t.done {
-		return nil, cache.ErrTxDone
+	if err := t.check(); err != nil {
+		return nil, err
 	}
 
-	it, ok := t.b.keys[key]
-	if !ok || it.Expired(time.Now()) {
+	it := t.lookup(key)
+	if it == nil || it.Expired(time.Now()) {
 		return nil, cache.ErrNotFound
 	}
 
@@ -181,53 +272,105 @@ func (t *transaction) Set(key string, val []byte, exp time.Time) error {
 		return err
 	}
 
-	if t.done {
-		return cache.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
 	}
-
-	t.backup(key)
-
-	it, ok := t.b.keys[key]
-	if !ok {
-		it = new(item)
-		t.b.keys[key] = it
+	if t.readOnly {
+		return cache.ErrReadOnly
 	}
 
-	it.exp = exp
-	it.val = append(it.val[:0], val...)
-
+	t.write(key, (&item{val: val, exp: exp}).Copy())
 	return nil
 }
 
 // Del implements cache.Transaction interface.
 func (t *transaction) Del(key string) error {
-	if t.done {
-		return cache.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return cache.ErrReadOnly
 	}
 
-	t.backup(key)
+	it := t.lookup(key)
+	t.write(key, nil)
 
-	it, ok := t.b.keys[key]
-	if ok {
-		delete(t.b.keys, key)
+	if it == nil || it.Expired(time.Now()) {
+		return cache.ErrNotFound
 	}
+	return nil
+}
 
-	if !ok || it.Expired(time.Now()) {
-		return cache.ErrNotFound
+// Scan implements cache.Scanner interface.
+func (t *transaction) Scan(fn func(string, []byte, time.Time) error) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	items := t.items()
+	keys := make([]string, 0, len(items))
+	for key, it := range items {
+		if !it.Expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		it := items[key].Copy()
+		if err := fn(key, it.val, it.exp); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (t *transaction) backup(key string) {
+// lookup returns the item stored under key as seen by the transaction.
+func (t *transaction) lookup(key string) *item {
+	if it, ok := t.writes[key]; ok {
+		return it
+	}
 	if t.flushed {
-		return
+		return nil
+	}
+	return t.b.keys[key]
+}
+
+// items returns all items as seen by the transaction.
+func (t *transaction) items() map[string]*item {
+	items := make(map[string]*item)
+	if !t.flushed {
+		for k, v := range t.b.keys {
+			items[k] = v
+		}
+	}
+
+	for k, v := range t.writes {
+		if v == nil {
+			delete(items, k)
+		} else {
+			items[k] = v
+		}
+	}
+	return items
+}
+
+func (t *transaction) write(key string, it *item) {
+	if t.writes == nil {
+		t.writes = make(map[string]*item)
 	}
+	t.writes[key] = it
+}
 
-	if t.xkeys == nil {
-		t.xkeys = make(map[string]*item)
+func copyItems(src map[string]*item) map[string]*item {
+	if src == nil {
+		return nil
 	}
 
-	if _, ok := t.xkeys[key]; !ok {
-		t.xkeys[key] = t.b.keys[key].Copy()
+	dst := make(map[string]*item, len(src))
+	for k, v := range src {
+		dst[k] = v
 	}
+	return dst
 }
@@ -1,10 +1,13 @@
 package cache_test
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/riposo/riposo/pkg/conn/cache"
 	"github.com/riposo/riposo/pkg/conn/cache/testdata"
+	"github.com/riposo/riposo/pkg/riposo"
 
 	. "github.com/bsm/ginkgo/v2"
 	. "github.com/bsm/gomega"
@@ -27,6 +30,59 @@ var _ = Describe("Backend", func() {
 	Describe("common", func() {
 		testdata.BehavesLikeBackend(&link)
 	})
+
+	It("does not block concurrent readers", func() {
+		ctx := context.Background()
+		exp := time.Now().Add(time.Hour)
+
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tx.Set("foo", []byte("bar"), exp)).To(Succeed())
+		Expect(tx.Commit()).To(Succeed())
+
+		tx1, err := subject.Begin(ctx, &riposo.TxOptions{ReadOnly: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer tx1.Rollback()
+
+		tx2, err := subject.Begin(ctx, &riposo.TxOptions{ReadOnly: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer tx2.Rollback()
+
+		Expect(tx1.Get("foo")).To(Equal([]byte("bar")))
+		Expect(tx2.Get("foo")).To(Equal([]byte("bar")))
+		Expect(tx2.Set("foo", []byte("baz"), exp)).To(MatchError(cache.ErrReadOnly))
+	})
+
+	It("serializes writers", func() {
+		ctx := context.Background()
+		exp := time.Now().Add(time.Hour)
+
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tx.Set("nonce", []byte("x"), exp)).To(Succeed())
+		Expect(tx.Commit()).To(Succeed())
+
+		tx1, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx1.Rollback()
+
+		errs := make(chan error, 1)
+		go func() {
+			tx2, err := subject.Begin(ctx, nil)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer tx2.Rollback()
+
+			errs <- tx2.Del("nonce")
+		}()
+
+		Expect(tx1.Del("nonce")).To(Succeed())
+		Consistently(errs).ShouldNot(Receive())
+		Expect(tx1.Commit()).To(Succeed())
+		Eventually(errs).Should(Receive(MatchError(cache.ErrNotFound)))
+	})
 })
 
 func TestSuite(t *testing.T) {
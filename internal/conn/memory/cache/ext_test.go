@@ -2,5 +2,5 @@ package cache
 
 // NumEntries is a test helper.
 func (t *transaction) NumEntries() (int64, error) {
-	return int64(len(t.b.keys)), nil
+	return int64(len(t.items())), nil
 }
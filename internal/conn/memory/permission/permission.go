@@ -23,7 +23,7 @@ type backend struct {
 	users map[string]util.Set
 	perms map[riposo.Path]map[string]util.Set
 
-	mu sync.Mutex
+	mu sync.RWMutex
 }
 
 // New inits a new in-memory permission backend. Please use for development and testing only!
@@ -39,10 +39,20 @@ func (*backend) Ping(_ context.Context) error {
 	return nil
 }
 
-// Begin implements permission.Backend interface.
-func (b *backend) Begin(_ context.Context) (permission.Transaction, error) {
+// Begin implements permission.Backend interface. Read-only transactions may
+// run concurrently, all other transactions are serialized.
+func (b *backend) Begin(ctx context.Context, opt *riposo.TxOptions) (permission.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if opt.IsReadOnly() {
+		b.mu.RLock()
+		return &transaction{b: b, ctx: ctx, readOnly: true}, nil
+	}
+
 	b.mu.Lock()
-	return &transaction{b: b}, nil
+	return &transaction{b: b, ctx: ctx}, nil
 }
 
 // Close implements permission.Backend interface.
@@ -53,12 +63,125 @@ func (*backend) Close() error {
 // --------------------------------------------------------------------
 
 type transaction struct {
-	b *backend
+	b   *backend
+	ctx context.Context
 
 	xusers map[string]util.Set
 	xperms map[riposo.Path]map[string]util.Set
+	spts   []savepoint
+
+	done, flushed, readOnly bool
+}
+
+// savepoint records the state of users and permissions before their first
+// modification after the savepoint was created.
+type savepoint struct {
+	name  string
+	undo  undoLog
+	flush *flushUndo
+}
+
+// flushUndo records the state before the first flush after a savepoint.
+type flushUndo struct {
+	users, xusers map[string]util.Set
+	perms, xperms map[riposo.Path]map[string]util.Set
+	undo          undoLog
+	flushed       bool
+}
+
+func (sp *savepoint) restore(t *transaction) {
+	if f := sp.flush; f != nil {
+		t.b.users, t.b.perms = f.users, f.perms
+		t.xusers, t.xperms = f.xusers, f.xperms
+		t.flushed = f.flushed
+		f.undo.apply(t.b.users, t.b.perms)
+	} else {
+		sp.undo.apply(t.b.users, t.b.perms)
+	}
+}
+
+// merge merges a subsequent savepoint into sp.
+func (sp *savepoint) merge(next *savepoint) {
+	if sp.flush != nil {
+		return
+	}
+	if next.flush != nil {
+		sp.undo.merge(next.flush.undo)
+		next.flush.undo = sp.undo
+		sp.flush = next.flush
+		sp.undo = next.undo
+		return
+	}
+	sp.undo.merge(next.undo)
+}
+
+// undoLog maps user IDs and paths to copies of their original values, nil
+// values indicate that the entry did not exist.
+type undoLog struct {
+	users map[string]util.Set
+	perms map[riposo.Path]map[string]util.Set
+}
 
-	done, flushed bool
+func (l *undoLog) recordUser(users map[string]util.Set, userID string) {
+	if l.users == nil {
+		l.users = make(map[string]util.Set)
+	}
+	if _, ok := l.users[userID]; !ok {
+		var cp util.Set
+		if set, ok := users[userID]; ok {
+			cp = set.Copy()
+		}
+		l.users[userID] = cp
+	}
+}
+
+func (l *undoLog) recordPerms(perms map[riposo.Path]map[string]util.Set, path riposo.Path) {
+	if l.perms == nil {
+		l.perms = make(map[riposo.Path]map[string]util.Set)
+	}
+	if _, ok := l.perms[path]; !ok {
+		var cp map[string]util.Set
+		if set, ok := perms[path]; ok {
+			cp = copyPerms(set)
+		}
+		l.perms[path] = cp
+	}
+}
+
+func (l *undoLog) merge(o undoLog) {
+	for userID, set := range o.users {
+		if l.users == nil {
+			l.users = make(map[string]util.Set)
+		}
+		if _, ok := l.users[userID]; !ok {
+			l.users[userID] = set
+		}
+	}
+	for path, perms := range o.perms {
+		if l.perms == nil {
+			l.perms = make(map[riposo.Path]map[string]util.Set)
+		}
+		if _, ok := l.perms[path]; !ok {
+			l.perms[path] = perms
+		}
+	}
+}
+
+func (l undoLog) apply(users map[string]util.Set, perms map[riposo.Path]map[string]util.Set) {
+	for userID, set := range l.users {
+		if set == nil {
+			delete(users, userID)
+		} else {
+			users[userID] = set
+		}
+	}
+	for path, set := range l.perms {
+		if set == nil {
+			delete(perms, path)
+		} else {
+			perms[path] = set
+		}
+	}
 }
 
 // Commit implements permission.Transaction interface.
@@ -67,7 +190,7 @@ func (t *transaction) Commit() error {
 		return permission.ErrTxDone
 	}
 	t.done = true
-	defer t.b.mu.Unlock()
+	defer t.unlock()
 
 	return nil
 }
@@ -78,8 +201,11 @@ func (t *transaction) Rollback() error {
 		return permission.ErrTxDone
 	}
 	t.done = true
-	defer t.b.mu.Unlock()
+	defer t.unlock()
 
+	if t.readOnly {
+		return nil
+	}
 	if t.flushed {
 		t.b.users, t.b.perms = t.xusers, t.xperms
 		return nil
@@ -103,12 +229,107 @@ func (t *transaction) Rollback() error {
 	return nil
 }
 
-// Flush implements permission.Transaction interface.
-func (t *transaction) Flush() error {
+// Savepoint implements permission.Transaction interface.
+func (t *transaction) Savepoint(name string) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	t.spts = append(t.spts, savepoint{name: name})
+	return nil
+}
+
+// RollbackTo implements permission.Transaction interface.
+func (t *transaction) RollbackTo(name string) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	pos := t.findSavepoint(name)
+	if pos < 0 {
+		return permission.ErrNoSavepoint
+	}
+	if t.readOnly {
+		t.spts = t.spts[:pos+1]
+		return nil
+	}
+
+	// undo changes in reverse order, re-create savepoint to allow subsequent
+	// rollbacks
+	for i := len(t.spts) - 1; i >= pos; i-- {
+		t.spts[i].restore(t)
+	}
+	t.spts = t.spts[:pos]
+	return t.Savepoint(name)
+}
+
+// Release implements permission.Transaction interface.
+func (t *transaction) Release(name string) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	pos := t.findSavepoint(name)
+	if pos < 0 {
+		return permission.ErrNoSavepoint
+	}
+
+	// retain undo logs of released savepoints in the parent savepoint
+	if pos != 0 && !t.readOnly {
+		for i := pos; i < len(t.spts); i++ {
+			t.spts[pos-1].merge(&t.spts[i])
+		}
+	}
+	t.spts = t.spts[:pos]
+	return nil
+}
+
+func (t *transaction) unlock() {
+	if t.readOnly {
+		t.b.mu.RUnlock()
+	} else {
+		t.b.mu.Unlock()
+	}
+}
+
+// check returns an error if the transaction can no longer be used.
+func (t *transaction) check() error {
 	if t.done {
 		return permission.ErrTxDone
 	}
+	return t.ctx.Err()
+}
+
+func (t *transaction) findSavepoint(name string) int {
+	for i := len(t.spts) - 1; i >= 0; i-- {
+		if t.spts[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
 
+// Flush implements permission.Transaction interface.
+func (t *transaction) Flush() error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return permission.ErrReadOnly
+	}
+
+	if n := len(t.spts); n != 0 && t.spts[n-1].flush == nil {
+		sp := &t.spts[n-1]
+		sp.flush = &flushUndo{
+			users:   t.b.users,
+			perms:   t.b.perms,
+			xusers:  t.xusers,
+			xperms:  t.xperms,
+			undo:    sp.undo,
+			flushed: t.flushed,
+		}
+		sp.undo = undoLog{}
+	}
 	if !t.flushed {
 		t.flushed = true
 		t.xusers = t.b.users
@@ -121,8 +342,8 @@ func (t *transaction) Flush() error {
 
 // GetUserPrincipals implements permission.Transaction interface.
 func (t *transaction) GetUserPrincipals(userID string) ([]string, error) {
-	if t.done {
-		return nil, permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return nil, err
 	}
 
 	set := t.b.users[userID].Copy()
@@ -145,8 +366,11 @@ func (t *transaction) GetUserPrincipals(userID string) ([]string, error) {
 
 // AddUserPrincipal implements permission.Transaction interface.
 func (t *transaction) AddUserPrincipal(principal string, userIDs []string) error {
-	if t.done {
-		return permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return permission.ErrReadOnly
 	}
 
 	for _, userID := range userIDs {
@@ -165,8 +389,11 @@ func (t *transaction) AddUserPrincipal(principal string, userIDs []string) error
 
 // RemoveUserPrincipal implements permission.Transaction interface.
 func (t *transaction) RemoveUserPrincipal(principal string, userIDs []string) error {
-	if t.done {
-		return permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return permission.ErrReadOnly
 	}
 
 	for _, userID := range userIDs {
@@ -181,8 +408,11 @@ func (t *transaction) RemoveUserPrincipal(principal string, userIDs []string) er
 
 // PurgeUserPrincipals implements permission.Transaction interface.
 func (t *transaction) PurgeUserPrincipals(principals []string) error {
-	if t.done {
-		return permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return permission.ErrReadOnly
 	}
 
 	for _, principal := range principals {
@@ -197,8 +427,8 @@ func (t *transaction) PurgeUserPrincipals(principals []string) error {
 
 // GetACEPrincipals implements permission.Transaction interface.
 func (t *transaction) GetACEPrincipals(ent permission.ACE) ([]string, error) {
-	if t.done {
-		return nil, permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return nil, err
 	}
 
 	if perms, ok := t.b.perms[ent.Path]; ok {
@@ -211,8 +441,11 @@ func (t *transaction) GetACEPrincipals(ent permission.ACE) ([]string, error) {
 
 // AddACEPrincipal implements permission.Transaction interface.
 func (t *transaction) AddACEPrincipal(principal string, ent permission.ACE) error {
-	if t.done {
-		return permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return permission.ErrReadOnly
 	}
 
 	t.backupPerms(ent.Path)
@@ -234,8 +467,11 @@ func (t *transaction) AddACEPrincipal(principal string, ent permission.ACE) erro
 
 // RemoveACEPrincipal implements permission.Transaction interface.
 func (t *transaction) RemoveACEPrincipal(principal string, ent permission.ACE) error {
-	if t.done {
-		return permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return permission.ErrReadOnly
 	}
 
 	if perms, ok := t.b.perms[ent.Path]; ok {
@@ -250,8 +486,8 @@ func (t *transaction) RemoveACEPrincipal(principal string, ent permission.ACE) e
 
 // GetAllACEPrincipals implements permission.Transaction interface.
 func (t *transaction) GetAllACEPrincipals(ents []permission.ACE) ([]string, error) {
-	if t.done {
-		return nil, permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return nil, err
 	}
 
 	if len(ents) == 0 {
@@ -271,8 +507,8 @@ func (t *transaction) GetAllACEPrincipals(ents []permission.ACE) ([]string, erro
 
 // GetPermissions implements permission.Transaction interface.
 func (t *transaction) GetPermissions(path riposo.Path) (schema.PermissionSet, error) {
-	if t.done {
-		return nil, permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return nil, err
 	}
 
 	perms := make(schema.PermissionSet, len(t.b.perms[path]))
@@ -282,10 +518,26 @@ func (t *transaction) GetPermissions(path riposo.Path) (schema.PermissionSet, er
 	return perms, nil
 }
 
+// GetAllPermissions implements permission.Transaction interface.
+func (t *transaction) GetAllPermissions(paths []riposo.Path) ([]schema.PermissionSet, error) {
+	sets := make([]schema.PermissionSet, 0, len(paths))
+	for _, path := range paths {
+		perms, err := t.GetPermissions(path)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, perms)
+	}
+	return sets, nil
+}
+
 // CreatePermissions implements permission.Transaction interface.
 func (t *transaction) CreatePermissions(path riposo.Path, set schema.PermissionSet) error {
-	if t.done {
-		return permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return permission.ErrReadOnly
 	}
 
 	t.backupPerms(path)
@@ -311,8 +563,11 @@ func (t *transaction) CreatePermissions(path riposo.Path, set schema.PermissionS
 
 // MergePermissions implements permission.Transaction interface.
 func (t *transaction) MergePermissions(path riposo.Path, set schema.PermissionSet) error {
-	if t.done {
-		return permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return permission.ErrReadOnly
 	}
 
 	t.backupPerms(path)
@@ -334,8 +589,11 @@ func (t *transaction) MergePermissions(path riposo.Path, set schema.PermissionSe
 
 // DeletePermissions implements permission.Transaction interface.
 func (t *transaction) DeletePermissions(paths []riposo.Path) error {
-	if t.done {
-		return permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return permission.ErrReadOnly
 	}
 
 	if len(paths) == 0 {
@@ -355,8 +613,8 @@ func (t *transaction) DeletePermissions(paths []riposo.Path) error {
 
 // GetAccessiblePaths implements permission.Transaction interface.
 func (t *transaction) GetAccessiblePaths(dst []riposo.Path, principals []string, ents []permission.ACE) ([]riposo.Path, error) {
-	if t.done {
-		return nil, permission.ErrTxDone
+	if err := t.check(); err != nil {
+		return nil, err
 	}
 
 	if len(principals) == 0 || len(ents) == 0 {
@@ -376,7 +634,55 @@ func (t *transaction) GetAccessiblePaths(dst []riposo.Path, principals []string,
 	return dst, nil
 }
 
+// ScanUserPrincipals implements permission.Scanner interface.
+func (t *transaction) ScanUserPrincipals(fn func(string, []string) error) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	userIDs := make([]string, 0, len(t.b.users))
+	for userID, set := range t.b.users {
+		if set.Len() != 0 {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+
+	for _, userID := range userIDs {
+		if err := fn(userID, t.b.users[userID].Slice()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanPermissions implements permission.Scanner interface.
+func (t *transaction) ScanPermissions(fn func(riposo.Path, schema.PermissionSet) error) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	paths := make([]riposo.Path, 0, len(t.b.perms))
+	for path, perms := range t.b.perms {
+		if len(perms) != 0 {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool { return paths[i] < paths[j] })
+
+	for _, path := range paths {
+		set, _ := t.GetPermissions(path)
+		if err := fn(path, set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (t *transaction) backupUser(userID string) {
+	if n := len(t.spts); n != 0 {
+		t.spts[n-1].undo.recordUser(t.b.users, userID)
+	}
 	if t.flushed {
 		return
 	}
@@ -391,6 +697,9 @@ func (t *transaction) backupUser(userID string) {
 }
 
 func (t *transaction) backupPerms(path riposo.Path) {
+	if n := len(t.spts); n != 0 {
+		t.spts[n-1].undo.recordPerms(t.b.perms, path)
+	}
 	if t.flushed {
 		return
 	}
@@ -400,12 +709,16 @@ func (t *transaction) backupPerms(path riposo.Path) {
 	}
 
 	if _, ok := t.xperms[path]; !ok {
-		pristine := t.b.perms[path]
-		t.xperms[path] = make(map[string]util.Set, len(pristine))
-		for k, v := range pristine {
-			t.xperms[path][k] = v.Copy()
-		}
+		t.xperms[path] = copyPerms(t.b.perms[path])
+	}
+}
+
+func copyPerms(perms map[string]util.Set) map[string]util.Set {
+	cp := make(map[string]util.Set, len(perms))
+	for k, v := range perms {
+		cp[k] = v.Copy()
 	}
+	return cp
 }
 
 func match(ents []permission.ACE, path riposo.Path, perm string) bool {
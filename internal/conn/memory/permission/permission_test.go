@@ -1,10 +1,13 @@
 package permission_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/riposo/riposo/pkg/conn/permission"
 	"github.com/riposo/riposo/pkg/conn/permission/testdata"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
 
 	. "github.com/bsm/ginkgo/v2"
 	. "github.com/bsm/gomega"
@@ -27,6 +30,61 @@ var _ = Describe("Backend", func() {
 	Describe("common", func() {
 		testdata.BehavesLikeBackend(&link)
 	})
+
+	It("rolls back released savepoints", func() {
+		tx, err := subject.Begin(context.Background(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.CreatePermissions("/buckets/a", schema.PermissionSet{"write": {"alice"}})).To(Succeed())
+		Expect(tx.Savepoint("sp1")).To(Succeed())
+		Expect(tx.AddUserPrincipal("team", []string{"alice"})).To(Succeed())
+		Expect(tx.Savepoint("sp2")).To(Succeed())
+		Expect(tx.Flush()).To(Succeed())
+		Expect(tx.CreatePermissions("/buckets/c", schema.PermissionSet{"write": {"bob"}})).To(Succeed())
+		Expect(tx.Savepoint("sp3")).To(Succeed())
+		Expect(tx.MergePermissions("/buckets/c", schema.PermissionSet{"read": {"bob"}})).To(Succeed())
+		Expect(tx.Release("sp2")).To(Succeed())
+
+		Expect(tx.RollbackTo("sp1")).To(Succeed())
+		Expect(tx.GetPermissions("/buckets/a")).To(Equal(schema.PermissionSet{"write": {"alice"}}))
+		Expect(tx.GetPermissions("/buckets/c")).To(BeEmpty())
+		Expect(tx.GetUserPrincipals("alice")).To(ConsistOf("alice", riposo.Authenticated, riposo.Everyone))
+	})
+
+	It("supports concurrent read-only transactions", func() {
+		ctx := context.Background()
+		opt := &riposo.TxOptions{ReadOnly: true}
+
+		tx1, err := subject.Begin(ctx, opt)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx1.Rollback()
+
+		tx2, err := subject.Begin(ctx, opt)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx2.Rollback()
+
+		Expect(tx1.GetUserPrincipals("alice")).To(ConsistOf("alice", riposo.Authenticated, riposo.Everyone))
+		Expect(tx2.AddUserPrincipal("team", []string{"alice"})).To(MatchError(permission.ErrReadOnly))
+		Expect(tx2.Flush()).To(MatchError(permission.ErrReadOnly))
+
+		// writers wait for readers
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+
+			tx, err := subject.Begin(ctx, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tx.AddUserPrincipal("team", []string{"alice"})).To(Succeed())
+			Expect(tx.Commit()).To(Succeed())
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+
+		Expect(tx1.Commit()).To(Succeed())
+		Expect(tx2.Rollback()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+	})
 })
 
 func TestSuite(t *testing.T) {
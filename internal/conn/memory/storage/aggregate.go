@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/params"
+	"github.com/riposo/riposo/pkg/schema"
+	"github.com/tidwall/gjson"
+)
+
+type aggregateState struct {
+	keys     []schema.Value
+	count    int64
+	num      int64 // number of numeric values
+	sum      float64
+	min, max float64
+}
+
+func (s *aggregateState) Add(val schema.Value) {
+	if val.Type != gjson.Number {
+		return
+	}
+
+	if s.num == 0 || val.Num < s.min {
+		s.min = val.Num
+	}
+	if s.num == 0 || val.Num > s.max {
+		s.max = val.Num
+	}
+	s.sum += val.Num
+	s.num++
+}
+
+func (s *aggregateState) Result(fn params.AggregateFunc) schema.Value {
+	if fn == params.AggregateCount {
+		return numericValue(float64(s.count))
+	} else if s.num == 0 {
+		return schema.ParseValue("")
+	}
+
+	switch fn {
+	case params.AggregateSum:
+		return numericValue(s.sum)
+	case params.AggregateAvg:
+		return numericValue(s.sum / float64(s.num))
+	case params.AggregateMin:
+		return numericValue(s.min)
+	case params.AggregateMax:
+		return numericValue(s.max)
+	}
+	return schema.ParseValue("")
+}
+
+func aggregate(objs []*schema.Object, agg params.Aggregation) []storage.AggregateGroup {
+	var states []*aggregateState
+	index := make(map[string]*aggregateState)
+	if len(agg.GroupBy) == 0 {
+		states = append(states, &aggregateState{keys: []schema.Value{}})
+		index[""] = states[0]
+	}
+
+	var id strings.Builder
+	for _, o := range objs {
+		keys := make([]schema.Value, 0, len(agg.GroupBy))
+		id.Reset()
+		for _, field := range agg.GroupBy {
+			key := normValue(o.Get(field))
+			keys = append(keys, key)
+			id.WriteString(groupKey(key))
+			id.WriteByte(0)
+		}
+
+		state, ok := index[id.String()]
+		if !ok {
+			state = &aggregateState{keys: keys}
+			index[id.String()] = state
+			states = append(states, state)
+		}
+
+		state.count++
+		if agg.Func != params.AggregateCount {
+			state.Add(o.Get(agg.Field))
+		}
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		for n := range agg.GroupBy {
+			if x := compare(states[i].keys[n], states[j].keys[n]); x != 0 {
+				return x < 0
+			}
+		}
+		return false
+	})
+
+	groups := make([]storage.AggregateGroup, 0, len(states))
+	for _, state := range states {
+		groups = append(groups, storage.AggregateGroup{
+			Keys:  state.keys,
+			Value: state.Result(agg.Func),
+		})
+	}
+	return groups
+}
+
+// normValue strips parse positions and normalizes missing values to null.
+func normValue(val schema.Value) schema.Value {
+	if val.IsNull() {
+		return schema.ParseValue("")
+	}
+	return schema.ParseValue(val.Raw)
+}
+
+func numericValue(f float64) schema.Value {
+	return schema.ParseValue(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+func groupKey(val schema.Value) string {
+	switch val.Type {
+	case gjson.Number:
+		return "n" + strconv.FormatFloat(val.Num, 'g', -1, 64)
+	case gjson.String:
+		return "s" + val.Str
+	}
+	return "r" + val.Raw
+}
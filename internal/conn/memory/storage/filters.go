@@ -3,6 +3,7 @@ package storage
 import (
 	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/riposo/riposo/pkg/params"
 	"github.com/riposo/riposo/pkg/schema"
@@ -10,8 +11,9 @@ import (
 )
 
 type objectSlice struct {
-	Slice []*schema.Object
-	Sort  []params.SortOrder
+	Slice  []*schema.Object
+	Sort   []params.SortOrder
+	Scores map[*schema.Object]int
 }
 
 func (s *objectSlice) Len() int      { return len(s.Slice) }
@@ -19,6 +21,16 @@ func (s *objectSlice) Swap(i, j int) { s.Slice[i], s.Slice[j] = s.Slice[j], s.Sl
 func (s *objectSlice) Less(i, j int) bool {
 	o1, o2 := s.Slice[i], s.Slice[j]
 	for _, so := range s.Sort {
+		if so.Field == params.ScoreField {
+			if x1, x2 := s.Scores[o1], s.Scores[o2]; x1 != x2 {
+				if so.Descending {
+					return x1 > x2
+				}
+				return x1 < x2
+			}
+			continue
+		}
+
 		if x := compare(o1.Get(so.Field), o2.Get(so.Field)); x != 0 {
 			if so.Descending {
 				return x > 0
@@ -89,8 +101,21 @@ func rank(t gjson.Type) int {
 // --------------------------------------------------------------------
 
 func match(o *schema.Object, f params.Filter) bool {
-	val := o.Get(f.Field)
+	switch f.Operator {
+	case params.OperatorSEARCH:
+		return searchScore(o, f) != 0
+	case params.OperatorAND:
+		return conditionMatch(o, f.Nested)
+	case params.OperatorOR:
+		for _, nf := range f.Nested {
+			if match(o, nf) {
+				return true
+			}
+		}
+		return false
+	}
 
+	val := o.Get(f.Field)
 	switch f.Operator {
 	case params.OperatorGT:
 		return compare(val, f.Value(0)) > 0
@@ -146,6 +171,86 @@ func match(o *schema.Object, f params.Filter) bool {
 	return false
 }
 
+// searchScore returns a relevance score, i.e. the number of query term
+// occurrences within the searched text, or 0 if the object does not match.
+// Unlike PostgreSQL, this is a simple tokenized fallback without stemming:
+// all terms must be present and terms prefixed with '-' must be absent.
+func searchScore(o *schema.Object, f params.Filter) int {
+	var text []string
+	switch f.Field {
+	case "":
+		text = appendStrings(text, gjson.ParseBytes(o.Extra))
+	case "id":
+		text = append(text, o.ID)
+	case "last_modified":
+		return 0
+	default:
+		text = appendStrings(text, gjson.Parse(o.Get(f.Field).Raw))
+	}
+
+	counts := make(map[string]int)
+	for _, s := range text {
+		for _, tok := range tokenize(s) {
+			counts[tok]++
+		}
+	}
+
+	var score, terms int
+	for _, term := range strings.Fields(f.Value(0).String()) {
+		exclude := strings.HasPrefix(term, "-")
+		for _, tok := range tokenize(term) {
+			n := counts[tok]
+			if exclude && n != 0 || !exclude && n == 0 {
+				return 0
+			}
+			score += n
+			terms++
+		}
+	}
+
+	if terms == 0 {
+		return 0
+	} else if score == 0 {
+		return 1
+	}
+	return score
+}
+
+func appendStrings(dst []string, val gjson.Result) []string {
+	switch {
+	case val.Type == gjson.String:
+		dst = append(dst, val.Str)
+	case val.IsArray() || val.IsObject():
+		val.ForEach(func(_, v gjson.Result) bool {
+			dst = appendStrings(dst, v)
+			return true
+		})
+	}
+	return dst
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func searchScores(objs []*schema.Object, cnd params.Condition) map[*schema.Object]int {
+	var scores map[*schema.Object]int
+	for _, f := range cnd {
+		if !f.IsSearch() {
+			continue
+		}
+		if scores == nil {
+			scores = make(map[*schema.Object]int, len(objs))
+		}
+		for _, o := range objs {
+			scores[o] += searchScore(o, f)
+		}
+	}
+	return scores
+}
+
 func conditionMatch(o *schema.Object, cnd params.Condition) bool {
 	for _, f := range cnd {
 		if !match(o, f) {
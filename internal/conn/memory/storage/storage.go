@@ -25,7 +25,8 @@ type backend struct {
 	hlp  riposo.Helpers
 	tree objectTree
 	dead objectTree
-	mu   sync.Mutex
+	hub  storage.Hub
+	mu   sync.RWMutex
 }
 
 // New inits a new in-memory store. Please use for development and testing only!
@@ -52,23 +53,35 @@ func (*backend) Close() error {
 	return nil
 }
 
-// Begin implements Backend interface.
-func (b *backend) Begin(_ context.Context) (storage.Transaction, error) {
+// Subscribe implements storage.Notifier interface.
+func (b *backend) Subscribe(fn func([]storage.Change)) (func(), error) {
+	return b.hub.Subscribe(fn)
+}
+
+// Begin implements Backend interface. Read-only transactions may run
+// concurrently, all other transactions are serialized.
+func (b *backend) Begin(ctx context.Context, opt *riposo.TxOptions) (storage.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if opt.IsReadOnly() {
+		b.mu.RLock()
+		return &transaction{b: b, ctx: ctx, readOnly: true}, nil
+	}
+
 	b.mu.Lock()
-	return &transaction{b: b}, nil
+	return &transaction{b: b, ctx: ctx}, nil
 }
 
 func (b *backend) delete(path riposo.Path, epoch riposo.Epoch, requireExact bool, cb func(string, *schema.Object, bool)) {
 	ns, objID := path.Split()
 
-	// fetch node
-	node := b.tree.GetNode(ns)
-	if node == nil {
-		return
-	}
-
 	// delete object
-	obj := node.Del(objID, epoch)
+	var obj *schema.Object
+	if node := b.tree.GetNode(ns); node != nil {
+		obj = node.Del(objID, epoch)
+	}
 	if obj != nil {
 		cb(ns, obj, true)
 		b.dead.FetchNode(ns, 0).ForcePut(obj)
@@ -92,12 +105,110 @@ func (b *backend) delete(path riposo.Path, epoch riposo.Epoch, requireExact bool
 // --------------------------------------------------------------------
 
 type transaction struct {
-	b *backend
+	b   *backend
+	ctx context.Context
+
+	xtree   objectTree
+	xdead   objectTree
+	spts    []savepoint
+	changes []storage.Change
+
+	done, flushed, readOnly bool
+}
+
+// savepoint records the state of namespaces before their first modification
+// after the savepoint was created.
+type savepoint struct {
+	name    string
+	undo    undoLog
+	flush   *flushUndo
+	changes int
+}
+
+// flushUndo records the state before the first flush after a savepoint.
+type flushUndo struct {
+	tree, dead   objectTree
+	xtree, xdead objectTree
+	undo         undoLog
+	flushed      bool
+}
+
+func (sp *savepoint) restore(t *transaction) {
+	if f := sp.flush; f != nil {
+		t.b.tree, t.b.dead = f.tree, f.dead
+		t.xtree, t.xdead = f.xtree, f.xdead
+		t.flushed = f.flushed
+		f.undo.apply(t.b.tree, t.b.dead)
+	} else {
+		sp.undo.apply(t.b.tree, t.b.dead)
+	}
+	t.changes = t.changes[:sp.changes]
+}
+
+// merge merges a subsequent savepoint into sp.
+func (sp *savepoint) merge(next *savepoint) {
+	if sp.flush != nil {
+		return
+	}
+	if next.flush != nil {
+		sp.undo.merge(next.flush.undo)
+		next.flush.undo = sp.undo
+		sp.flush = next.flush
+		sp.undo = next.undo
+		return
+	}
+	sp.undo.merge(next.undo)
+}
+
+// undoLog maps namespaces to copies of their original nodes, nil values
+// indicate that the namespace did not exist.
+type undoLog struct {
+	tree, dead objectTree
+}
+
+func (l *undoLog) record(tree, dead objectTree, ns string) {
+	if l.tree == nil {
+		l.tree = make(objectTree)
+		l.dead = make(objectTree)
+	}
+	if _, ok := l.tree[ns]; !ok {
+		l.tree[ns] = tree[ns].Copy()
+	}
+	if _, ok := l.dead[ns]; !ok {
+		l.dead[ns] = dead[ns].Copy()
+	}
+}
 
-	xtree objectTree
-	xdead objectTree
+func (l *undoLog) merge(o undoLog) {
+	for ns := range o.tree {
+		if l.tree == nil {
+			l.tree = make(objectTree)
+			l.dead = make(objectTree)
+		}
+		if _, ok := l.tree[ns]; !ok {
+			l.tree[ns] = o.tree[ns]
+		}
+		if _, ok := l.dead[ns]; !ok {
+			l.dead[ns] = o.dead[ns]
+		}
+	}
+}
 
-	done, flushed bool
+func (l undoLog) apply(tree, dead objectTree) {
+	for ns, node := range l.tree {
+		if node == nil {
+			delete(tree, ns)
+		} else {
+			tree[ns] = node
+		}
+	}
+	for ns, node := range l.dead {
+		if node == nil {
+			delete(dead, ns)
+		} else {
+			dead[ns] = node
+		}
+	}
 }
 
 // Commit implements Transaction interface.
@@ -106,8 +217,9 @@ func (t *transaction) Commit() error {
 		return storage.ErrTxDone
 	}
 	t.done = true
-	defer t.b.mu.Unlock()
+	t.unlock()
 
+	t.b.hub.Publish(t.changes)
 	return nil
 }
 
@@ -117,8 +229,11 @@ func (t *transaction) Rollback() error {
 		return storage.ErrTxDone
 	}
 	t.done = true
-	defer t.b.mu.Unlock()
+	defer t.unlock()
 
+	if t.readOnly {
+		return nil
+	}
 	if t.flushed {
 		t.b.tree = t.xtree
 		t.b.dead = t.xdead
@@ -142,12 +257,107 @@ func (t *transaction) Rollback() error {
 	return nil
 }
 
-// Flush implements Transaction interface.
-func (t *transaction) Flush() error {
+// Savepoint implements Transaction interface.
+func (t *transaction) Savepoint(name string) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	t.spts = append(t.spts, savepoint{name: name, changes: len(t.changes)})
+	return nil
+}
+
+// RollbackTo implements Transaction interface.
+func (t *transaction) RollbackTo(name string) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	pos := t.findSavepoint(name)
+	if pos < 0 {
+		return storage.ErrNoSavepoint
+	}
+	if t.readOnly {
+		t.spts = t.spts[:pos+1]
+		return nil
+	}
+
+	// undo changes in reverse order, re-create savepoint to allow subsequent
+	// rollbacks
+	for i := len(t.spts) - 1; i >= pos; i-- {
+		t.spts[i].restore(t)
+	}
+	t.spts = t.spts[:pos]
+	return t.Savepoint(name)
+}
+
+// Release implements Transaction interface.
+func (t *transaction) Release(name string) error {
+	if err := t.check(); err != nil {
+		return err
+	}
+
+	pos := t.findSavepoint(name)
+	if pos < 0 {
+		return storage.ErrNoSavepoint
+	}
+
+	// retain undo logs of released savepoints in the parent savepoint
+	if pos != 0 && !t.readOnly {
+		for i := pos; i < len(t.spts); i++ {
+			t.spts[pos-1].merge(&t.spts[i])
+		}
+	}
+	t.spts = t.spts[:pos]
+	return nil
+}
+
+func (t *transaction) unlock() {
+	if t.readOnly {
+		t.b.mu.RUnlock()
+	} else {
+		t.b.mu.Unlock()
+	}
+}
+
+// check returns an error if the transaction can no longer be used.
+func (t *transaction) check() error {
 	if t.done {
 		return storage.ErrTxDone
 	}
+	return t.ctx.Err()
+}
 
+func (t *transaction) findSavepoint(name string) int {
+	for i := len(t.spts) - 1; i >= 0; i-- {
+		if t.spts[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Flush implements Transaction interface.
+func (t *transaction) Flush() error {
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return storage.ErrReadOnly
+	}
+
+	if n := len(t.spts); n != 0 && t.spts[n-1].flush == nil {
+		sp := &t.spts[n-1]
+		sp.flush = &flushUndo{
+			tree:    t.b.tree,
+			dead:    t.b.dead,
+			xtree:   t.xtree,
+			xdead:   t.xdead,
+			undo:    sp.undo,
+			flushed: t.flushed,
+		}
+		sp.undo = undoLog{}
+	}
 	if !t.flushed {
 		t.flushed = true
 		t.xtree = t.b.tree
@@ -155,6 +365,7 @@ func (t *transaction) Flush() error {
 	}
 	t.b.tree = make(objectTree)
 	t.b.dead = make(objectTree)
+	t.notify("", 0, true)
 	return nil
 }
 
@@ -163,8 +374,8 @@ func (t *transaction) ModTime(path riposo.Path) (riposo.Epoch, error) {
 	if !path.IsNode() {
 		return 0, storage.ErrInvalidPath
 	}
-	if t.done {
-		return 0, storage.ErrTxDone
+	if err := t.check(); err != nil {
+		return 0, err
 	}
 
 	ns, _ := path.Split()
@@ -179,8 +390,8 @@ func (t *transaction) Exists(path riposo.Path) (bool, error) {
 	if path.IsNode() {
 		return false, storage.ErrInvalidPath
 	}
-	if t.done {
-		return false, storage.ErrTxDone
+	if err := t.check(); err != nil {
+		return false, err
 	}
 
 	obj := t.b.tree.Get(path.Split())
@@ -192,8 +403,8 @@ func (t *transaction) Get(path riposo.Path, _ bool) (*schema.Object, error) {
 	if path.IsNode() {
 		return nil, storage.ErrInvalidPath
 	}
-	if t.done {
-		return nil, storage.ErrTxDone
+	if err := t.check(); err != nil {
+		return nil, err
 	}
 
 	obj := t.b.tree.Get(path.Split())
@@ -223,8 +434,11 @@ func (t *transaction) Create(path riposo.Path, obj *schema.Object) error {
 	if !path.IsNode() {
 		return storage.ErrInvalidPath
 	}
-	if t.done {
-		return storage.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return storage.ErrReadOnly
 	}
 
 	now := riposo.EpochFromTime(t.b.cc.Now())
@@ -242,13 +456,51 @@ func (t *transaction) Create(path riposo.Path, obj *schema.Object) error {
 	obj.Norm()
 	t.b.dead.Unlink(ns, obj.ID)
 	t.b.tree.FetchNode(ns, 0).Put(obj, now)
+	t.notify(riposo.JoinPath(ns, obj.ID), obj.ModTime, false)
+	return nil
+}
+
+// Restore implements Restorer interface.
+func (t *transaction) Restore(path riposo.Path, obj *schema.Object) error {
+	if !path.IsNode() || obj.ID == "" {
+		return storage.ErrInvalidPath
+	}
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return storage.ErrReadOnly
+	}
+
+	ns, _ := path.Split()
+	t.backup(ns)
+
+	if exst := t.b.tree.Get(ns, obj.ID); exst != nil {
+		return storage.ErrObjectExists
+	}
+
+	obj.Norm()
+	node := t.b.tree.FetchNode(ns, obj.ModTime)
+	if obj.Deleted {
+		t.b.dead.FetchNode(ns, 0).ForcePut(obj)
+	} else {
+		t.b.dead.Unlink(ns, obj.ID)
+		node.ForcePut(obj)
+	}
+	if node.modTime < obj.ModTime {
+		node.modTime = obj.ModTime
+	}
+	t.notify(riposo.JoinPath(ns, obj.ID), obj.ModTime, obj.Deleted)
 	return nil
 }
 
 // Update implements Transaction interface.
 func (t *transaction) Update(path riposo.Path, obj *schema.Object) error {
-	if t.done {
-		return storage.ErrTxDone
+	if err := t.check(); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return storage.ErrReadOnly
 	}
 
 	now := riposo.EpochFromTime(t.b.cc.Now())
@@ -258,6 +510,7 @@ func (t *transaction) Update(path riposo.Path, obj *schema.Object) error {
 	obj.Norm()
 	t.b.dead.Unlink(ns, obj.ID)
 	t.b.tree.FetchNode(ns, 0).Put(obj, now)
+	t.notify(riposo.JoinPath(ns, obj.ID), obj.ModTime, false)
 	return nil
 }
 
@@ -266,8 +519,11 @@ func (t *transaction) Delete(path riposo.Path) (*schema.Object, error) {
 	if path.IsNode() {
 		return nil, storage.ErrInvalidPath
 	}
-	if t.done {
-		return nil, storage.ErrTxDone
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+	if t.readOnly {
+		return nil, storage.ErrReadOnly
 	}
 
 	now := riposo.EpochFromTime(t.b.cc.Now())
@@ -283,6 +539,7 @@ func (t *transaction) Delete(path riposo.Path) (*schema.Object, error) {
 	if deleted == nil {
 		return nil, storage.ErrNotFound
 	}
+	t.notify(path, deleted.ModTime, true)
 	return deleted, nil
 }
 
@@ -291,26 +548,26 @@ func (t *transaction) ListAll(path riposo.Path, opt storage.ListOptions) ([]*sch
 	if !path.IsNode() {
 		return nil, storage.ErrInvalidPath
 	}
-	if t.done {
-		return nil, storage.ErrTxDone
+	if err := t.check(); err != nil {
+		return nil, err
 	}
 
 	ns, _ := path.Split()
 
 	var objs []*schema.Object
 	t.b.tree.Each(ns, opt.Condition, func(obj *schema.Object) {
-		objs = append(objs, obj)
+		objs = append(objs, copyObject(obj))
 	})
 
 	if opt.Include == storage.IncludeAll {
 		t.b.dead.Each(ns, opt.Condition, func(obj *schema.Object) {
-			objs = append(objs, obj)
+			objs = append(objs, copyObject(obj))
 		})
 	}
 
 	objs = paginationFilter(objs, opt.Pagination)
 	if len(opt.Sort) != 0 {
-		sort.Sort(&objectSlice{Slice: objs, Sort: opt.Sort})
+		sort.Sort(&objectSlice{Slice: objs, Sort: opt.Sort, Scores: searchScores(objs, opt.Condition)})
 	}
 	if opt.Limit > 0 && len(objs) > opt.Limit {
 		objs = objs[:opt.Limit]
@@ -323,8 +580,8 @@ func (t *transaction) CountAll(path riposo.Path, opt storage.CountOptions) (int6
 	if !path.IsNode() {
 		return 0, storage.ErrInvalidPath
 	}
-	if t.done {
-		return 0, storage.ErrTxDone
+	if err := t.check(); err != nil {
+		return 0, err
 	}
 
 	ns, _ := path.Split()
@@ -336,6 +593,24 @@ func (t *transaction) CountAll(path riposo.Path, opt storage.CountOptions) (int6
 	return cnt, nil
 }
 
+// Aggregate implements storage.Aggregator interface.
+func (t *transaction) Aggregate(path riposo.Path, opt storage.AggregateOptions) ([]storage.AggregateGroup, error) {
+	if !path.IsNode() {
+		return nil, storage.ErrInvalidPath
+	}
+	if err := t.check(); err != nil {
+		return nil, err
+	}
+
+	ns, _ := path.Split()
+
+	var objs []*schema.Object
+	t.b.tree.Each(ns, opt.Condition, func(obj *schema.Object) {
+		objs = append(objs, obj)
+	})
+	return aggregate(objs, opt.Aggregation), nil
+}
+
 // DeleteAll implements Transaction interface.
 func (t *transaction) DeleteAll(paths []riposo.Path) (modTime riposo.Epoch, deleted []riposo.Path, _ error) {
 	for _, path := range paths {
@@ -343,8 +618,11 @@ func (t *transaction) DeleteAll(paths []riposo.Path) (modTime riposo.Epoch, dele
 			return 0, nil, storage.ErrInvalidPath
 		}
 	}
-	if t.done {
-		return 0, nil, storage.ErrTxDone
+	if err := t.check(); err != nil {
+		return 0, nil, err
+	}
+	if t.readOnly {
+		return 0, nil, storage.ErrReadOnly
 	}
 	if len(paths) == 0 {
 		return 0, nil, nil
@@ -360,6 +638,7 @@ func (t *transaction) DeleteAll(paths []riposo.Path) (modTime riposo.Epoch, dele
 				modTime = obj.ModTime
 			}
 			deleted = append(deleted, riposo.JoinPath(ns, obj.ID))
+			t.notify(riposo.JoinPath(ns, obj.ID), obj.ModTime, true)
 		})
 	}
 	return
@@ -367,8 +646,11 @@ func (t *transaction) DeleteAll(paths []riposo.Path) (modTime riposo.Epoch, dele
 
 // Purge implements Transaction interface.
 func (t *transaction) Purge(olderThan riposo.Epoch) (cnt int64, err error) {
-	if t.done {
-		return 0, storage.ErrTxDone
+	if err := t.check(); err != nil {
+		return 0, err
+	}
+	if t.readOnly {
+		return 0, storage.ErrReadOnly
 	}
 
 	for ns, node := range t.b.dead {
@@ -387,7 +669,14 @@ func (t *transaction) Purge(olderThan riposo.Epoch) (cnt int64, err error) {
 	return
 }
 
+func (t *transaction) notify(path riposo.Path, modTime riposo.Epoch, deleted bool) {
+	t.changes = append(t.changes, storage.Change{Path: path, ModTime: modTime, Deleted: deleted})
+}
+
 func (t *transaction) backup(ns string) {
+	if n := len(t.spts); n != 0 {
+		t.spts[n-1].undo.record(t.b.tree, t.b.dead, ns)
+	}
 	if t.flushed {
 		return
 	}
@@ -1,6 +1,7 @@
 package storage_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/benbjohnson/clock"
@@ -8,6 +9,8 @@ import (
 	"github.com/riposo/riposo/pkg/conn/storage/testdata"
 	"github.com/riposo/riposo/pkg/mock"
 	"github.com/riposo/riposo/pkg/params"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
 
 	. "github.com/bsm/ginkgo/v2"
 	. "github.com/bsm/gomega"
@@ -35,6 +38,89 @@ var _ = Describe("Backend", func() {
 	Describe("common", func() {
 		testdata.BehavesLikeBackend(&link)
 	})
+
+	It("binds transactions to contexts", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "foo"})).To(Succeed())
+
+		cancel()
+		Expect(tx.Exists("/buckets/foo")).Error().To(MatchError(context.Canceled))
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "bar"})).To(MatchError(context.Canceled))
+
+		_, err = subject.Begin(ctx, nil)
+		Expect(err).To(MatchError(context.Canceled))
+
+		// commits are not bound to the context
+		Expect(tx.Commit()).To(Succeed())
+
+		tx, err = subject.Begin(context.Background(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+		Expect(tx.Exists("/buckets/foo")).To(BeTrue())
+		Expect(tx.Exists("/buckets/bar")).To(BeFalse())
+	})
+
+	It("rolls back released savepoints", func() {
+		tx, err := subject.Begin(context.Background(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "a"})).To(Succeed())
+		Expect(tx.Savepoint("sp1")).To(Succeed())
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "b"})).To(Succeed())
+		Expect(tx.Savepoint("sp2")).To(Succeed())
+		Expect(tx.Flush()).To(Succeed())
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "c"})).To(Succeed())
+		Expect(tx.Savepoint("sp3")).To(Succeed())
+		Expect(tx.Delete("/buckets/c")).Error().NotTo(HaveOccurred())
+		Expect(tx.Release("sp2")).To(Succeed())
+
+		Expect(tx.RollbackTo("sp1")).To(Succeed())
+		Expect(tx.Exists("/buckets/a")).To(BeTrue())
+		Expect(tx.Exists("/buckets/b")).To(BeFalse())
+		Expect(tx.Exists("/buckets/c")).To(BeFalse())
+		Expect(tx.CountAll("/buckets/*", storage.CountOptions{})).To(Equal(int64(1)))
+	})
+
+	It("supports concurrent read-only transactions", func() {
+		ctx := context.Background()
+		opt := &riposo.TxOptions{ReadOnly: true}
+
+		tx1, err := subject.Begin(ctx, opt)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx1.Rollback()
+
+		tx2, err := subject.Begin(ctx, opt)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx2.Rollback()
+
+		Expect(tx1.Exists("/buckets/foo")).To(BeFalse())
+		Expect(tx2.Create("/buckets/*", &schema.Object{ID: "foo"})).To(MatchError(storage.ErrReadOnly))
+		Expect(tx2.Savepoint("sp")).To(Succeed())
+		Expect(tx2.RollbackTo("sp")).To(Succeed())
+		Expect(tx2.Release("sp")).To(Succeed())
+
+		// writers wait for readers
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+
+			tx, err := subject.Begin(ctx, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tx.Create("/buckets/*", &schema.Object{ID: "foo"})).To(Succeed())
+			Expect(tx.Commit()).To(Succeed())
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+
+		Expect(tx1.Commit()).To(Succeed())
+		Expect(tx2.Rollback()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+	})
 })
 
 func TestSuite(t *testing.T) {
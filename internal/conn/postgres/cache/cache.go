@@ -8,9 +8,11 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/riposo/riposo/internal/conn/postgres/common"
 	"github.com/riposo/riposo/pkg/conn/cache"
 	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
 	"go.uber.org/multierr"
 )
 
@@ -82,9 +84,14 @@ func (cn *conn) Ping(ctx context.Context) error {
 	return cn.db.PingContext(ctx)
 }
 
+// PoolStats implements conn.Pooled interface.
+func (cn *conn) PoolStats() *schema.PoolStats {
+	return common.PoolStats(cn.db)
+}
+
 // Begin implements cache.Backend interface.
-func (cn *conn) Begin(ctx context.Context) (cache.Transaction, error) {
-	tx, err := cn.db.BeginTx(ctx, nil)
+func (cn *conn) Begin(ctx context.Context, opt *riposo.TxOptions) (cache.Transaction, error) {
+	tx, err := cn.db.BeginTx(common.Detach(ctx), common.TxOptions(opt))
 	if err != nil {
 		return nil, err
 	}
@@ -149,6 +156,24 @@ func (tx *transaction) Rollback() error {
 	return normErr(tx.Tx.Rollback())
 }
 
+// Savepoint implements cache.Transaction interface.
+func (tx *transaction) Savepoint(name string) error {
+	_, err := tx.ExecContext(tx.ctx, `SAVEPOINT `+pq.QuoteIdentifier(name))
+	return normErr(err)
+}
+
+// RollbackTo implements cache.Transaction interface.
+func (tx *transaction) RollbackTo(name string) error {
+	_, err := tx.ExecContext(tx.ctx, `ROLLBACK TO SAVEPOINT `+pq.QuoteIdentifier(name))
+	return normErr(err)
+}
+
+// Release implements cache.Transaction interface.
+func (tx *transaction) Release(name string) error {
+	_, err := tx.ExecContext(tx.ctx, `RELEASE SAVEPOINT `+pq.QuoteIdentifier(name))
+	return normErr(err)
+}
+
 // Flush implements cache.Transaction interface.
 func (tx *transaction) Flush() error {
 	_, err := tx.ExecContext(tx.ctx, `TRUNCATE cache_keys`)
@@ -203,9 +228,37 @@ func (tx *transaction) Del(key string) error {
 	return normErr(err)
 }
 
+// Scan implements cache.Scanner.
+func (tx *transaction) Scan(fn func(string, []byte, time.Time) error) error {
+	rows, err := tx.QueryContext(tx.ctx, sqlScanKeys, time.Now().UTC())
+	if err != nil {
+		return normErr(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var val []byte
+		var exp time.Time
+		if err := rows.Scan(&key, &val, &exp); err != nil {
+			return err
+		}
+		if err := fn(key, val, exp); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 func normErr(err error) error {
 	if errors.Is(err, sql.ErrTxDone) {
 		return cache.ErrTxDone
+	} else if common.IsInvalidSavepoint(err) {
+		return cache.ErrNoSavepoint
+	} else if common.IsReadOnly(err) {
+		return cache.ErrReadOnly
+	} else if common.IsTimeout(err) {
+		return schema.BackendError(err)
 	} else if errors.Is(err, sql.ErrNoRows) {
 		return cache.ErrNotFound
 	}
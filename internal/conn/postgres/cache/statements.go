@@ -35,6 +35,16 @@ WHERE key = $1
 RETURNING key
 `
 
+// Placeholders:
+//
+//	$1 - now
+const sqlScanKeys = `
+SELECT key, value, expires_at
+FROM cache_keys
+WHERE expires_at > $1
+ORDER BY key
+`
+
 // Placeholders:
 //
 //	$1 - now
@@ -4,33 +4,22 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
-	_ "github.com/lib/pq" // this is specifically for PG
+	"github.com/lib/pq" // this is specifically for PG
+	"github.com/riposo/riposo/pkg/riposo"
 )
 
 // Connect connects to a PG database.
 func Connect(ctx context.Context, dsn string, versionField string, targetVersion int32, fs embed.FS) (*sql.DB, error) {
-	schema := "postgres"
-	if pos := strings.Index(dsn, "://"); pos > -1 {
-		schema = dsn[:pos]
-	}
-
-	db, err := sql.Open(schema, dsn)
+	db, err := open(ctx, dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := validateEncoding(ctx, db, "utf8"); err != nil {
-		_ = db.Close()
-		return nil, err
-	}
-	if err := validateTimezone(ctx, db, "utc"); err != nil {
-		_ = db.Close()
-		return nil, err
-	}
-
 	version, err := schemaVersion(ctx, db, versionField)
 	if err != nil {
 		_ = db.Close()
@@ -51,6 +40,35 @@ func Connect(ctx context.Context, dsn string, versionField string, targetVersion
 	return db, nil
 }
 
+// open opens a DB, applies pool options and validates its settings.
+func open(ctx context.Context, dsn string) (*sql.DB, error) {
+	dsn, opt, err := ParseOptions(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := "postgres"
+	if pos := strings.Index(dsn, "://"); pos > -1 {
+		schema = dsn[:pos]
+	}
+
+	db, err := sql.Open(schema, dsn)
+	if err != nil {
+		return nil, err
+	}
+	opt.Apply(db)
+
+	if err := validateEncoding(ctx, db, "utf8"); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if err := validateTimezone(ctx, db, "utc"); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
 // validateEncoding makes sure database is set to specific encoding.
 func validateEncoding(ctx context.Context, db *sql.DB, encoding string) error {
 	var value string
@@ -115,3 +133,48 @@ func schemaVersion(ctx context.Context, db *sql.DB, field string) (version int32
 	}
 	return
 }
+
+// IsInvalidSavepoint returns true if the error was caused by an
+// invalid/unknown savepoint.
+func IsInvalidSavepoint(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "3B001"
+}
+
+// Detach returns a context which carries the values of ctx but is never
+// cancelled. Transactions are started with a detached context to ensure that
+// commits complete once started, statements use the original context.
+func Detach(ctx context.Context) context.Context {
+	return detachedContext{Context: ctx}
+}
+
+type detachedContext struct{ context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// TxOptions converts transaction options.
+func TxOptions(opt *riposo.TxOptions) *sql.TxOptions {
+	if opt == nil {
+		return nil
+	}
+
+	txo := &sql.TxOptions{ReadOnly: opt.ReadOnly}
+	switch opt.Isolation {
+	case riposo.IsolationReadCommitted:
+		txo.Isolation = sql.LevelReadCommitted
+	case riposo.IsolationRepeatableRead:
+		txo.Isolation = sql.LevelRepeatableRead
+	case riposo.IsolationSerializable:
+		txo.Isolation = sql.LevelSerializable
+	}
+	return txo
+}
+
+// IsReadOnly returns true if the error was caused by a write within a
+// read-only transaction.
+func IsReadOnly(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "25006"
+}
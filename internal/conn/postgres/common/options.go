@@ -0,0 +1,112 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/riposo/riposo/pkg/schema"
+)
+
+// Options contain connection pool options.
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// ParseOptions extracts connection pool options from a DSN URL. Statement and
+// lock timeouts may be specified as durations, e.g. statement_timeout=5s, and
+// are passed to the server in milliseconds. Example:
+//
+//	postgres://localhost/riposo?max_open_conns=20&max_idle_conns=5&conn_max_lifetime=30m&statement_timeout=5s&lock_timeout=2s
+func ParseOptions(dsn string) (string, *Options, error) {
+	opt := &Options{MaxIdleConns: 2}
+	if !strings.Contains(dsn, "://") {
+		return dsn, opt, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := u.Query()
+	if s := query.Get("max_open_conns"); s != "" {
+		if opt.MaxOpenConns, err = strconv.Atoi(s); err != nil {
+			return "", nil, fmt.Errorf("invalid max_open_conns %q", s)
+		}
+	}
+	if s := query.Get("max_idle_conns"); s != "" {
+		if opt.MaxIdleConns, err = strconv.Atoi(s); err != nil {
+			return "", nil, fmt.Errorf("invalid max_idle_conns %q", s)
+		}
+	}
+	if s := query.Get("conn_max_lifetime"); s != "" {
+		if opt.ConnMaxLifetime, err = time.ParseDuration(s); err != nil {
+			return "", nil, fmt.Errorf("invalid conn_max_lifetime %q", s)
+		}
+	}
+	for _, key := range []string{"statement_timeout", "lock_timeout"} {
+		if s := query.Get(key); s != "" {
+			ms, err := parseMillis(s)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid %s %q", key, s)
+			}
+			query.Set(key, strconv.FormatInt(ms, 10))
+		}
+	}
+
+	query.Del("max_open_conns")
+	query.Del("max_idle_conns")
+	query.Del("conn_max_lifetime")
+	u.RawQuery = query.Encode()
+	return u.String(), opt, nil
+}
+
+// Apply applies options to a DB.
+func (o *Options) Apply(db *sql.DB) {
+	db.SetMaxOpenConns(o.MaxOpenConns)
+	db.SetMaxIdleConns(o.MaxIdleConns)
+	db.SetConnMaxLifetime(o.ConnMaxLifetime)
+}
+
+// parseMillis parses a duration or a plain number of milliseconds.
+func parseMillis(s string) (int64, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil && ms >= 0 {
+		return ms, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	} else if d < 0 {
+		return 0, errors.New("negative duration")
+	}
+	return d.Milliseconds(), nil
+}
+
+// PoolStats returns connection pool stats.
+func PoolStats(db *sql.DB) *schema.PoolStats {
+	stats := db.Stats()
+	return &schema.PoolStats{
+		MaxOpen:      stats.MaxOpenConnections,
+		Open:         stats.OpenConnections,
+		InUse:        stats.InUse,
+		Idle:         stats.Idle,
+		WaitCount:    stats.WaitCount,
+		WaitDuration: stats.WaitDuration.Milliseconds(),
+	}
+}
+
+// IsTimeout returns true if the error was caused by a statement or lock
+// timeout.
+func IsTimeout(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == "57014" || pqErr.Code == "55P03")
+}
@@ -0,0 +1,80 @@
+package common_test
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/riposo/riposo/pkg/riposo"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/internal/conn/postgres/common"
+)
+
+var _ = Describe("ParseOptions", func() {
+	It("parses", func() {
+		dsn, opt, err := ParseOptions("postgres://primary/db?timezone=UTC&max_open_conns=20&max_idle_conns=5&conn_max_lifetime=30m&statement_timeout=5s&lock_timeout=1500")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dsn).To(Equal("postgres://primary/db?lock_timeout=1500&statement_timeout=5000&timezone=UTC"))
+		Expect(opt).To(Equal(&Options{
+			MaxOpenConns:    20,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+		}))
+	})
+
+	It("applies defaults", func() {
+		dsn, opt, err := ParseOptions("postgres://primary/db")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dsn).To(Equal("postgres://primary/db"))
+		Expect(opt).To(Equal(&Options{MaxIdleConns: 2}))
+
+		dsn, opt, err = ParseOptions("host=primary dbname=db")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dsn).To(Equal("host=primary dbname=db"))
+		Expect(opt).To(Equal(&Options{MaxIdleConns: 2}))
+	})
+
+	It("validates", func() {
+		_, _, err := ParseOptions("postgres://primary/db?max_open_conns=x")
+		Expect(err).To(MatchError(`invalid max_open_conns "x"`))
+
+		_, _, err = ParseOptions("postgres://primary/db?conn_max_lifetime=1")
+		Expect(err).To(MatchError(`invalid conn_max_lifetime "1"`))
+
+		_, _, err = ParseOptions("postgres://primary/db?statement_timeout=-1s")
+		Expect(err).To(MatchError(`invalid statement_timeout "-1s"`))
+	})
+})
+
+var _ = Describe("IsTimeout", func() {
+	It("detects timeouts", func() {
+		Expect(IsTimeout(&pq.Error{Code: "57014"})).To(BeTrue())
+		Expect(IsTimeout(fmt.Errorf("wrapped: %w", &pq.Error{Code: "55P03"}))).To(BeTrue())
+		Expect(IsTimeout(&pq.Error{Code: "23505"})).To(BeFalse())
+		Expect(IsTimeout(fmt.Errorf("other"))).To(BeFalse())
+	})
+})
+
+var _ = Describe("IsReadOnly", func() {
+	It("detects read-only violations", func() {
+		Expect(IsReadOnly(&pq.Error{Code: "25006"})).To(BeTrue())
+		Expect(IsReadOnly(&pq.Error{Code: "23505"})).To(BeFalse())
+	})
+})
+
+var _ = Describe("TxOptions", func() {
+	It("converts", func() {
+		Expect(TxOptions(nil)).To(BeNil())
+		Expect(TxOptions(&riposo.TxOptions{})).To(Equal(&sql.TxOptions{}))
+		Expect(TxOptions(&riposo.TxOptions{
+			ReadOnly:  true,
+			Isolation: riposo.IsolationRepeatableRead,
+		})).To(Equal(&sql.TxOptions{
+			ReadOnly:  true,
+			Isolation: sql.LevelRepeatableRead,
+		}))
+	})
+})
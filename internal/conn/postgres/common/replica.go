@@ -0,0 +1,116 @@
+package common
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// replicaBackoff is the period for which a failing replica is skipped.
+const replicaBackoff = 30 * time.Second
+
+// SplitReplicas removes replica query parameters from a DSN URL and returns
+// the primary DSN along with the DSNs of the replicas. Replicas may either be
+// specified as full DSNs or as host[:port] values, which inherit all other
+// settings from the primary, e.g.:
+//
+//	postgres://user@primary/riposo?replica=replica1&replica=replica2:5433
+func SplitReplicas(dsn string) (string, []string, error) {
+	if !strings.Contains(dsn, "://") {
+		return dsn, nil, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := u.Query()
+	values := query["replica"]
+	if len(values) == 0 {
+		return dsn, nil, nil
+	}
+
+	query.Del("replica")
+	u.RawQuery = query.Encode()
+
+	replicas := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" {
+			return "", nil, fmt.Errorf("invalid blank replica")
+		}
+
+		if strings.Contains(value, "://") {
+			replicas = append(replicas, value)
+		} else {
+			r := *u
+			r.Host = value
+			replicas = append(replicas, r.String())
+		}
+	}
+	return u.String(), replicas, nil
+}
+
+// ConnectReplica connects to a read replica. Unlike Connect, it does not
+// create or migrate schemas but expects the replica to be up-to-date.
+func ConnectReplica(ctx context.Context, dsn string, versionField string, targetVersion int32) (*sql.DB, error) {
+	db, err := open(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := schemaVersion(ctx, db, versionField)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	} else if version != targetVersion {
+		_ = db.Close()
+		return nil, fmt.Errorf("unexpected replica schema version %d", version)
+	}
+
+	return db, nil
+}
+
+// Balancer distributes read-only transactions across replicas.
+type Balancer struct {
+	next  uint32
+	until []int64 // unix nanos
+}
+
+// NewBalancer inits a new balancer for n replicas.
+func NewBalancer(n int) *Balancer {
+	return &Balancer{until: make([]int64, n)}
+}
+
+// Pick calls fn with the index of each healthy replica, in round-robin order,
+// until fn succeeds. Replicas for which fn fails are skipped for a short
+// period of time. Pick returns false if no replica could be used.
+func (b *Balancer) Pick(ctx context.Context, fn func(int) error) bool {
+	if b == nil || len(b.until) == 0 {
+		return false
+	}
+
+	n := uint32(len(b.until))
+	offset := atomic.AddUint32(&b.next, 1)
+	for i := uint32(0); i < n; i++ {
+		pos := (offset + i) % n
+		now := time.Now().UnixNano()
+		if atomic.LoadInt64(&b.until[pos]) > now {
+			continue
+		}
+
+		if err := fn(int(pos)); err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			atomic.StoreInt64(&b.until[pos], now+int64(replicaBackoff))
+			continue
+		}
+		return true
+	}
+	return false
+}
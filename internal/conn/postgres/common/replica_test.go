@@ -0,0 +1,94 @@
+package common_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/internal/conn/postgres/common"
+)
+
+var _ = Describe("SplitReplicas", func() {
+	It("extracts replicas", func() {
+		primary, replicas, err := SplitReplicas("postgres://u:p@primary/db?timezone=UTC&replica=r1&replica=r2:5433&replica=postgres%3A%2F%2Fother%2Fdb")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(primary).To(Equal("postgres://u:p@primary/db?timezone=UTC"))
+		Expect(replicas).To(Equal([]string{
+			"postgres://u:p@r1/db?timezone=UTC",
+			"postgres://u:p@r2:5433/db?timezone=UTC",
+			"postgres://other/db",
+		}))
+	})
+
+	It("passes through plain DSNs", func() {
+		Expect(SplitReplicas("postgres://primary/db?timezone=UTC")).To(Equal("postgres://primary/db?timezone=UTC"))
+		Expect(SplitReplicas("host=primary dbname=db")).To(Equal("host=primary dbname=db"))
+	})
+
+	It("rejects blank replicas", func() {
+		_, _, err := SplitReplicas("postgres://primary/db?replica=")
+		Expect(err).To(MatchError("invalid blank replica"))
+	})
+})
+
+var _ = Describe("Balancer", func() {
+	var subject *Balancer
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		subject = NewBalancer(3)
+	})
+
+	It("requires replicas", func() {
+		Expect(subject.Pick(ctx, func(int) error { return nil })).To(BeTrue())
+		Expect(NewBalancer(0).Pick(ctx, func(int) error { return nil })).To(BeFalse())
+	})
+
+	It("picks in round-robin order", func() {
+		var picked []int
+		for i := 0; i < 4; i++ {
+			Expect(subject.Pick(ctx, func(n int) error {
+				picked = append(picked, n)
+				return nil
+			})).To(BeTrue())
+		}
+		Expect(picked).To(Equal([]int{1, 2, 0, 1}))
+	})
+
+	It("skips unhealthy replicas", func() {
+		var attempts []int
+		Expect(subject.Pick(ctx, func(n int) error {
+			attempts = append(attempts, n)
+			if n == 1 {
+				return errors.New("failed")
+			}
+			return nil
+		})).To(BeTrue())
+		Expect(attempts).To(Equal([]int{1, 2}))
+
+		attempts = attempts[:0]
+		for i := 0; i < 3; i++ {
+			Expect(subject.Pick(ctx, func(n int) error {
+				attempts = append(attempts, n)
+				return nil
+			})).To(BeTrue())
+		}
+		Expect(attempts).To(Equal([]int{2, 0, 2}))
+	})
+
+	It("falls back when all replicas fail", func() {
+		var attempts int
+		Expect(subject.Pick(ctx, func(int) error {
+			attempts++
+			return errors.New("failed")
+		})).To(BeFalse())
+		Expect(attempts).To(Equal(3))
+
+		Expect(subject.Pick(ctx, func(int) error {
+			attempts++
+			return nil
+		})).To(BeFalse())
+		Expect(attempts).To(Equal(3))
+	})
+})
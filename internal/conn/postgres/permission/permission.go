@@ -30,17 +30,27 @@ func init() {
 }
 
 type conn struct {
-	db   *sql.DB
-	stmt struct {
+	db       *sql.DB
+	replicas []*conn
+	balancer *common.Balancer
+	stmt     struct {
 		getUserPrincipals, removeUserPrincipal, purgeUserPrincipals,
 		getACEPrincipals, matchACEPrincipals,
 		insertACE, deleteACE,
-		getPerms, deletePerms *sql.Stmt
+		getPerms, getAllPerms, deletePerms *sql.Stmt
 	}
 }
 
-// Connect connects to a PostgreSQL server.
+// Connect connects to a PostgreSQL server. Read replicas can be specified
+// via replica query parameters, e.g.
+// postgres://primary/riposo?replica=replica1&replica=replica2. Transactions
+// with a read-only hint are routed to healthy replicas.
 func Connect(ctx context.Context, dsn string) (permission.Backend, error) {
+	dsn, replicaDSNs, err := common.SplitReplicas(dsn)
+	if err != nil {
+		return nil, err
+	}
+
 	// Connect to the DB.
 	db, err := common.Connect(ctx, dsn, "permission_schema_version", schemaVersion, embedFS)
 	if err != nil {
@@ -51,7 +61,26 @@ func Connect(ctx context.Context, dsn string) (permission.Backend, error) {
 	cn := &conn{db: db}
 	if err := cn.prepare(ctx); err != nil {
 		_ = cn.Close()
+		return nil, err
 	}
+
+	// Connect to the replicas.
+	for _, replicaDSN := range replicaDSNs {
+		rdb, err := common.ConnectReplica(ctx, replicaDSN, "permission_schema_version", schemaVersion)
+		if err != nil {
+			_ = cn.Close()
+			return nil, err
+		}
+
+		replica := &conn{db: rdb}
+		cn.replicas = append(cn.replicas, replica)
+		if err := replica.prepare(ctx); err != nil {
+			_ = cn.Close()
+			return nil, err
+		}
+	}
+	cn.balancer = common.NewBalancer(len(cn.replicas))
+
 	return cn, nil
 }
 
@@ -81,6 +110,9 @@ func (cn *conn) prepare(ctx context.Context) (err error) {
 	if cn.stmt.getPerms, err = cn.db.PrepareContext(ctx, sqlGetPerms); err != nil {
 		return
 	}
+	if cn.stmt.getAllPerms, err = cn.db.PrepareContext(ctx, sqlGetAllPerms); err != nil {
+		return
+	}
 	return
 }
 
@@ -89,9 +121,28 @@ func (cn *conn) Ping(ctx context.Context) error {
 	return cn.db.PingContext(ctx)
 }
 
-// Begin implements permission.Backend interface.
-func (cn *conn) Begin(ctx context.Context) (permission.Transaction, error) {
-	tx, err := cn.db.BeginTx(ctx, nil)
+// PoolStats implements conn.Pooled interface.
+func (cn *conn) PoolStats() *schema.PoolStats {
+	return common.PoolStats(cn.db)
+}
+
+// Begin implements permission.Backend interface. Read-only transactions are
+// routed to replicas, if configured.
+func (cn *conn) Begin(ctx context.Context, opt *riposo.TxOptions) (permission.Transaction, error) {
+	var rtx *transaction
+	if opt.IsReadOnly() && cn.balancer.Pick(ctx, func(i int) error {
+		replica := cn.replicas[i]
+		tx, err := replica.db.BeginTx(common.Detach(ctx), common.TxOptions(opt))
+		if err != nil {
+			return err
+		}
+		rtx = &transaction{Tx: tx, cn: replica, ctx: ctx}
+		return nil
+	}) {
+		return rtx, nil
+	}
+
+	tx, err := cn.db.BeginTx(common.Detach(ctx), common.TxOptions(opt))
 	if err != nil {
 		return nil, err
 	}
@@ -100,6 +151,9 @@ func (cn *conn) Begin(ctx context.Context) (permission.Transaction, error) {
 
 // Close implements permission.Backend.
 func (cn *conn) Close() (err error) {
+	for _, replica := range cn.replicas {
+		err = multierr.Append(err, replica.Close())
+	}
 	if cn.stmt.getUserPrincipals != nil {
 		err = multierr.Append(err, cn.stmt.getUserPrincipals.Close())
 	}
@@ -124,6 +178,9 @@ func (cn *conn) Close() (err error) {
 	if cn.stmt.getPerms != nil {
 		err = multierr.Append(err, cn.stmt.getPerms.Close())
 	}
+	if cn.stmt.getAllPerms != nil {
+		err = multierr.Append(err, cn.stmt.getAllPerms.Close())
+	}
 	if cn.db != nil {
 		err = multierr.Append(err, cn.db.Close())
 	}
@@ -148,6 +205,24 @@ func (tx *transaction) Rollback() error {
 	return normErr(tx.Tx.Rollback())
 }
 
+// Savepoint implements permission.Transaction interface.
+func (tx *transaction) Savepoint(name string) error {
+	_, err := tx.ExecContext(tx.ctx, `SAVEPOINT `+pq.QuoteIdentifier(name))
+	return normErr(err)
+}
+
+// RollbackTo implements permission.Transaction interface.
+func (tx *transaction) RollbackTo(name string) error {
+	_, err := tx.ExecContext(tx.ctx, `ROLLBACK TO SAVEPOINT `+pq.QuoteIdentifier(name))
+	return normErr(err)
+}
+
+// Release implements permission.Transaction interface.
+func (tx *transaction) Release(name string) error {
+	_, err := tx.ExecContext(tx.ctx, `RELEASE SAVEPOINT `+pq.QuoteIdentifier(name))
+	return normErr(err)
+}
+
 // Flush implements permission.Transaction interface.
 func (tx *transaction) Flush() error {
 	_, err := tx.ExecContext(tx.ctx, `TRUNCATE permission_paths, permission_principals`)
@@ -356,6 +431,56 @@ func (tx *transaction) GetPermissions(path riposo.Path) (schema.PermissionSet, e
 	return perms, nil
 }
 
+// GetAllPermissions implements permission.Transaction.
+func (tx *transaction) GetAllPermissions(paths []riposo.Path) ([]schema.PermissionSet, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(paths))
+	for _, path := range paths {
+		keys = append(keys, path.String())
+	}
+
+	stmt := tx.StmtContext(tx.ctx, tx.cn.stmt.getAllPerms)
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(tx.ctx, pq.Array(keys))
+	if err != nil {
+		return nil, normErr(err)
+	}
+	defer rows.Close()
+
+	index := make(map[riposo.Path]schema.PermissionSet, len(paths))
+	for rows.Next() {
+		var path riposo.Path
+		var perm, principal string
+		if err := rows.Scan(&path, &perm, &principal); err != nil {
+			return nil, err
+		}
+
+		perms, ok := index[path]
+		if !ok {
+			perms = make(schema.PermissionSet)
+			index[path] = perms
+		}
+		perms[perm] = append(perms[perm], principal)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sets := make([]schema.PermissionSet, 0, len(paths))
+	for _, path := range paths {
+		perms, ok := index[path]
+		if !ok {
+			perms = make(schema.PermissionSet)
+		}
+		sets = append(sets, perms)
+	}
+	return sets, nil
+}
+
 // CreatePermissions implements permission.Transaction.
 func (tx *transaction) CreatePermissions(path riposo.Path, set schema.PermissionSet) error {
 	stmt := minisql.Pooled()
@@ -477,9 +602,89 @@ func (tx *transaction) DeletePermissions(paths []riposo.Path) error {
 	return normErr(err)
 }
 
+// ScanUserPrincipals implements permission.Scanner interface.
+func (tx *transaction) ScanUserPrincipals(fn func(string, []string) error) error {
+	rows, err := tx.QueryContext(tx.ctx, sqlScanUserPrincipals)
+	if err != nil {
+		return normErr(err)
+	}
+	defer rows.Close()
+
+	var userID string
+	var principals []string
+	for rows.Next() {
+		var uid, principal string
+		if err := rows.Scan(&uid, &principal); err != nil {
+			return err
+		}
+
+		if uid != userID && len(principals) != 0 {
+			if err := fn(userID, principals); err != nil {
+				return err
+			}
+			principals = nil
+		}
+		userID = uid
+		principals = append(principals, principal)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(principals) != 0 {
+		return fn(userID, principals)
+	}
+	return nil
+}
+
+// ScanPermissions implements permission.Scanner interface.
+func (tx *transaction) ScanPermissions(fn func(riposo.Path, schema.PermissionSet) error) error {
+	rows, err := tx.QueryContext(tx.ctx, sqlScanPerms)
+	if err != nil {
+		return normErr(err)
+	}
+	defer rows.Close()
+
+	var path riposo.Path
+	var perms schema.PermissionSet
+	for rows.Next() {
+		var p riposo.Path
+		var perm, principal string
+		if err := rows.Scan(&p, &perm, &principal); err != nil {
+			return err
+		}
+
+		if p != path && perms != nil {
+			if err := fn(path, perms); err != nil {
+				return err
+			}
+			perms = nil
+		}
+		if perms == nil {
+			perms = make(schema.PermissionSet)
+		}
+		path = p
+		perms[perm] = append(perms[perm], principal)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if perms != nil {
+		return fn(path, perms)
+	}
+	return nil
+}
+
 func normErr(err error) error {
 	if errors.Is(err, sql.ErrTxDone) {
 		return permission.ErrTxDone
+	} else if common.IsInvalidSavepoint(err) {
+		return permission.ErrNoSavepoint
+	} else if common.IsReadOnly(err) {
+		return permission.ErrReadOnly
+	} else if common.IsTimeout(err) {
+		return schema.BackendError(err)
 	}
 	return err
 }
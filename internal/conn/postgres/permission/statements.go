@@ -82,3 +82,24 @@ SELECT permission, principal
 FROM permission_paths
 WHERE path = $1
 `
+
+// Placeholders:
+//
+//	$1 - paths
+const sqlGetAllPerms = `
+SELECT path, permission, principal
+FROM permission_paths
+WHERE path = ANY($1)
+`
+
+const sqlScanUserPrincipals = `
+SELECT user_id, principal
+FROM permission_principals
+ORDER BY user_id, principal
+`
+
+const sqlScanPerms = `
+SELECT path, permission, principal
+FROM permission_paths
+ORDER BY path, permission, principal
+`
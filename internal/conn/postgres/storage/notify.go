@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/riposo/riposo/internal/conn/postgres/common"
+	"github.com/riposo/riposo/pkg/conn/storage"
+)
+
+const (
+	// notifyChannel is the channel used to NOTIFY committed changes.
+	notifyChannel = "riposo_storage"
+	// maxNotifyPayload is the maximum payload size, PostgreSQL limits payloads
+	// to 8000 bytes.
+	maxNotifyPayload = 7900
+)
+
+// Subscribe implements storage.Notifier interface. Changes are published via
+// LISTEN/NOTIFY, which allows subscribers to receive changes committed by all
+// instances connected to the same database. Changes may be missed while the
+// listener reconnects after connection failures.
+func (cn *conn) Subscribe(fn func([]storage.Change)) (func(), error) {
+	if err := cn.listen(); err != nil {
+		return nil, err
+	}
+	return cn.hub.Subscribe(fn)
+}
+
+func (cn *conn) listen() error {
+	cn.listenMu.Lock()
+	defer cn.listenMu.Unlock()
+
+	if cn.listener != nil {
+		return nil
+	}
+
+	listener := pq.NewListener(cn.dsn, 10*time.Second, time.Minute, nil)
+	if err := listener.Listen(notifyChannel); err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	cn.listener = listener
+	go cn.listenLoop(listener)
+	return nil
+}
+
+func (cn *conn) listenLoop(listener *pq.Listener) {
+	for n := range listener.Notify {
+		if n == nil { // reconnected
+			continue
+		}
+
+		var changes []storage.Change
+		if err := json.Unmarshal([]byte(n.Extra), &changes); err != nil {
+			continue
+		}
+		cn.hub.Publish(changes)
+	}
+}
+
+// --------------------------------------------------------------------
+
+type txSavepoint struct {
+	name    string
+	changes int
+}
+
+func (tx *transaction) track(change storage.Change) {
+	tx.changes = append(tx.changes, change)
+}
+
+func (tx *transaction) trackSavepoint(name string) {
+	tx.spts = append(tx.spts, txSavepoint{name: name, changes: len(tx.changes)})
+}
+
+func (tx *transaction) trackRollbackTo(name string) {
+	if pos := tx.findSavepoint(name); pos > -1 {
+		tx.changes = tx.changes[:tx.spts[pos].changes]
+		tx.spts = tx.spts[:pos+1]
+	}
+}
+
+func (tx *transaction) trackRelease(name string) {
+	if pos := tx.findSavepoint(name); pos > -1 {
+		tx.spts = tx.spts[:pos]
+	}
+}
+
+func (tx *transaction) findSavepoint(name string) int {
+	for i := len(tx.spts) - 1; i >= 0; i-- {
+		if tx.spts[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// notify issues NOTIFY statements with all tracked changes. Notifications are
+// only delivered once the transaction is committed.
+func (tx *transaction) notify() error {
+	if len(tx.changes) == 0 {
+		return nil
+	}
+
+	buf := new(bytes.Buffer)
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		buf.WriteByte(']')
+		_, err := tx.ExecContext(common.Detach(tx.ctx), `SELECT pg_notify($1, $2)`, notifyChannel, buf.String())
+		buf.Reset()
+		return err
+	}
+
+	for _, change := range tx.changes {
+		data, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+
+		if buf.Len() != 0 && buf.Len()+len(data)+2 > maxNotifyPayload {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		if buf.Len() == 0 {
+			buf.WriteByte('[')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.Write(data)
+	}
+	return flush()
+}
@@ -10,9 +10,14 @@ import (
 	"github.com/riposo/riposo/pkg/util"
 )
 
+const defaultSearchLanguage = "simple"
+
 type queryBuilder struct {
 	*minisql.Query
 	hasWhere bool
+
+	lang     string          // full-text search language
+	searches []params.Filter // full-text search filters, for relevance sorting
 }
 
 func newQueryBuilder() *queryBuilder {
@@ -33,19 +38,32 @@ func (b *queryBuilder) Limit(n int) {
 }
 
 func (b *queryBuilder) OrderBy(order []params.SortOrder) {
-	if len(order) == 0 {
-		return
-	}
+	var n int
+	for _, so := range order {
+		if so.Field == params.ScoreField && len(b.searches) == 0 {
+			continue // relevance is only available for full-text searches
+		}
 
-	b.AppendString(" ORDER BY ")
-	for i, so := range order {
-		if i != 0 {
+		if n++; n == 1 {
+			b.AppendString(" ORDER BY ")
+		} else {
 			b.AppendString(", ")
 		}
 
 		switch so.Field {
 		case "id", "last_modified":
 			b.AppendString(so.Field)
+		case params.ScoreField:
+			for i, flt := range b.searches {
+				if i != 0 {
+					b.AppendString(" + ")
+				}
+				b.AppendString("ts_rank(")
+				b.searchDocument(flt)
+				b.AppendString(", ")
+				b.searchQuery(flt)
+				b.AppendString(")")
+			}
 		default:
 			b.AppendString("data")
 			util.SplitFunc(so.Field, ".", func(attr string) {
@@ -61,6 +79,57 @@ func (b *queryBuilder) OrderBy(order []params.SortOrder) {
 	}
 }
 
+func (b *queryBuilder) Aggregate(agg params.Aggregation) {
+	for _, field := range agg.GroupBy {
+		b.jsonField(field)
+		b.AppendString(", ")
+	}
+
+	switch agg.Func {
+	case params.AggregateCount:
+		b.AppendString("COUNT(1)")
+		return
+	case params.AggregateSum:
+		b.AppendString("SUM(")
+	case params.AggregateAvg:
+		b.AppendString("AVG(")
+	case params.AggregateMin:
+		b.AppendString("MIN(")
+	case params.AggregateMax:
+		b.AppendString("MAX(")
+	}
+
+	// only consider numeric values
+	b.AppendString("CASE WHEN jsonb_typeof(")
+	b.jsonField(agg.Field)
+	b.AppendString(") = 'number' THEN (")
+	b.jsonField(agg.Field)
+	b.AppendString(" #>> '{}')::float8 END)")
+}
+
+func (b *queryBuilder) GroupBy(n int) {
+	if n == 0 {
+		return
+	}
+
+	b.AppendString(" GROUP BY ")
+	for i := 1; i <= n; i++ {
+		if i != 1 {
+			b.AppendString(", ")
+		}
+		b.AppendInt(int64(i))
+	}
+
+	b.AppendString(" ORDER BY ")
+	for i := 1; i <= n; i++ {
+		if i != 1 {
+			b.AppendString(", ")
+		}
+		b.AppendInt(int64(i))
+		b.AppendString(" ASC")
+	}
+}
+
 func (b *queryBuilder) Where(str string) {
 	b.where()
 	b.AppendString(str)
@@ -78,6 +147,12 @@ func (b *queryBuilder) ConditionFilter(cond params.Condition) {
 		return
 	}
 
+	for _, flt := range cond {
+		if flt.IsSearch() {
+			b.searches = append(b.searches, flt)
+		}
+	}
+
 	b.where()
 	b.condition(cond)
 }
@@ -205,9 +280,69 @@ func (b *queryBuilder) filter(flt params.Filter) {
 		} else {
 			b.AppendString("FALSE")
 		}
+	case params.OperatorAND:
+		b.nested(flt.Nested, " AND ", "TRUE")
+	case params.OperatorOR:
+		b.nested(flt.Nested, " OR ", "FALSE")
+	case params.OperatorSEARCH:
+		if flt.Field == "last_modified" {
+			b.AppendString("FALSE")
+		} else {
+			b.searchDocument(flt)
+			b.AppendString(" @@ ")
+			b.searchQuery(flt)
+		}
 	}
 }
 
+func (b *queryBuilder) nested(filters []params.Filter, sep, empty string) {
+	if len(filters) == 0 {
+		b.AppendString(empty)
+		return
+	}
+
+	b.AppendString("( ")
+	for i, flt := range filters {
+		if i != 0 {
+			b.AppendString(sep)
+		}
+		b.filter(flt)
+	}
+	b.AppendString(" )")
+}
+
+func (b *queryBuilder) searchDocument(flt params.Filter) {
+	b.AppendString("to_tsvector(")
+	b.searchLanguage()
+	b.AppendString(", ")
+	switch flt.Field {
+	case "":
+		b.AppendString("data")
+	case "id":
+		b.AppendString("id")
+	default:
+		b.filterField(flt)
+	}
+	b.AppendString(")")
+}
+
+func (b *queryBuilder) searchQuery(flt params.Filter) {
+	b.AppendString("websearch_to_tsquery(")
+	b.searchLanguage()
+	b.AppendString(", ")
+	b.AppendValue(flt.Value(0).String())
+	b.AppendString(")")
+}
+
+func (b *queryBuilder) searchLanguage() {
+	lang := b.lang
+	if lang == "" {
+		lang = defaultSearchLanguage
+	}
+	b.AppendValue(lang)
+	b.AppendString("::regconfig")
+}
+
 func (b *queryBuilder) filterComparison(flt params.Filter, opstr string, required bool) {
 	if isDataQuery(flt.Field) {
 		b.AppendString("(")
@@ -257,6 +392,23 @@ func (b *queryBuilder) filterField(flt params.Filter) {
 	}
 }
 
+// jsonField appends a field as JSONB, normalizing JSON null values to NULL.
+func (b *queryBuilder) jsonField(field string) {
+	switch field {
+	case "id", "last_modified":
+		b.AppendString("to_jsonb(")
+		b.AppendString(field)
+		b.AppendString(")")
+	default:
+		b.AppendString("NULLIF(data")
+		util.SplitFunc(field, ".", func(attr string) {
+			b.AppendString("->")
+			b.AppendValue(attr)
+		})
+		b.AppendString(", 'null'::jsonb)")
+	}
+}
+
 func (b *queryBuilder) filterValue(flt params.Filter) {
 	switch flt.Operator {
 	case params.OperatorLIKE:
@@ -101,6 +101,48 @@ ON CONFLICT (path, id) DO UPDATE SET
 RETURNING last_modified
 `
 
+// Placeholders:
+//
+//	$1 - path
+//	$2 - id
+//	$3 - data
+//	$4 - deleted
+const sqlRestoreObject = `
+INSERT INTO storage_objects (
+  path,
+  id,
+  data,
+  last_modified,
+  deleted
+)
+VALUES (
+  $1,
+  $2,
+  ($3)::JSONB,
+  NULL,
+  $4
+)
+ON CONFLICT (path, id) DO UPDATE SET
+  data = EXCLUDED.data,
+  last_modified = EXCLUDED.last_modified,
+  deleted = EXCLUDED.deleted
+WHERE storage_objects.deleted = TRUE
+`
+
+// Placeholders:
+//
+//	$1 - path
+//	$2 - id
+//	$3 - last_modified
+//
+// Updates of last_modified only do not trigger storage_objects_set_last_modified.
+const sqlRestoreModTime = `
+UPDATE storage_objects
+  SET last_modified = $3
+WHERE path = $1
+AND id = $2
+`
+
 // Placeholders:
 //
 //	$1 - path
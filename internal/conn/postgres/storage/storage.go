@@ -6,7 +6,11 @@ import (
 	"embed"
 	"errors"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/lib/pq"
 	"github.com/riposo/riposo/internal/conn/postgres/common"
 	"github.com/riposo/riposo/pkg/conn/storage"
 	"github.com/riposo/riposo/pkg/riposo"
@@ -28,9 +32,16 @@ func init() {
 // --------------------------------------------------------------------
 
 type conn struct {
-	db   *sql.DB
-	hlp  riposo.Helpers
-	stmt struct {
+	db         *sql.DB
+	hlp        riposo.Helpers
+	searchLang string
+	replicas   []*conn
+	balancer   *common.Balancer
+	dsn        string
+	hub        storage.Hub
+	listener   *pq.Listener
+	listenMu   sync.Mutex
+	stmt       struct {
 		getModTime,
 		existsObject,
 		getObject,
@@ -43,19 +54,56 @@ type conn struct {
 	}
 }
 
-// Connect connects to a PostgreSQL server.
+// Connect connects to a PostgreSQL server. The language used for full-text
+// searches can be configured via a search_language query parameter,
+// e.g. postgres://localhost/riposo?search_language=english.
+//
+// Read replicas can be specified via replica query parameters, e.g.
+// postgres://primary/riposo?replica=replica1&replica=replica2. Transactions
+// with a read-only hint are routed to healthy replicas.
 func Connect(ctx context.Context, dsn string, hlp riposo.Helpers) (storage.Backend, error) {
+	dsn, searchLang, err := extractSearchLanguage(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn, replicaDSNs, err := common.SplitReplicas(dsn)
+	if err != nil {
+		return nil, err
+	}
+
 	// connect to the DB.
 	db, err := common.Connect(ctx, dsn, "storage_schema_version", schemaVersion, embedFS)
 	if err != nil {
 		return nil, err
 	}
 
-	cn := &conn{db: db, hlp: hlp}
+	cn := &conn{db: db, hlp: hlp, searchLang: searchLang}
 	if err := cn.prepare(ctx); err != nil {
 		_ = cn.Close()
 		return nil, err
 	}
+	if cn.dsn, _, err = common.ParseOptions(dsn); err != nil {
+		_ = cn.Close()
+		return nil, err
+	}
+
+	// connect to the replicas.
+	for _, replicaDSN := range replicaDSNs {
+		rdb, err := common.ConnectReplica(ctx, replicaDSN, "storage_schema_version", schemaVersion)
+		if err != nil {
+			_ = cn.Close()
+			return nil, err
+		}
+
+		replica := &conn{db: rdb, hlp: hlp, searchLang: searchLang}
+		cn.replicas = append(cn.replicas, replica)
+		if err := replica.prepare(ctx); err != nil {
+			_ = cn.Close()
+			return nil, err
+		}
+	}
+	cn.balancer = common.NewBalancer(len(cn.replicas))
 
 	return cn, nil
 }
@@ -98,9 +146,28 @@ func (cn *conn) Ping(ctx context.Context) error {
 	return cn.db.PingContext(ctx)
 }
 
-// Begin implements storage.Backend interface.
-func (cn *conn) Begin(ctx context.Context) (storage.Transaction, error) {
-	tx, err := cn.db.BeginTx(ctx, nil)
+// PoolStats implements conn.Pooled interface.
+func (cn *conn) PoolStats() *schema.PoolStats {
+	return common.PoolStats(cn.db)
+}
+
+// Begin implements storage.Backend interface. Read-only transactions are
+// routed to replicas, if configured.
+func (cn *conn) Begin(ctx context.Context, opt *riposo.TxOptions) (storage.Transaction, error) {
+	var rtx *transaction
+	if opt.IsReadOnly() && cn.balancer.Pick(ctx, func(i int) error {
+		replica := cn.replicas[i]
+		tx, err := replica.db.BeginTx(common.Detach(ctx), common.TxOptions(opt))
+		if err != nil {
+			return err
+		}
+		rtx = &transaction{Tx: tx, cn: replica, ctx: ctx}
+		return nil
+	}) {
+		return rtx, nil
+	}
+
+	tx, err := cn.db.BeginTx(common.Detach(ctx), common.TxOptions(opt))
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +176,12 @@ func (cn *conn) Begin(ctx context.Context) (storage.Transaction, error) {
 
 // Close closes the DB connection.
 func (cn *conn) Close() (err error) {
+	if cn.listener != nil {
+		err = multierr.Append(err, cn.listener.Close())
+	}
+	for _, replica := range cn.replicas {
+		err = multierr.Append(err, replica.Close())
+	}
 	if cn.stmt.getModTime != nil {
 		err = multierr.Append(err, cn.stmt.getModTime.Close())
 	}
@@ -146,12 +219,18 @@ func (cn *conn) Close() (err error) {
 
 type transaction struct {
 	*sql.Tx
-	cn  *conn
-	ctx context.Context
+	cn      *conn
+	ctx     context.Context
+	changes []storage.Change
+	spts    []txSavepoint
 }
 
 // Commit implements storage.Transaction interface.
 func (tx *transaction) Commit() error {
+	if err := tx.notify(); err != nil {
+		_ = tx.Tx.Rollback()
+		return normErr(err)
+	}
 	return normErr(tx.Tx.Commit())
 }
 
@@ -160,10 +239,40 @@ func (tx *transaction) Rollback() error {
 	return normErr(tx.Tx.Rollback())
 }
 
+// Savepoint implements storage.Transaction interface.
+func (tx *transaction) Savepoint(name string) error {
+	if _, err := tx.ExecContext(tx.ctx, `SAVEPOINT `+pq.QuoteIdentifier(name)); err != nil {
+		return normErr(err)
+	}
+	tx.trackSavepoint(name)
+	return nil
+}
+
+// RollbackTo implements storage.Transaction interface.
+func (tx *transaction) RollbackTo(name string) error {
+	if _, err := tx.ExecContext(tx.ctx, `ROLLBACK TO SAVEPOINT `+pq.QuoteIdentifier(name)); err != nil {
+		return normErr(err)
+	}
+	tx.trackRollbackTo(name)
+	return nil
+}
+
+// Release implements storage.Transaction interface.
+func (tx *transaction) Release(name string) error {
+	if _, err := tx.ExecContext(tx.ctx, `RELEASE SAVEPOINT `+pq.QuoteIdentifier(name)); err != nil {
+		return normErr(err)
+	}
+	tx.trackRelease(name)
+	return nil
+}
+
 // Flush implements storage.Transaction interface.
 func (tx *transaction) Flush() error {
-	_, err := tx.ExecContext(tx.ctx, `TRUNCATE storage_objects, storage_timestamps`)
-	return normErr(err)
+	if _, err := tx.ExecContext(tx.ctx, `TRUNCATE storage_objects, storage_timestamps`); err != nil {
+		return normErr(err)
+	}
+	tx.track(storage.Change{Deleted: true})
+	return nil
 }
 
 // ModTime implements storage.Transaction interface.
@@ -322,6 +431,33 @@ func (tx *transaction) Create(path riposo.Path, obj *schema.Object) error {
 	}
 
 	obj.ModTime = modTime
+	tx.track(storage.Change{Path: riposo.JoinPath(ns, obj.ID), ModTime: modTime})
+	return nil
+}
+
+// Restore implements storage.Restorer interface.
+func (tx *transaction) Restore(path riposo.Path, obj *schema.Object) error {
+	if !path.IsNode() || obj.ID == "" {
+		return storage.ErrInvalidPath
+	}
+
+	ns, _ := path.Split()
+	obj.Norm()
+
+	res, err := tx.ExecContext(tx.ctx, sqlRestoreObject, ns, obj.ID, obj.Extra, obj.Deleted)
+	if err != nil {
+		return normErr(err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return storage.ErrObjectExists
+	}
+
+	if _, err := tx.ExecContext(tx.ctx, sqlRestoreModTime, ns, obj.ID, obj.ModTime); err != nil {
+		return normErr(err)
+	}
+	tx.track(storage.Change{Path: riposo.JoinPath(ns, obj.ID), ModTime: obj.ModTime, Deleted: obj.Deleted})
 	return nil
 }
 
@@ -341,6 +477,7 @@ func (tx *transaction) Update(path riposo.Path, obj *schema.Object) error {
 	}
 
 	obj.ModTime = modTime
+	tx.track(storage.Change{Path: path, ModTime: modTime})
 	return nil
 }
 
@@ -373,6 +510,7 @@ func (tx *transaction) Delete(path riposo.Path) (*schema.Object, error) {
 		return nil, normErr(err)
 	}
 
+	tx.track(storage.Change{Path: path, ModTime: obj.ModTime, Deleted: true})
 	return &obj, nil
 }
 
@@ -383,6 +521,7 @@ func (tx *transaction) CountAll(path riposo.Path, opt storage.CountOptions) (int
 	}
 
 	stmt := newQueryBuilder()
+	stmt.lang = tx.cn.searchLang
 	defer stmt.Release()
 
 	ns, _ := path.Split()
@@ -406,6 +545,7 @@ func (tx *transaction) ListAll(path riposo.Path, opt storage.ListOptions) ([]*sc
 	}
 
 	stmt := newQueryBuilder()
+	stmt.lang = tx.cn.searchLang
 	defer stmt.Release()
 
 	ns, _ := path.Split()
@@ -439,6 +579,67 @@ func (tx *transaction) ListAll(path riposo.Path, opt storage.ListOptions) ([]*sc
 	return objs, nil
 }
 
+// Aggregate implements storage.Aggregator interface.
+func (tx *transaction) Aggregate(path riposo.Path, opt storage.AggregateOptions) ([]storage.AggregateGroup, error) {
+	if !path.IsNode() {
+		return nil, storage.ErrInvalidPath
+	}
+
+	stmt := newQueryBuilder()
+	stmt.lang = tx.cn.searchLang
+	defer stmt.Release()
+
+	ns, _ := path.Split()
+	stmt.AppendString(`SELECT `)
+	stmt.Aggregate(opt.Aggregation)
+	stmt.AppendString(` FROM storage_objects`)
+	stmt.Where(`path = `)
+	stmt.AppendValue(ns)
+	stmt.Where(`NOT deleted`)
+	stmt.ConditionFilter(opt.Condition)
+	stmt.GroupBy(len(opt.Aggregation.GroupBy))
+
+	rows, err := stmt.QueryContext(tx.ctx, tx)
+	if err != nil {
+		return nil, normErr(err)
+	}
+	defer rows.Close()
+
+	numKeys := len(opt.Aggregation.GroupBy)
+	keys := make([][]byte, numKeys)
+	dest := make([]interface{}, 0, numKeys+1)
+	for i := range keys {
+		dest = append(dest, &keys[i])
+	}
+
+	var value sql.NullFloat64
+	dest = append(dest, &value)
+
+	var groups []storage.AggregateGroup
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		group := storage.AggregateGroup{
+			Keys:  make([]schema.Value, 0, numKeys),
+			Value: schema.ParseValue(""),
+		}
+		for _, key := range keys {
+			group.Keys = append(group.Keys, schema.ParseValue(string(key)))
+		}
+		if value.Valid {
+			group.Value = schema.ParseValue(strconv.FormatFloat(value.Float64, 'f', -1, 64))
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
 // DeleteAll implements storage.Transaction interface.
 func (tx *transaction) DeleteAll(paths []riposo.Path) (riposo.Epoch, []riposo.Path, error) {
 	for _, path := range paths {
@@ -527,6 +728,9 @@ func (tx *transaction) DeleteAll(paths []riposo.Path) (riposo.Epoch, []riposo.Pa
 		return 0, nil, err
 	}
 
+	for _, path := range deleted {
+		tx.track(storage.Change{Path: path, ModTime: modTime, Deleted: true})
+	}
 	return modTime, deleted, nil
 }
 
@@ -545,8 +749,36 @@ func (tx *transaction) Purge(olderThan riposo.Epoch) (int64, error) {
 func normErr(err error) error {
 	if errors.Is(err, sql.ErrTxDone) {
 		return storage.ErrTxDone
+	} else if common.IsInvalidSavepoint(err) {
+		return storage.ErrNoSavepoint
+	} else if common.IsReadOnly(err) {
+		return storage.ErrReadOnly
+	} else if common.IsTimeout(err) {
+		return schema.BackendError(err)
 	} else if errors.Is(err, sql.ErrNoRows) {
 		return storage.ErrNotFound
 	}
 	return err
 }
+
+// extractSearchLanguage removes the search_language option from a DSN URL.
+func extractSearchLanguage(dsn string) (string, string, error) {
+	if !strings.Contains(dsn, "://") {
+		return dsn, "", nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", err
+	}
+
+	query := u.Query()
+	lang := query.Get("search_language")
+	if lang == "" {
+		return dsn, "", nil
+	}
+
+	query.Del("search_language")
+	u.RawQuery = query.Encode()
+	return u.String(), lang, nil
+}
@@ -29,7 +29,7 @@ var _ = Describe("Backend", func() {
 	})
 
 	It("supports CONTAINS ANY filters", func() {
-		tx, err := instance.Begin(ctx)
+		tx, err := instance.Begin(ctx, nil)
 		Expect(err).NotTo(HaveOccurred())
 		defer tx.Rollback()
 
@@ -0,0 +1,181 @@
+package common
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/riposo"
+)
+
+// Scheme is the URL scheme of router backends.
+const Scheme = "router"
+
+// Config configures routes.
+type Config struct {
+	// Default is the URL of the backend that stores all data outside of
+	// routed buckets, including bucket objects.
+	Default string
+	// Routes map bucket ID patterns to backend URLs. The first matching route
+	// wins.
+	Routes []Route
+}
+
+// Route maps bucket ID patterns to a backend URL.
+type Route struct {
+	Buckets []string
+	URL     string
+}
+
+// Table is a routing table.
+type Table struct {
+	urls   []string
+	routes []route
+}
+
+type route struct {
+	buckets []string
+	index   int
+}
+
+// NewTable inits a new routing table.
+func NewTable(cfg *Config) (*Table, error) {
+	if cfg.Default == "" {
+		return nil, fmt.Errorf("no default backend URL")
+	}
+
+	t := new(Table)
+	if _, err := t.addURL(cfg.Default); err != nil {
+		return nil, err
+	}
+
+	for _, r := range cfg.Routes {
+		if len(r.Buckets) == 0 {
+			return nil, fmt.Errorf("route to %q has no buckets", r.URL)
+		}
+
+		index, err := t.addURL(r.URL)
+		if err != nil {
+			return nil, err
+		}
+		t.routes = append(t.routes, route{buckets: r.Buckets, index: index})
+	}
+	return t, nil
+}
+
+func (t *Table) addURL(s string) (int, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return 0, fmt.Errorf("invalid backend URL %q", s)
+	} else if u.Scheme == Scheme {
+		return 0, fmt.Errorf("invalid backend URL %q, routers cannot be nested", s)
+	}
+
+	for i, exst := range t.urls {
+		if exst == s {
+			return i, nil
+		}
+	}
+	t.urls = append(t.urls, s)
+	return len(t.urls) - 1, nil
+}
+
+// URLs returns the URLs of all backends, starting with the default.
+func (t *Table) URLs() []string {
+	return t.urls
+}
+
+// Lookup returns the index of the backend which stores data at the given path.
+func (t *Table) Lookup(path riposo.Path) int {
+	bucketID, nested := splitBucket(path)
+	if !nested {
+		return 0
+	}
+	return t.match(bucketID)
+}
+
+// Spread returns the indices of the backends which may store data at or
+// below the given path, e.g. for recursive deletes.
+func (t *Table) Spread(path riposo.Path) []int {
+	bucketID, nested := splitBucket(path)
+	if nested {
+		return []int{t.match(bucketID)}
+	} else if i := t.match(bucketID); i != 0 {
+		return []int{0, i}
+	}
+	return []int{0}
+}
+
+func (t *Table) match(bucketID string) int {
+	if bucketID == "" {
+		return 0
+	}
+
+	for _, r := range t.routes {
+		if riposo.Path(bucketID).Match(r.buckets...) {
+			return r.index
+		}
+	}
+	return 0
+}
+
+// splitBucket extracts the bucket ID from a path and reports whether the path
+// is nested within a bucket.
+func splitBucket(path riposo.Path) (string, bool) {
+	s := path.String()
+	if !strings.HasPrefix(s, "/buckets/") {
+		return "", false
+	}
+
+	bucketID, _, nested := strings.Cut(s[len("/buckets/"):], "/")
+	return bucketID, nested
+}
+
+// Group groups paths by the indices of the backends returned by fn.
+func Group(paths []riposo.Path, fn func(riposo.Path) []int) map[int][]riposo.Path {
+	groups := make(map[int][]riposo.Path)
+	for _, path := range paths {
+		for _, i := range fn(path) {
+			groups[i] = append(groups[i], path)
+		}
+	}
+	return groups
+}
+
+// Savepoints tracks the names of active savepoints, so they can be replayed
+// on lazily started transactions.
+type Savepoints []string
+
+// Add adds a savepoint.
+func (s *Savepoints) Add(name string) {
+	*s = append(*s, name)
+}
+
+// RollbackTo discards all savepoints after name. It reports whether the
+// savepoint exists.
+func (s *Savepoints) RollbackTo(name string) bool {
+	if i := s.find(name); i > -1 {
+		*s = (*s)[:i+1]
+		return true
+	}
+	return false
+}
+
+// Release releases the savepoint name and all savepoints after it. It reports
+// whether the savepoint exists.
+func (s *Savepoints) Release(name string) bool {
+	if i := s.find(name); i > -1 {
+		*s = (*s)[:i]
+		return true
+	}
+	return false
+}
+
+func (s Savepoints) find(name string) int {
+	for i := len(s) - 1; i > -1; i-- {
+		if s[i] == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,103 @@
+package common_test
+
+import (
+	"testing"
+
+	"github.com/riposo/riposo/pkg/riposo"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/internal/conn/router/common"
+)
+
+var _ = Describe("Table", func() {
+	var subject *Table
+
+	BeforeEach(func() {
+		var err error
+		subject, err = NewTable(&Config{
+			Default: "memory:",
+			Routes: []Route{
+				{Buckets: []string{"big-*", "!big-small"}, URL: "postgres://big/riposo"},
+				{Buckets: []string{"huge"}, URL: "postgres://huge/riposo"},
+				{Buckets: []string{"large"}, URL: "postgres://big/riposo"},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("validates", func() {
+		_, err := NewTable(&Config{})
+		Expect(err).To(MatchError("no default backend URL"))
+
+		_, err = NewTable(&Config{Default: "router:"})
+		Expect(err).To(MatchError(`invalid backend URL "router:", routers cannot be nested`))
+
+		_, err = NewTable(&Config{Default: "memory:", Routes: []Route{{URL: "memory://x"}}})
+		Expect(err).To(MatchError(`route to "memory://x" has no buckets`))
+
+		_, err = NewTable(&Config{Default: "memory:", Routes: []Route{{Buckets: []string{"x"}, URL: "bad"}}})
+		Expect(err).To(MatchError(`invalid backend URL "bad"`))
+	})
+
+	It("deduplicates URLs", func() {
+		Expect(subject.URLs()).To(Equal([]string{
+			"memory:",
+			"postgres://big/riposo",
+			"postgres://huge/riposo",
+		}))
+	})
+
+	It("looks up paths", func() {
+		Expect(subject.Lookup("/accounts/alice")).To(Equal(0))
+		Expect(subject.Lookup("/buckets/*")).To(Equal(0))
+		Expect(subject.Lookup("/buckets/big-one")).To(Equal(0))
+		Expect(subject.Lookup("/buckets/big-one/collections/*")).To(Equal(1))
+		Expect(subject.Lookup("/buckets/big-small/collections/*")).To(Equal(0))
+		Expect(subject.Lookup("/buckets/huge/collections/c/records/r")).To(Equal(2))
+		Expect(subject.Lookup("/buckets/large/groups/g")).To(Equal(1))
+		Expect(subject.Lookup("/buckets/other/groups/g")).To(Equal(0))
+	})
+
+	It("spreads paths", func() {
+		Expect(subject.Spread("/accounts/alice")).To(Equal([]int{0}))
+		Expect(subject.Spread("/buckets/other")).To(Equal([]int{0}))
+		Expect(subject.Spread("/buckets/huge")).To(Equal([]int{0, 2}))
+		Expect(subject.Spread("/buckets/huge/collections/c")).To(Equal([]int{2}))
+		Expect(subject.Spread("/buckets/*")).To(Equal([]int{0}))
+	})
+
+	It("groups paths", func() {
+		Expect(Group([]riposo.Path{
+			"/buckets/huge",
+			"/buckets/big-one/collections/c",
+			"/buckets/other",
+		}, subject.Spread)).To(Equal(map[int][]riposo.Path{
+			0: {"/buckets/huge", "/buckets/other"},
+			1: {"/buckets/big-one/collections/c"},
+			2: {"/buckets/huge"},
+		}))
+	})
+})
+
+var _ = Describe("Savepoints", func() {
+	It("tracks savepoints", func() {
+		var subject Savepoints
+		subject.Add("a")
+		subject.Add("b")
+		subject.Add("c")
+
+		Expect(subject.RollbackTo("b")).To(BeTrue())
+		Expect(subject).To(Equal(Savepoints{"a", "b"}))
+		Expect(subject.RollbackTo("c")).To(BeFalse())
+
+		Expect(subject.Release("b")).To(BeTrue())
+		Expect(subject).To(Equal(Savepoints{"a"}))
+		Expect(subject.Release("b")).To(BeFalse())
+	})
+})
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "internal/conn/router/common")
+}
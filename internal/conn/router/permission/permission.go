@@ -0,0 +1,448 @@
+package permission
+
+import (
+	"context"
+	"net/url"
+	"sort"
+
+	"github.com/riposo/riposo/internal/conn/router/common"
+	"github.com/riposo/riposo/pkg/conn/permission"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+	"github.com/riposo/riposo/pkg/util"
+	"go.uber.org/multierr"
+)
+
+func init() {
+	permission.Register(common.Scheme, func(ctx context.Context, _ *url.URL, hlp riposo.Helpers) (permission.Backend, error) {
+		var cfg struct {
+			Permission struct {
+				Router common.Config
+			}
+		}
+		if err := hlp.ParseConfig(&cfg); err != nil {
+			return nil, err
+		}
+
+		tbl, err := common.NewTable(&cfg.Permission.Router)
+		if err != nil {
+			return nil, err
+		}
+
+		backends := make([]permission.Backend, 0, len(tbl.URLs()))
+		for _, u := range tbl.URLs() {
+			b, err := permission.Connect(ctx, u, hlp)
+			if err != nil {
+				for _, b := range backends {
+					_ = b.Close()
+				}
+				return nil, err
+			}
+			backends = append(backends, b)
+		}
+		return New(tbl, backends), nil
+	})
+}
+
+type backend struct {
+	tbl      *common.Table
+	backends []permission.Backend
+}
+
+// New inits a new routing backend. Backends must be aligned with the URLs of
+// the table. User principals are stored in the default backend.
+func New(tbl *common.Table, backends []permission.Backend) permission.Backend {
+	return &backend{tbl: tbl, backends: backends}
+}
+
+// Ping implements permission.Backend interface.
+func (b *backend) Ping(ctx context.Context) error {
+	for _, sub := range b.backends {
+		if err := sub.Ping(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Begin implements permission.Backend interface. Transactions are started on
+// backends in order, once they are first accessed, and committed one after
+// another. Please note that commits are not atomic across backends.
+func (b *backend) Begin(ctx context.Context, opt *riposo.TxOptions) (permission.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &transaction{
+		ctx:      ctx,
+		opt:      opt,
+		tbl:      b.tbl,
+		backends: b.backends,
+		txs:      make([]permission.Transaction, len(b.backends)),
+	}, nil
+}
+
+// Close implements permission.Backend interface.
+func (b *backend) Close() (err error) {
+	for _, sub := range b.backends {
+		err = multierr.Append(err, sub.Close())
+	}
+	return
+}
+
+// --------------------------------------------------------------------
+
+type transaction struct {
+	ctx      context.Context
+	opt      *riposo.TxOptions
+	tbl      *common.Table
+	backends []permission.Backend
+	txs      []permission.Transaction
+	spts     common.Savepoints
+	done     bool
+}
+
+// Commit implements permission.Transaction interface.
+func (t *transaction) Commit() (err error) {
+	if t.done {
+		return permission.ErrTxDone
+	}
+	t.done = true
+
+	for _, tx := range t.txs {
+		if tx == nil {
+			continue
+		}
+
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}
+	return
+}
+
+// Rollback implements permission.Transaction interface.
+func (t *transaction) Rollback() (err error) {
+	if t.done {
+		return permission.ErrTxDone
+	}
+	t.done = true
+
+	for _, tx := range t.txs {
+		if tx != nil {
+			err = multierr.Append(err, tx.Rollback())
+		}
+	}
+	return
+}
+
+// Savepoint implements permission.Transaction interface.
+func (t *transaction) Savepoint(name string) error {
+	if err := t.started(func(tx permission.Transaction) error { return tx.Savepoint(name) }); err != nil {
+		return err
+	}
+	t.spts.Add(name)
+	return nil
+}
+
+// RollbackTo implements permission.Transaction interface.
+func (t *transaction) RollbackTo(name string) error {
+	if !t.spts.RollbackTo(name) {
+		return permission.ErrNoSavepoint
+	}
+	return t.started(func(tx permission.Transaction) error { return tx.RollbackTo(name) })
+}
+
+// Release implements permission.Transaction interface.
+func (t *transaction) Release(name string) error {
+	if !t.spts.Release(name) {
+		return permission.ErrNoSavepoint
+	}
+	return t.started(func(tx permission.Transaction) error { return tx.Release(name) })
+}
+
+// Flush implements permission.Transaction interface.
+func (t *transaction) Flush() error {
+	return t.each(func(tx permission.Transaction) error { return tx.Flush() })
+}
+
+// GetUserPrincipals implements permission.Transaction interface.
+func (t *transaction) GetUserPrincipals(userID string) ([]string, error) {
+	tx, err := t.tx(0)
+	if err != nil {
+		return nil, err
+	}
+	return tx.GetUserPrincipals(userID)
+}
+
+// AddUserPrincipal implements permission.Transaction interface.
+func (t *transaction) AddUserPrincipal(principal string, userIDs []string) error {
+	tx, err := t.tx(0)
+	if err != nil {
+		return err
+	}
+	return tx.AddUserPrincipal(principal, userIDs)
+}
+
+// RemoveUserPrincipal implements permission.Transaction interface.
+func (t *transaction) RemoveUserPrincipal(principal string, userIDs []string) error {
+	tx, err := t.tx(0)
+	if err != nil {
+		return err
+	}
+	return tx.RemoveUserPrincipal(principal, userIDs)
+}
+
+// PurgeUserPrincipals implements permission.Transaction interface.
+func (t *transaction) PurgeUserPrincipals(principals []string) error {
+	tx, err := t.tx(0)
+	if err != nil {
+		return err
+	}
+	return tx.PurgeUserPrincipals(principals)
+}
+
+// GetACEPrincipals implements permission.Transaction interface.
+func (t *transaction) GetACEPrincipals(ent permission.ACE) ([]string, error) {
+	tx, err := t.route(ent.Path)
+	if err != nil {
+		return nil, err
+	}
+	return tx.GetACEPrincipals(ent)
+}
+
+// AddACEPrincipal implements permission.Transaction interface.
+func (t *transaction) AddACEPrincipal(principal string, ent permission.ACE) error {
+	tx, err := t.route(ent.Path)
+	if err != nil {
+		return err
+	}
+	return tx.AddACEPrincipal(principal, ent)
+}
+
+// RemoveACEPrincipal implements permission.Transaction interface.
+func (t *transaction) RemoveACEPrincipal(principal string, ent permission.ACE) error {
+	tx, err := t.route(ent.Path)
+	if err != nil {
+		return err
+	}
+	return tx.RemoveACEPrincipal(principal, ent)
+}
+
+// GetAllACEPrincipals implements permission.Transaction interface.
+func (t *transaction) GetAllACEPrincipals(ents []permission.ACE) ([]string, error) {
+	if len(ents) == 0 {
+		return nil, nil
+	}
+
+	groups := t.groupACEs(ents)
+	if len(groups) == 1 {
+		for i, group := range groups {
+			tx, err := t.tx(i)
+			if err != nil {
+				return nil, err
+			}
+			return tx.GetAllACEPrincipals(group)
+		}
+	}
+
+	res := util.NewSet()
+	for i := range t.txs {
+		group, ok := groups[i]
+		if !ok {
+			continue
+		}
+
+		tx, err := t.tx(i)
+		if err != nil {
+			return nil, err
+		}
+
+		principals, err := tx.GetAllACEPrincipals(group)
+		if err != nil {
+			return nil, err
+		}
+		res.MergeSlice(principals)
+	}
+	return res.Slice(), nil
+}
+
+// GetPermissions implements permission.Transaction interface.
+func (t *transaction) GetPermissions(path riposo.Path) (schema.PermissionSet, error) {
+	tx, err := t.route(path)
+	if err != nil {
+		return nil, err
+	}
+	return tx.GetPermissions(path)
+}
+
+// GetAllPermissions implements permission.Transaction interface.
+func (t *transaction) GetAllPermissions(paths []riposo.Path) ([]schema.PermissionSet, error) {
+	positions := make(map[int][]int)
+	for pos, path := range paths {
+		i := t.tbl.Lookup(path)
+		positions[i] = append(positions[i], pos)
+	}
+
+	sets := make([]schema.PermissionSet, len(paths))
+	for i, group := range positions {
+		batch := make([]riposo.Path, 0, len(group))
+		for _, pos := range group {
+			batch = append(batch, paths[pos])
+		}
+
+		tx, err := t.tx(i)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := tx.GetAllPermissions(batch)
+		if err != nil {
+			return nil, err
+		}
+		for n, pos := range group {
+			sets[pos] = res[n]
+		}
+	}
+	return sets, nil
+}
+
+// CreatePermissions implements permission.Transaction interface.
+func (t *transaction) CreatePermissions(path riposo.Path, set schema.PermissionSet) error {
+	tx, err := t.route(path)
+	if err != nil {
+		return err
+	}
+	return tx.CreatePermissions(path, set)
+}
+
+// MergePermissions implements permission.Transaction interface.
+func (t *transaction) MergePermissions(path riposo.Path, set schema.PermissionSet) error {
+	tx, err := t.route(path)
+	if err != nil {
+		return err
+	}
+	return tx.MergePermissions(path, set)
+}
+
+// DeletePermissions implements permission.Transaction interface.
+func (t *transaction) DeletePermissions(paths []riposo.Path) error {
+	groups := common.Group(paths, t.tbl.Spread)
+	for i := range t.txs {
+		group, ok := groups[i]
+		if !ok {
+			continue
+		}
+
+		tx, err := t.tx(i)
+		if err != nil {
+			return err
+		}
+		if err := tx.DeletePermissions(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAccessiblePaths implements permission.Transaction interface.
+func (t *transaction) GetAccessiblePaths(dst []riposo.Path, principals []string, ents []permission.ACE) ([]riposo.Path, error) {
+	if len(principals) == 0 || len(ents) == 0 {
+		return dst, nil
+	}
+
+	groups := t.groupACEs(ents)
+	for i := range t.txs {
+		group, ok := groups[i]
+		if !ok {
+			continue
+		}
+
+		tx, err := t.tx(i)
+		if err != nil {
+			return nil, err
+		}
+		if dst, err = tx.GetAccessiblePaths(dst, principals, group); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(dst, func(i, j int) bool { return dst[i] < dst[j] })
+	return dst, nil
+}
+
+func (t *transaction) groupACEs(ents []permission.ACE) map[int][]permission.ACE {
+	groups := make(map[int][]permission.ACE)
+	for _, ent := range ents {
+		i := t.tbl.Lookup(ent.Path)
+		groups[i] = append(groups[i], ent)
+	}
+	return groups
+}
+
+// tx returns the transaction of the i-th backend. Transactions are started on
+// first use and savepoints are replayed on them. Backends are always started
+// in index order, including all backends before i, so that locks are acquired
+// in the same order by concurrent transactions.
+func (t *transaction) tx(i int) (permission.Transaction, error) {
+	if t.done {
+		return nil, permission.ErrTxDone
+	} else if tx := t.txs[i]; tx != nil {
+		return tx, nil
+	}
+
+	for j := 0; j <= i; j++ {
+		if t.txs[j] != nil {
+			continue
+		}
+
+		tx, err := t.backends[j].Begin(t.ctx, t.opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range t.spts {
+			if err := tx.Savepoint(name); err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+		}
+		t.txs[j] = tx
+	}
+	return t.txs[i], nil
+}
+
+func (t *transaction) route(path riposo.Path) (permission.Transaction, error) {
+	return t.tx(t.tbl.Lookup(path))
+}
+
+// each applies fn to the transactions of all backends.
+func (t *transaction) each(fn func(permission.Transaction) error) error {
+	for i := range t.txs {
+		tx, err := t.tx(i)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// started applies fn to all started transactions.
+func (t *transaction) started(fn func(permission.Transaction) error) error {
+	if t.done {
+		return permission.ErrTxDone
+	}
+
+	for _, tx := range t.txs {
+		if tx == nil {
+			continue
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package permission_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/riposo/riposo/internal/conn/router/common"
+	"github.com/riposo/riposo/pkg/conn/permission"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	memp "github.com/riposo/riposo/internal/conn/memory/permission"
+	. "github.com/riposo/riposo/internal/conn/router/permission"
+)
+
+var _ = Describe("Backend", func() {
+	var subject permission.Backend
+	var main, big permission.Backend
+	var tx permission.Transaction
+	var ctx = context.Background()
+
+	getPermissions := func(b permission.Backend, path riposo.Path) schema.PermissionSet {
+		tx, err := b.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		set, err := tx.GetPermissions(path)
+		Expect(err).NotTo(HaveOccurred())
+		return set
+	}
+
+	BeforeEach(func() {
+		tbl, err := common.NewTable(&common.Config{
+			Default: "memory:",
+			Routes:  []common.Route{{Buckets: []string{"big"}, URL: "memory://big"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		main = memp.New()
+		big = memp.New()
+		subject = New(tbl, []permission.Backend{main, big})
+
+		tx, err = subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(tx.AddUserPrincipal("team", []string{"alice"})).To(Succeed())
+		Expect(tx.CreatePermissions("/buckets/big", schema.PermissionSet{"write": {"alice"}})).To(Succeed())
+		Expect(tx.CreatePermissions("/buckets/small", schema.PermissionSet{"write": {"alice"}})).To(Succeed())
+		Expect(tx.CreatePermissions("/buckets/big/collections/c1", schema.PermissionSet{"read": {"team"}})).To(Succeed())
+		Expect(tx.CreatePermissions("/buckets/small/collections/c2", schema.PermissionSet{"read": {"bob"}})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		_ = tx.Rollback()
+		Expect(subject.Close()).To(Succeed())
+	})
+
+	It("routes bucket content", func() {
+		Expect(tx.GetUserPrincipals("alice")).To(ContainElement("team"))
+		Expect(tx.GetAllPermissions([]riposo.Path{
+			"/buckets/big/collections/c1",
+			"/buckets/small/collections/c2",
+			"/buckets/big",
+		})).To(Equal([]schema.PermissionSet{
+			{"read": {"team"}},
+			{"read": {"bob"}},
+			{"write": {"alice"}},
+		}))
+		Expect(tx.Commit()).To(Succeed())
+
+		Expect(getPermissions(main, "/buckets/big")).To(HaveLen(1))
+		Expect(getPermissions(main, "/buckets/small/collections/c2")).To(HaveLen(1))
+		Expect(getPermissions(main, "/buckets/big/collections/c1")).To(BeEmpty())
+		Expect(getPermissions(big, "/buckets/big/collections/c1")).To(HaveLen(1))
+	})
+
+	It("gets ACE principals across backends", func() {
+		Expect(tx.GetAllACEPrincipals([]permission.ACE{
+			{Perm: "write", Path: "/buckets/big"},
+			{Perm: "read", Path: "/buckets/big/collections/c1"},
+			{Perm: "read", Path: "/buckets/small/collections/c2"},
+		})).To(ConsistOf("alice", "bob", "team"))
+	})
+
+	It("gets accessible paths across backends", func() {
+		Expect(tx.GetAccessiblePaths(nil, []string{"alice", "team", "bob"}, []permission.ACE{
+			{Perm: "read", Path: "/buckets/big/collections/*"},
+			{Perm: "read", Path: "/buckets/small/collections/*"},
+			{Perm: "write", Path: "/buckets/*"},
+		})).To(Equal([]riposo.Path{
+			"/buckets/big",
+			"/buckets/big/collections/c1",
+			"/buckets/small",
+			"/buckets/small/collections/c2",
+		}))
+	})
+
+	It("deletes bucket content across backends", func() {
+		Expect(tx.DeletePermissions([]riposo.Path{"/buckets/big"})).To(Succeed())
+		Expect(tx.Commit()).To(Succeed())
+
+		Expect(getPermissions(main, "/buckets/big")).To(BeEmpty())
+		Expect(getPermissions(main, "/buckets/small")).To(HaveLen(1))
+		Expect(getPermissions(big, "/buckets/big/collections/c1")).To(BeEmpty())
+	})
+})
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "internal/conn/router/permission")
+}
@@ -0,0 +1,6 @@
+package router
+
+import (
+	_ "github.com/riposo/riposo/internal/conn/router/permission" // permission backend
+	_ "github.com/riposo/riposo/internal/conn/router/storage"    // storage backend
+)
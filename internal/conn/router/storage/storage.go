@@ -0,0 +1,446 @@
+package storage
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/riposo/riposo/internal/conn/router/common"
+	"github.com/riposo/riposo/pkg/conn"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+	"go.uber.org/multierr"
+)
+
+func init() {
+	storage.Register(common.Scheme, func(ctx context.Context, _ *url.URL, hlp riposo.Helpers) (storage.Backend, error) {
+		var cfg struct {
+			Storage struct {
+				Router common.Config
+			}
+		}
+		if err := hlp.ParseConfig(&cfg); err != nil {
+			return nil, err
+		}
+
+		tbl, err := common.NewTable(&cfg.Storage.Router)
+		if err != nil {
+			return nil, err
+		}
+
+		backends := make([]storage.Backend, 0, len(tbl.URLs()))
+		for _, u := range tbl.URLs() {
+			b, err := storage.Connect(ctx, u, hlp)
+			if err != nil {
+				for _, b := range backends {
+					_ = b.Close()
+				}
+				return nil, err
+			}
+			backends = append(backends, b)
+		}
+		return New(tbl, backends), nil
+	})
+}
+
+type backend struct {
+	tbl      *common.Table
+	backends []storage.Backend
+}
+
+// New inits a new routing backend. Backends must be aligned with the URLs of
+// the table.
+func New(tbl *common.Table, backends []storage.Backend) storage.Backend {
+	return &backend{tbl: tbl, backends: backends}
+}
+
+// Ping implements storage.Backend interface.
+func (b *backend) Ping(ctx context.Context) error {
+	for _, sub := range b.backends {
+		if err := sub.Ping(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Begin implements storage.Backend interface. Transactions are started on
+// backends in order, once they are first accessed, and committed one after
+// another. Please note that commits are not atomic across backends.
+func (b *backend) Begin(ctx context.Context, opt *riposo.TxOptions) (storage.Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &transaction{
+		ctx:      ctx,
+		opt:      opt,
+		tbl:      b.tbl,
+		backends: b.backends,
+		txs:      make([]storage.Transaction, len(b.backends)),
+	}, nil
+}
+
+// Subscribe implements storage.Notifier interface.
+func (b *backend) Subscribe(fn func([]storage.Change)) (func(), error) {
+	var cancels []func()
+	cancel := func() {
+		for _, c := range cancels {
+			c()
+		}
+	}
+
+	for _, sub := range b.backends {
+		ntf, ok := sub.(storage.Notifier)
+		if !ok {
+			cancel()
+			return nil, conn.ErrNotSupported
+		}
+
+		c, err := ntf.Subscribe(fn)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		cancels = append(cancels, c)
+	}
+	return cancel, nil
+}
+
+// Close implements storage.Backend interface.
+func (b *backend) Close() (err error) {
+	for _, sub := range b.backends {
+		err = multierr.Append(err, sub.Close())
+	}
+	return
+}
+
+// --------------------------------------------------------------------
+
+type transaction struct {
+	ctx      context.Context
+	opt      *riposo.TxOptions
+	tbl      *common.Table
+	backends []storage.Backend
+	txs      []storage.Transaction
+	spts     common.Savepoints
+	done     bool
+}
+
+// Commit implements storage.Transaction interface.
+func (t *transaction) Commit() (err error) {
+	if t.done {
+		return storage.ErrTxDone
+	}
+	t.done = true
+
+	for _, tx := range t.txs {
+		if tx == nil {
+			continue
+		}
+
+		if err == nil {
+			err = tx.Commit()
+		} else {
+			_ = tx.Rollback()
+		}
+	}
+	return
+}
+
+// Rollback implements storage.Transaction interface.
+func (t *transaction) Rollback() (err error) {
+	if t.done {
+		return storage.ErrTxDone
+	}
+	t.done = true
+
+	for _, tx := range t.txs {
+		if tx != nil {
+			err = multierr.Append(err, tx.Rollback())
+		}
+	}
+	return
+}
+
+// Savepoint implements storage.Transaction interface.
+func (t *transaction) Savepoint(name string) error {
+	if err := t.started(func(tx storage.Transaction) error { return tx.Savepoint(name) }); err != nil {
+		return err
+	}
+	t.spts.Add(name)
+	return nil
+}
+
+// RollbackTo implements storage.Transaction interface.
+func (t *transaction) RollbackTo(name string) error {
+	if !t.spts.RollbackTo(name) {
+		return storage.ErrNoSavepoint
+	}
+	return t.started(func(tx storage.Transaction) error { return tx.RollbackTo(name) })
+}
+
+// Release implements storage.Transaction interface.
+func (t *transaction) Release(name string) error {
+	if !t.spts.Release(name) {
+		return storage.ErrNoSavepoint
+	}
+	return t.started(func(tx storage.Transaction) error { return tx.Release(name) })
+}
+
+// Flush implements storage.Transaction interface.
+func (t *transaction) Flush() error {
+	return t.each(func(tx storage.Transaction) error { return tx.Flush() })
+}
+
+// Purge implements storage.Transaction interface.
+func (t *transaction) Purge(olderThan riposo.Epoch) (cnt int64, _ error) {
+	err := t.each(func(tx storage.Transaction) error {
+		n, err := tx.Purge(olderThan)
+		cnt += n
+		return err
+	})
+	return cnt, err
+}
+
+// ModTime implements storage.Transaction interface.
+func (t *transaction) ModTime(path riposo.Path) (riposo.Epoch, error) {
+	tx, err := t.route(path)
+	if err != nil {
+		return 0, err
+	}
+	return tx.ModTime(path)
+}
+
+// ListAll implements storage.Transaction interface.
+func (t *transaction) ListAll(path riposo.Path, opt storage.ListOptions) ([]*schema.Object, error) {
+	tx, err := t.route(path)
+	if err != nil {
+		return nil, err
+	}
+	return tx.ListAll(path, opt)
+}
+
+// CountAll implements storage.Transaction interface.
+func (t *transaction) CountAll(path riposo.Path, opt storage.CountOptions) (int64, error) {
+	tx, err := t.route(path)
+	if err != nil {
+		return 0, err
+	}
+	return tx.CountAll(path, opt)
+}
+
+// Aggregate implements storage.Aggregator interface.
+func (t *transaction) Aggregate(path riposo.Path, opt storage.AggregateOptions) ([]storage.AggregateGroup, error) {
+	tx, err := t.route(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ag, ok := tx.(storage.Aggregator)
+	if !ok {
+		return nil, conn.ErrNotSupported
+	}
+	return ag.Aggregate(path, opt)
+}
+
+// DeleteAll implements storage.Transaction interface.
+func (t *transaction) DeleteAll(paths []riposo.Path) (modTime riposo.Epoch, deleted []riposo.Path, _ error) {
+	groups := common.Group(paths, t.tbl.Spread)
+	for i := range t.txs {
+		group, ok := groups[i]
+		if !ok {
+			continue
+		}
+
+		tx, err := t.tx(i)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		epoch, del, err := tx.DeleteAll(group)
+		if err != nil {
+			return 0, nil, err
+		}
+		if epoch > modTime {
+			modTime = epoch
+		}
+		deleted = append(deleted, del...)
+	}
+	return
+}
+
+// Exists implements storage.Transaction interface.
+func (t *transaction) Exists(path riposo.Path) (bool, error) {
+	tx, err := t.route(path)
+	if err != nil {
+		return false, err
+	}
+	return tx.Exists(path)
+}
+
+// Get implements storage.Transaction interface.
+func (t *transaction) Get(path riposo.Path, lock bool) (*schema.Object, error) {
+	tx, err := t.route(path)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Get(path, lock)
+}
+
+// GetBatch implements storage.Transaction interface.
+func (t *transaction) GetBatch(paths []riposo.Path, lock bool) ([]*schema.Object, error) {
+	positions := make(map[int][]int)
+	for pos, path := range paths {
+		i := t.tbl.Lookup(path)
+		positions[i] = append(positions[i], pos)
+	}
+
+	objs := make([]*schema.Object, len(paths))
+	for i, group := range positions {
+		batch := make([]riposo.Path, 0, len(group))
+		for _, pos := range group {
+			batch = append(batch, paths[pos])
+		}
+
+		tx, err := t.tx(i)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := tx.GetBatch(batch, lock)
+		if err != nil {
+			return nil, err
+		}
+		for n, pos := range group {
+			objs[pos] = res[n]
+		}
+	}
+	return objs, nil
+}
+
+// Create implements storage.Transaction interface.
+func (t *transaction) Create(path riposo.Path, obj *schema.Object) error {
+	tx, err := t.route(path)
+	if err != nil {
+		return err
+	}
+	return tx.Create(path, obj)
+}
+
+// Restore implements storage.Restorer interface.
+func (t *transaction) Restore(path riposo.Path, obj *schema.Object) error {
+	tx, err := t.route(path)
+	if err != nil {
+		return err
+	}
+
+	rs, ok := tx.(storage.Restorer)
+	if !ok {
+		return conn.ErrNotSupported
+	}
+	return rs.Restore(path, obj)
+}
+
+// Update implements storage.Transaction interface.
+func (t *transaction) Update(path riposo.Path, obj *schema.Object) error {
+	tx, err := t.route(path)
+	if err != nil {
+		return err
+	}
+	return tx.Update(path, obj)
+}
+
+// Delete implements storage.Transaction interface. Deletes of bucket objects
+// are propagated to the backends which store the bucket content.
+func (t *transaction) Delete(path riposo.Path) (*schema.Object, error) {
+	tx, err := t.route(path)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := tx.Delete(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range t.tbl.Spread(path) {
+		if i == t.tbl.Lookup(path) {
+			continue
+		}
+
+		tx, err := t.tx(i)
+		if err != nil {
+			return nil, err
+		}
+		if _, _, err := tx.DeleteAll([]riposo.Path{path}); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+// tx returns the transaction of the i-th backend. Transactions are started on
+// first use and savepoints are replayed on them. Backends are always started
+// in index order, including all backends before i, so that locks are acquired
+// in the same order by concurrent transactions.
+func (t *transaction) tx(i int) (storage.Transaction, error) {
+	if t.done {
+		return nil, storage.ErrTxDone
+	} else if tx := t.txs[i]; tx != nil {
+		return tx, nil
+	}
+
+	for j := 0; j <= i; j++ {
+		if t.txs[j] != nil {
+			continue
+		}
+
+		tx, err := t.backends[j].Begin(t.ctx, t.opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range t.spts {
+			if err := tx.Savepoint(name); err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+		}
+		t.txs[j] = tx
+	}
+	return t.txs[i], nil
+}
+
+func (t *transaction) route(path riposo.Path) (storage.Transaction, error) {
+	return t.tx(t.tbl.Lookup(path))
+}
+
+// each applies fn to the transactions of all backends.
+func (t *transaction) each(fn func(storage.Transaction) error) error {
+	for i := range t.txs {
+		tx, err := t.tx(i)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// started applies fn to all started transactions.
+func (t *transaction) started(fn func(storage.Transaction) error) error {
+	if t.done {
+		return storage.ErrTxDone
+	}
+
+	for _, tx := range t.txs {
+		if tx == nil {
+			continue
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
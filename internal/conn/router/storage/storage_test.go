@@ -0,0 +1,211 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/benbjohnson/clock"
+	"github.com/riposo/riposo/internal/conn/router/common"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	mems "github.com/riposo/riposo/internal/conn/memory/storage"
+	. "github.com/riposo/riposo/internal/conn/router/storage"
+)
+
+var _ = Describe("Backend", func() {
+	var subject storage.Backend
+	var main, big storage.Backend
+	var ctx = context.Background()
+
+	BeforeEach(func() {
+		tbl, err := common.NewTable(&common.Config{
+			Default: "memory:",
+			Routes:  []common.Route{{Buckets: []string{"big"}, URL: "memory://big"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		hlp := mock.Helpers()
+		main = mems.New(clock.New(), hlp)
+		big = mems.New(clock.New(), hlp)
+		subject = New(tbl, []storage.Backend{main, big})
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+	})
+
+	exists := func(b storage.Backend, path riposo.Path) bool {
+		tx, err := b.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		ok, err := tx.Exists(path)
+		Expect(err).NotTo(HaveOccurred())
+		return ok
+	}
+
+	It("routes bucket content", func() {
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "big"})).To(Succeed())
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "small"})).To(Succeed())
+		Expect(tx.Create("/buckets/big/collections/*", &schema.Object{ID: "c1"})).To(Succeed())
+		Expect(tx.Create("/buckets/small/collections/*", &schema.Object{ID: "c2"})).To(Succeed())
+		Expect(tx.GetBatch([]riposo.Path{
+			"/buckets/small/collections/c2",
+			"/buckets/big/collections/c1",
+			"/buckets/big",
+		}, false)).To(HaveLen(3))
+		Expect(tx.Commit()).To(Succeed())
+
+		Expect(exists(main, "/buckets/big")).To(BeTrue())
+		Expect(exists(main, "/buckets/small")).To(BeTrue())
+		Expect(exists(main, "/buckets/small/collections/c2")).To(BeTrue())
+		Expect(exists(main, "/buckets/big/collections/c1")).To(BeFalse())
+		Expect(exists(big, "/buckets/big/collections/c1")).To(BeTrue())
+		Expect(exists(big, "/buckets/big")).To(BeFalse())
+	})
+
+	It("lists and counts", func() {
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "big"})).To(Succeed())
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "small"})).To(Succeed())
+		Expect(tx.Create("/buckets/big/collections/*", &schema.Object{ID: "c1"})).To(Succeed())
+		Expect(tx.Create("/buckets/big/collections/*", &schema.Object{ID: "c2"})).To(Succeed())
+
+		Expect(tx.ListAll("/buckets/*", storage.ListOptions{})).To(HaveLen(2))
+		Expect(tx.CountAll("/buckets/big/collections/*", storage.CountOptions{})).To(Equal(int64(2)))
+		Expect(tx.CountAll("/buckets/small/collections/*", storage.CountOptions{})).To(Equal(int64(0)))
+		Expect(tx.ModTime("/buckets/big/collections/*")).NotTo(BeZero())
+	})
+
+	It("supports savepoints", func() {
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "big"})).To(Succeed())
+		Expect(tx.Savepoint("sp")).To(Succeed())
+		Expect(tx.Create("/buckets/big/collections/*", &schema.Object{ID: "c1"})).To(Succeed())
+		Expect(tx.RollbackTo("sp")).To(Succeed())
+		Expect(tx.Release("sp")).To(Succeed())
+		Expect(tx.Commit()).To(Succeed())
+
+		Expect(exists(main, "/buckets/big")).To(BeTrue())
+		Expect(exists(big, "/buckets/big/collections/c1")).To(BeFalse())
+	})
+
+	It("starts backend transactions on first use", func() {
+		counting := &countingBackend{Backend: big}
+		tbl, err := common.NewTable(&common.Config{
+			Default: "memory:",
+			Routes:  []common.Route{{Buckets: []string{"big"}, URL: "memory://big"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		subject = New(tbl, []storage.Backend{main, counting})
+
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "big"})).To(Succeed())
+		Expect(tx.Savepoint("sp")).To(Succeed())
+		Expect(counting.begins).To(Equal(0))
+
+		Expect(tx.Create("/buckets/big/collections/*", &schema.Object{ID: "c1"})).To(Succeed())
+		Expect(counting.begins).To(Equal(1))
+		Expect(tx.RollbackTo("sp")).To(Succeed())
+		Expect(tx.Release("sp")).To(Succeed())
+		Expect(tx.RollbackTo("sp")).To(MatchError(storage.ErrNoSavepoint))
+		Expect(tx.Commit()).To(Succeed())
+		Expect(counting.begins).To(Equal(1))
+
+		Expect(exists(main, "/buckets/big")).To(BeTrue())
+		Expect(exists(big, "/buckets/big/collections/c1")).To(BeFalse())
+	})
+
+	It("starts backend transactions in order", func() {
+		var started []string
+		tbl, err := common.NewTable(&common.Config{
+			Default: "memory:",
+			Routes:  []common.Route{{Buckets: []string{"big"}, URL: "memory://big"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		subject = New(tbl, []storage.Backend{
+			&countingBackend{Backend: main, name: "main", started: &started},
+			&countingBackend{Backend: big, name: "big", started: &started},
+		})
+
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.ModTime("/buckets/big/collections/*")).To(BeZero())
+		Expect(started).To(Equal([]string{"main", "big"}))
+		Expect(tx.Exists("/buckets/big")).To(BeFalse())
+		Expect(started).To(Equal([]string{"main", "big"}))
+	})
+
+	It("flushes and purges all backends", func() {
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "big"})).To(Succeed())
+		Expect(tx.Create("/buckets/big/collections/*", &schema.Object{ID: "c1"})).To(Succeed())
+		Expect(tx.DeleteAll([]riposo.Path{"/buckets/big"})).Error().NotTo(HaveOccurred())
+		Expect(tx.Purge(0)).To(Equal(int64(2)))
+
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "big"})).To(Succeed())
+		Expect(tx.Create("/buckets/big/collections/*", &schema.Object{ID: "c1"})).To(Succeed())
+		Expect(tx.Flush()).To(Succeed())
+		Expect(tx.Commit()).To(Succeed())
+
+		Expect(exists(main, "/buckets/big")).To(BeFalse())
+		Expect(exists(big, "/buckets/big/collections/c1")).To(BeFalse())
+	})
+
+	It("deletes bucket content across backends", func() {
+		tx, err := subject.Begin(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Create("/buckets/*", &schema.Object{ID: "big"})).To(Succeed())
+		Expect(tx.Create("/buckets/big/collections/*", &schema.Object{ID: "c1"})).To(Succeed())
+		Expect(tx.Delete("/buckets/big")).To(HaveField("ID", "big"))
+		Expect(tx.Commit()).To(Succeed())
+
+		Expect(exists(main, "/buckets/big")).To(BeFalse())
+		Expect(exists(big, "/buckets/big/collections/c1")).To(BeFalse())
+	})
+})
+
+type countingBackend struct {
+	storage.Backend
+	begins  int
+	name    string
+	started *[]string
+}
+
+func (b *countingBackend) Begin(ctx context.Context, opt *riposo.TxOptions) (storage.Transaction, error) {
+	b.begins++
+	if b.started != nil {
+		*b.started = append(*b.started, b.name)
+	}
+	return b.Backend.Begin(ctx, opt)
+}
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "internal/conn/router/storage")
+}
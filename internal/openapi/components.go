@@ -0,0 +1,298 @@
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+)
+
+func defaultSchemas() map[string]Object {
+	return map[string]Object{
+		"Object": {
+			"type": "object",
+			"properties": Object{
+				"id":            Object{"type": "string"},
+				"last_modified": Object{"type": "integer", "format": "int64"},
+				"deleted":       Object{"type": "boolean"},
+			},
+			"additionalProperties": true,
+		},
+		"Permissions": {
+			"type": "object",
+			"additionalProperties": Object{
+				"type":  "array",
+				"items": Object{"type": "string"},
+			},
+		},
+		"Resource": {
+			"type": "object",
+			"properties": Object{
+				"data":        Object{"$ref": "#/components/schemas/Object"},
+				"permissions": Object{"$ref": "#/components/schemas/Permissions"},
+			},
+		},
+		"ObjectList": {
+			"type":     "object",
+			"required": []string{"data"},
+			"properties": Object{
+				"data": Object{
+					"type":  "array",
+					"items": Object{"$ref": "#/components/schemas/Object"},
+				},
+			},
+		},
+		"Error": {
+			"type":     "object",
+			"required": []string{"code", "errno", "error"},
+			"properties": Object{
+				"code":    Object{"type": "integer", "description": "HTTP status code"},
+				"errno":   Object{"type": "integer", "description": "Internal error number"},
+				"error":   Object{"type": "string", "description": "HTTP status text"},
+				"message": Object{"type": "string"},
+				"info":    Object{"type": "string"},
+				"details": Object{},
+			},
+		},
+		"BatchRequest": {
+			"type":     "object",
+			"required": []string{"requests"},
+			"properties": Object{
+				"defaults": Object{"$ref": "#/components/schemas/BatchRequestPart"},
+				"requests": Object{
+					"type":  "array",
+					"items": Object{"$ref": "#/components/schemas/BatchRequestPart"},
+				},
+			},
+		},
+		"BatchRequestPart": {
+			"type": "object",
+			"properties": Object{
+				"method":  Object{"type": "string"},
+				"path":    Object{"type": "string"},
+				"body":    Object{},
+				"headers": Object{"type": "object", "additionalProperties": Object{"type": "string"}},
+			},
+		},
+		"BatchResponse": {
+			"type": "object",
+			"properties": Object{
+				"responses": Object{
+					"type": "array",
+					"items": Object{
+						"type": "object",
+						"properties": Object{
+							"status":  Object{"type": "integer"},
+							"path":    Object{"type": "string"},
+							"body":    Object{},
+							"headers": Object{"type": "object", "additionalProperties": Object{"type": "string"}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func defaultResponses() map[string]Object {
+	return map[string]Object{
+		"Error":        jsonResponse("Error", "#/components/schemas/Error"),
+		"Object":       jsonResponse("Single object", "#/components/schemas/Resource"),
+		"ObjectList":   jsonResponse("List of objects", "#/components/schemas/ObjectList"),
+		"NotModified":  {"description": "Not modified"},
+		"Count":        {"description": "Object count", "headers": Object{"Total-Objects": Object{"schema": Object{"type": "integer"}}}},
+		"BatchResults": jsonResponse("Batch results", "#/components/schemas/BatchResponse"),
+	}
+}
+
+func defaultParameters() map[string]Object {
+	return map[string]Object{
+		"_sort": {
+			"name":        "_sort",
+			"in":          "query",
+			"description": "Comma-separated list of fields to sort by, prefix with '-' for descending order.",
+			"schema":      Object{"type": "string"},
+		},
+		"_limit": {
+			"name":        "_limit",
+			"in":          "query",
+			"description": "Maximum number of objects per page.",
+			"schema":      Object{"type": "integer"},
+		},
+		"_token": {
+			"name":        "_token",
+			"in":          "query",
+			"description": "Pagination token, as provided by the Next-Page header.",
+			"schema":      Object{"type": "string"},
+		},
+		"_since": {
+			"name":        "_since",
+			"in":          "query",
+			"description": "Only return objects modified after this epoch.",
+			"schema":      Object{"type": "integer", "format": "int64"},
+		},
+		"_before": {
+			"name":        "_before",
+			"in":          "query",
+			"description": "Only return objects modified before this epoch.",
+			"schema":      Object{"type": "integer", "format": "int64"},
+		},
+		"q": {
+			"name":        "q",
+			"in":          "query",
+			"description": "Full-text search across all fields, sort by '-_score' for relevance.",
+			"schema":      Object{"type": "string"},
+		},
+		"_filter": {
+			"name":        "_filter",
+			"in":          "query",
+			"description": "Filter expression, e.g. or(eq(status,\"open\"),and(min_priority(3),has_assignee)).",
+			"schema":      Object{"type": "string"},
+		},
+		"_aggregate": {
+			"name":        "_aggregate",
+			"in":          "query",
+			"description": "Aggregate objects instead of listing them: count, sum(field), avg(field), min(field) or max(field).",
+			"schema":      Object{"type": "string"},
+		},
+		"_group_by": {
+			"name":        "_group_by",
+			"in":          "query",
+			"description": "Comma-separated list of fields to group aggregations by.",
+			"schema":      Object{"type": "string"},
+		},
+		"If-Match": {
+			"name":   "If-Match",
+			"in":     "header",
+			"schema": Object{"type": "string"},
+		},
+		"If-None-Match": {
+			"name":   "If-None-Match",
+			"in":     "header",
+			"schema": Object{"type": "string"},
+		},
+	}
+}
+
+// --------------------------------------------------------------------
+
+func listOperation(summary string, tags []string) Object {
+	return Object{
+		"summary":    summary,
+		"tags":       tags,
+		"parameters": append(refs("_sort", "_limit", "_token", "_since", "_before", "q", "_filter", "_aggregate", "_group_by", "If-None-Match"), filterParameters()...),
+		"responses": responses(map[int]string{
+			http.StatusOK:          "ObjectList",
+			http.StatusNotModified: "NotModified",
+		}),
+	}
+}
+
+func countOperation(summary string, tags []string) Object {
+	return Object{
+		"summary":    summary,
+		"tags":       tags,
+		"parameters": append(refs("_since", "_before", "q", "_filter", "If-None-Match"), filterParameters()...),
+		"responses": responses(map[int]string{
+			http.StatusOK:          "Count",
+			http.StatusNotModified: "NotModified",
+		}),
+	}
+}
+
+func deleteAllOperation(summary string, tags []string) Object {
+	return Object{
+		"summary":    summary,
+		"tags":       tags,
+		"parameters": append(refs("_sort", "_limit", "_token", "_since", "_before", "q", "_filter", "If-Match"), filterParameters()...),
+		"responses":  responses(map[int]string{http.StatusOK: "ObjectList"}),
+	}
+}
+
+func createOperation(summary string, tags []string) Object {
+	return Object{
+		"summary":     summary,
+		"tags":        tags,
+		"requestBody": jsonBody("#/components/schemas/Resource"),
+		"responses": responses(map[int]string{
+			http.StatusOK:      "Object",
+			http.StatusCreated: "Object",
+		}),
+	}
+}
+
+func getOperation(summary string, tags []string) Object {
+	return Object{
+		"summary":    summary,
+		"tags":       tags,
+		"parameters": refs("If-None-Match"),
+		"responses": responses(map[int]string{
+			http.StatusOK:          "Object",
+			http.StatusNotModified: "NotModified",
+		}),
+	}
+}
+
+func writeOperation(summary string, tags []string, extraCodes ...int) Object {
+	codes := map[int]string{http.StatusOK: "Object"}
+	for _, code := range extraCodes {
+		codes[code] = "Object"
+	}
+
+	return Object{
+		"summary":     summary,
+		"tags":        tags,
+		"parameters":  refs("If-Match", "If-None-Match"),
+		"requestBody": jsonBody("#/components/schemas/Resource"),
+		"responses":   responses(codes),
+	}
+}
+
+func deleteOperation(summary string, tags []string) Object {
+	return Object{
+		"summary":    summary,
+		"tags":       tags,
+		"parameters": refs("If-Match"),
+		"responses":  responses(map[int]string{http.StatusOK: "Object"}),
+	}
+}
+
+func batchOperation() Object {
+	return Object{
+		"summary":     "Send multiple operations in one request",
+		"tags":        []string{"batch"},
+		"requestBody": jsonBody("#/components/schemas/BatchRequest"),
+		"responses":   responses(map[int]string{http.StatusOK: "BatchResults"}),
+	}
+}
+
+func refs(names ...string) []interface{} {
+	res := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		res = append(res, Object{"$ref": "#/components/parameters/" + name})
+	}
+	return res
+}
+
+func responses(codes map[int]string) Object {
+	res := Object{"default": Object{"$ref": "#/components/responses/Error"}}
+	for code, name := range codes {
+		res[strconv.Itoa(code)] = Object{"$ref": "#/components/responses/" + name}
+	}
+	return res
+}
+
+func jsonBody(ref string) Object {
+	return Object{
+		"content": Object{
+			"application/json": Object{"schema": Object{"$ref": ref}},
+		},
+	}
+}
+
+func jsonResponse(description, ref string) Object {
+	return Object{
+		"description": description,
+		"content": Object{
+			"application/json": Object{"schema": Object{"$ref": ref}},
+		},
+	}
+}
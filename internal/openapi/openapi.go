@@ -0,0 +1,268 @@
+// Package openapi generates OpenAPI 3 descriptions from registered routes.
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/params"
+)
+
+// Version is the implemented OpenAPI specification version.
+const Version = "3.0.3"
+
+// Info contains general API information.
+type Info struct {
+	Title       string
+	Version     string
+	Docs        string
+	Namespace   string
+	AuthMethods []string
+}
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI      string                `json:"openapi"`
+	Info         docInfo               `json:"info"`
+	ExternalDocs *docExternal          `json:"externalDocs,omitempty"`
+	Servers      []docServer           `json:"servers"`
+	Paths        map[string]PathItem   `json:"paths"`
+	Components   docComponents         `json:"components"`
+	Security     []map[string][]string `json:"security,omitempty"`
+}
+
+// PathItem contains operations by lowercase HTTP method.
+type PathItem map[string]Object
+
+// Object is a generic OpenAPI object.
+type Object = map[string]interface{}
+
+type docInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type docExternal struct {
+	URL string `json:"url"`
+}
+
+type docServer struct {
+	URL string `json:"url"`
+}
+
+type docComponents struct {
+	Schemas         map[string]Object `json:"schemas"`
+	Responses       map[string]Object `json:"responses"`
+	Parameters      map[string]Object `json:"parameters"`
+	SecuritySchemes map[string]Object `json:"securitySchemes,omitempty"`
+}
+
+// Generate generates a document from routes.
+func Generate(rts *api.Routes, info Info) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    docInfo{Title: info.Title, Version: info.Version},
+		Servers: []docServer{{URL: info.Namespace}},
+		Paths:   make(map[string]PathItem),
+		Components: docComponents{
+			Schemas:    defaultSchemas(),
+			Responses:  defaultResponses(),
+			Parameters: defaultParameters(),
+		},
+	}
+	if info.Docs != "" {
+		doc.ExternalDocs = &docExternal{URL: info.Docs}
+	}
+
+	// security schemes
+	for _, name := range info.AuthMethods {
+		if doc.Components.SecuritySchemes == nil {
+			doc.Components.SecuritySchemes = make(map[string]Object)
+		}
+		doc.Components.SecuritySchemes[name] = securityScheme(name)
+		doc.Security = append(doc.Security, map[string][]string{name: {}})
+	}
+
+	// batch endpoint
+	doc.add(http.MethodPost, "/batch", batchOperation())
+
+	// resources
+	for _, prefix := range rts.Resources() {
+		doc.addResource(rts, prefix)
+	}
+
+	// custom handlers
+	for _, h := range rts.Handlers() {
+		doc.addHandler(rts, h)
+	}
+
+	return doc
+}
+
+func (d *Document) add(method, pattern string, op Object) {
+	path, params := normPattern(pattern)
+	if len(params) != 0 {
+		op["parameters"] = append(params, toSlice(op["parameters"])...)
+	}
+
+	item, ok := d.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+func (d *Document) addResource(rts *api.Routes, prefix string) {
+	name := resourceName(prefix)
+	plural := strings.TrimSuffix(name, "s") + "s"
+	single := strings.TrimSuffix(name, "s")
+	tags := []string{plural}
+
+	objectPath := strings.TrimSuffix(prefix, "/") + "/{id}"
+	for _, ent := range []struct {
+		Method, Pattern string
+		Op              Object
+	}{
+		{http.MethodGet, prefix, listOperation("List "+plural, tags)},
+		{http.MethodHead, prefix, countOperation("Count "+plural, tags)},
+		{http.MethodDelete, prefix, deleteAllOperation("Delete "+plural, tags)},
+		{http.MethodPost, prefix, createOperation("Create a "+single, tags)},
+		{http.MethodGet, objectPath, getOperation("Retrieve a "+single, tags)},
+		{http.MethodPut, objectPath, writeOperation("Create or replace a "+single, tags, http.StatusCreated)},
+		{http.MethodPatch, objectPath, writeOperation("Update a "+single, tags)},
+		{http.MethodDelete, objectPath, deleteOperation("Delete a "+single, tags)},
+	} {
+		if meta := rts.Operation(ent.Method, ent.Pattern); meta != nil {
+			applyMeta(ent.Op, meta)
+		}
+		d.add(ent.Method, ent.Pattern, ent.Op)
+	}
+}
+
+func (d *Document) addHandler(rts *api.Routes, h api.RouteInfo) {
+	methods := []string{h.Method}
+	if h.Method == "" {
+		// routes registered via Handle accept any method, document all described
+		// methods or fall back on GET
+		methods = methods[:0]
+		for _, m := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			if rts.Operation(m, h.Pattern) != nil {
+				methods = append(methods, m)
+			}
+		}
+		if len(methods) == 0 {
+			methods = append(methods, http.MethodGet)
+		}
+	}
+
+	for _, m := range methods {
+		op := Object{
+			"responses": Object{
+				"default": Object{"$ref": "#/components/responses/Error"},
+			},
+		}
+		if meta := rts.Operation(m, h.Pattern); meta != nil {
+			applyMeta(op, meta)
+		}
+		d.add(m, h.Pattern, op)
+	}
+}
+
+// --------------------------------------------------------------------
+
+func applyMeta(op Object, meta *api.Operation) {
+	if meta.Summary != "" {
+		op["summary"] = meta.Summary
+	}
+	if meta.Description != "" {
+		op["description"] = meta.Description
+	}
+	if len(meta.Tags) != 0 {
+		op["tags"] = meta.Tags
+	}
+	if meta.Deprecated {
+		op["deprecated"] = true
+	}
+	for key, val := range meta.Extra {
+		op[key] = val
+	}
+}
+
+func securityScheme(name string) Object {
+	switch name {
+	case "basic":
+		return Object{"type": "http", "scheme": "basic"}
+	case "bearer", "openid", "oauth":
+		return Object{"type": "http", "scheme": "bearer", "description": "Authentication via " + name}
+	default:
+		return Object{"type": "apiKey", "in": "header", "name": "Authorization", "description": "Authentication via " + name}
+	}
+}
+
+// normPattern converts a chi route pattern into an OpenAPI path and extracts
+// path parameters.
+func normPattern(pattern string) (string, []interface{}) {
+	var params []interface{}
+
+	parts := strings.Split(pattern, "/")
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.TrimPrefix(part, "{"), "}")
+		if pos := strings.IndexByte(name, ':'); pos > -1 {
+			name = name[:pos]
+		}
+		parts[i] = "{" + name + "}"
+		params = append(params, Object{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   Object{"type": "string"},
+		})
+	}
+
+	path := strings.Join(parts, "/")
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path, params
+}
+
+func resourceName(prefix string) string {
+	parts := strings.Split(strings.Trim(prefix, "/"), "/")
+	return parts[len(parts)-1]
+}
+
+func toSlice(v interface{}) []interface{} {
+	if vv, ok := v.([]interface{}); ok {
+		return vv
+	}
+	return nil
+}
+
+func filterParameters() []interface{} {
+	var prefixes []string
+	params.EachPrefix(func(_ params.Operator, prefix string) {
+		prefixes = append(prefixes, prefix)
+	})
+	sort.Strings(prefixes)
+
+	return []interface{}{
+		Object{
+			"name":        "filters",
+			"in":          "query",
+			"description": "Filter by field values. Field names may be prefixed by an operator: " + strings.Join(prefixes, ", ") + ".",
+			"style":       "form",
+			"explode":     true,
+			"schema": Object{
+				"type":                 "object",
+				"additionalProperties": Object{"type": "string"},
+			},
+		},
+	}
+}
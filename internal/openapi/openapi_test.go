@@ -0,0 +1,100 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/riposo/riposo/pkg/api"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/internal/openapi"
+)
+
+var _ = Describe("Generate", func() {
+	var subject *Document
+
+	BeforeEach(func() {
+		rts := api.NewRoutes(&api.Config{})
+		rts.Resource("/buckets", nil)
+		rts.Resource("/buckets/{bucket_id}/collections", nil)
+		rts.Method(http.MethodGet, "/accounts/{account_id:[a-z]+}/status", http.NotFoundHandler())
+		rts.Handle("/custom", http.NotFoundHandler())
+		rts.Describe(http.MethodGet, "/accounts/{account_id:[a-z]+}/status", &api.Operation{
+			Summary: "Account status",
+			Tags:    []string{"accounts"},
+			Extra:   map[string]interface{}{"x-internal": true},
+		})
+		rts.Describe(http.MethodPost, "/custom", &api.Operation{Summary: "Custom action"})
+		rts.Describe(http.MethodDelete, "/buckets", &api.Operation{Deprecated: true})
+
+		subject = Generate(rts, Info{
+			Title:       "Example",
+			Version:     "1.2.3",
+			Namespace:   "/v1",
+			AuthMethods: []string{"basic", "openid"},
+		})
+	})
+
+	It("includes info", func() {
+		Expect(subject.OpenAPI).To(Equal(Version))
+		Expect(json.Marshal(subject.Info)).To(MatchJSON(`{"title":"Example","version":"1.2.3"}`))
+		Expect(json.Marshal(subject.Servers)).To(MatchJSON(`[{"url":"/v1"}]`))
+		Expect(json.Marshal(subject.Security)).To(MatchJSON(`[{"basic":[]},{"openid":[]}]`))
+	})
+
+	It("documents paths", func() {
+		Expect(subject.Paths).To(HaveLen(7))
+		Expect(subject.Paths).To(HaveKey("/batch"))
+		Expect(subject.Paths).To(HaveKey("/buckets"))
+		Expect(subject.Paths).To(HaveKey("/buckets/{id}"))
+		Expect(subject.Paths).To(HaveKey("/buckets/{bucket_id}/collections"))
+		Expect(subject.Paths).To(HaveKey("/buckets/{bucket_id}/collections/{id}"))
+		Expect(subject.Paths).To(HaveKey("/accounts/{account_id}/status"))
+
+		Expect(subject.Paths["/buckets"]).To(HaveLen(4))
+		Expect(subject.Paths["/buckets/{id}"]).To(HaveLen(4))
+		Expect(subject.Paths["/buckets"]["get"]).To(HaveKeyWithValue("summary", "List buckets"))
+		Expect(subject.Paths["/buckets/{id}"]["patch"]).To(HaveKeyWithValue("summary", "Update a bucket"))
+		Expect(subject.Paths["/buckets"]["delete"]).To(HaveKeyWithValue("deprecated", true))
+	})
+
+	It("documents parameters", func() {
+		data, err := json.Marshal(subject.Paths["/buckets/{bucket_id}/collections"]["get"]["parameters"])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`{"in":"path","name":"bucket_id","required":true,"schema":{"type":"string"}}`))
+		Expect(string(data)).To(ContainSubstring(`#/components/parameters/_sort`))
+		Expect(string(data)).To(ContainSubstring(`#/components/parameters/_limit`))
+		Expect(string(data)).To(ContainSubstring(`#/components/parameters/_token`))
+		Expect(string(data)).To(ContainSubstring(`#/components/parameters/q`))
+		Expect(string(data)).To(ContainSubstring(`#/components/parameters/_filter`))
+		Expect(string(data)).To(ContainSubstring(`min_, `))
+		Expect(string(data)).To(ContainSubstring(`has_`))
+	})
+
+	It("includes custom operation metadata", func() {
+		Expect(subject.Paths["/accounts/{account_id}/status"]).To(HaveLen(1))
+		op := subject.Paths["/accounts/{account_id}/status"]["get"]
+		Expect(op).To(HaveKeyWithValue("summary", "Account status"))
+		Expect(op).To(HaveKeyWithValue("tags", []string{"accounts"}))
+		Expect(op).To(HaveKeyWithValue("x-internal", true))
+
+		Expect(subject.Paths["/custom"]).To(HaveLen(1))
+		Expect(subject.Paths["/custom"]["post"]).To(HaveKeyWithValue("summary", "Custom action"))
+	})
+
+	It("includes components", func() {
+		Expect(subject.Components.Schemas).To(HaveKey("Error"))
+		Expect(subject.Components.Schemas).To(HaveKey("BatchRequest"))
+		Expect(subject.Components.SecuritySchemes).To(HaveKeyWithValue("basic", map[string]interface{}{
+			"type":   "http",
+			"scheme": "basic",
+		}))
+	})
+})
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "internal/openapi")
+}
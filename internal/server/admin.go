@@ -0,0 +1,157 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+)
+
+// Restricts access to configured admin principals.
+func (m *mux) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		txn := api.GetTxn(r)
+		if !isAdmin(txn.User, m.conf().Admin.Principals) {
+			if txn.User.ID == riposo.Everyone {
+				api.Render(w, schema.MissingAuthToken)
+			} else {
+				api.Render(w, schema.Forbidden)
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Flush removes all data from storage, permission and cache backends.
+func (*mux) Flush(w http.ResponseWriter, r *http.Request) {
+	txn := api.GetTxn(r)
+
+	if err := txn.Store.Flush(); err != nil {
+		api.Render(w, err)
+		return
+	}
+	if err := txn.Perms.Flush(); err != nil {
+		api.Render(w, err)
+		return
+	}
+	if err := txn.Cache.Flush(); err != nil {
+		api.Render(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// FlushCache removes all data from the cache backend.
+func (*mux) FlushCache(w http.ResponseWriter, r *http.Request) {
+	txn := api.GetTxn(r)
+
+	if err := txn.Cache.Flush(); err != nil {
+		api.Render(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Purge purges deleted objects (tombstones) from the storage backend. Accepts
+// an optional _before epoch parameter.
+func (*mux) Purge(w http.ResponseWriter, r *http.Request) {
+	txn := api.GetTxn(r)
+
+	var olderThan riposo.Epoch
+	if s := r.URL.Query().Get("_before"); s != "" {
+		val := schema.ParseValue(s)
+		if val.Int() < 1 {
+			api.Render(w, schema.InvalidQuery("_before must be a valid epoch"))
+			return
+		}
+		olderThan = riposo.Epoch(val.Int())
+	}
+
+	num, err := txn.Store.Purge(olderThan)
+	if err != nil {
+		api.Render(w, err)
+		return
+	}
+
+	api.Render(w, &schema.Purged{Purged: num})
+}
+
+// Reload reloads the configuration. Please note that only settings which are
+// evaluated per request, such as project info, end-of-service and admin
+// principals are affected.
+func (m *mux) Reload(w http.ResponseWriter, r *http.Request) {
+	next, err := m.conf().Reload()
+	if err != nil {
+		api.Render(w, err)
+		return
+	}
+
+	m.mu.Lock()
+	m.cfg = next
+	m.mu.Unlock()
+
+	riposo.Logger.Println("configuration reloaded")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Explain explains the read, write and create permissions of a principal on
+// a path. Accepts path and (optional) principal query parameters and defaults
+// to the current user.
+func (m *mux) Explain(w http.ResponseWriter, r *http.Request) {
+	txn := api.GetTxn(r)
+	query := r.URL.Query()
+
+	path := riposo.NormPath(query.Get("path"))
+	if path == "" || path.IsNode() {
+		api.Render(w, schema.InvalidQuery("path must address an object"))
+		return
+	}
+
+	principal := query.Get("principal")
+	principals := txn.User.Principals
+	if principal == "" {
+		principal = txn.User.ID
+	} else {
+		var err error
+		if principals, err = txn.Perms.GetUserPrincipals(principal); err != nil {
+			api.Render(w, err)
+			return
+		}
+	}
+
+	decisions, err := m.rts.Explain(txn, path, principals)
+	if err != nil {
+		api.Render(w, err)
+		return
+	}
+
+	api.Render(w, &explanation{
+		Path:        path,
+		Principal:   principal,
+		Principals:  principals,
+		Permissions: decisions,
+	})
+}
+
+type explanation struct {
+	Path        riposo.Path              `json:"path"`
+	Principal   string                   `json:"principal"`
+	Principals  []string                 `json:"principals"`
+	Permissions map[string]*api.Decision `json:"permissions"`
+}
+
+func isAdmin(user *api.User, principals []string) bool {
+	for _, p := range user.Principals {
+		for _, q := range principals {
+			if p == q {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -14,7 +14,7 @@ import (
 )
 
 // NewMux inits a new handler for tests.
-func NewMux() http.Handler {
+func NewMux(opts ...func(*config.Config)) http.Handler {
 	hlp := mock.Helpers()
 	cns := mock.Conns(hlp)
 
@@ -29,16 +29,45 @@ func NewMux() http.Handler {
 	cfg.Capabilities = new(plugin.Set)
 	cfg.Backoff.Duration = 60 * time.Second
 	cfg.RetryAfter = 30 * time.Second
+	for _, opt := range opts {
+		opt(cfg)
+	}
 
 	rts := api.NewRoutes(cfg.APIConfig())
 	rts.Resource("/buckets", nil)
 	rts.Handle("/failure", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		api.Render(w, schema.InternalError(fmt.Errorf("doh")))
 	}))
+	rts.Handle("/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		api.Render(w, r.Context().Err())
+	}))
+	rts.Handle("/hooks", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("name")
+		txn := api.GetTxn(r)
+		txn.OnCommit(func() { HookEvents = append(HookEvents, "commit:"+name) })
+		txn.OnRollback(func() { HookEvents = append(HookEvents, "rollback:"+name) })
+		HookEvents = append(HookEvents, "handle:"+name)
+
+		switch r.URL.Query().Get("fail") {
+		case "client":
+			api.Render(w, schema.InvalidQuery("failed"))
+		case "server":
+			api.Render(w, schema.InternalError(fmt.Errorf("doh")))
+		case "timeout":
+			<-r.Context().Done()
+			api.Render(w, struct{}{})
+		default:
+			api.Render(w, struct{}{})
+		}
+	}))
 
 	return newMux(rts, hlp, cns, mockAuth{}, cfg)
 }
 
+// HookEvents records the transaction hooks run by the /hooks route.
+var HookEvents []string
+
 type mockAuth struct{}
 
 func (mockAuth) Authenticate(r *http.Request) (*api.User, error) {
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strconv"
@@ -11,6 +12,7 @@ import (
 	"github.com/riposo/riposo/pkg/auth"
 	"github.com/riposo/riposo/pkg/conn"
 	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
 )
 
 // Backoff and Retry-After header middleware.
@@ -88,11 +90,26 @@ func (w *backoffWrapper) showRetryAfter() bool {
 // ----------------------------------------------------------------------------
 
 // Combined middleware to create transactions and authenticate users.
-func transactional(cns *conn.Set, hlp riposo.Helpers, am auth.Method) func(http.Handler) http.Handler {
+// Transactions are bound to the request context and rolled back when the
+// context is cancelled or exceeds the optional timeout.
+func transactional(cns *conn.Set, hlp riposo.Helpers, am auth.Method, timeout time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// init transaction
-			txn, err := api.NewTxn(r.Context(), cns, hlp)
+			// apply request timeout
+			if timeout > 0 {
+				ctx, cancel := context.WithTimeout(r.Context(), timeout)
+				defer cancel()
+
+				r = r.WithContext(ctx)
+			}
+
+			// init transaction, read-only for safe methods
+			var opt *riposo.TxOptions
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				opt = &riposo.TxOptions{ReadOnly: true}
+			}
+
+			txn, err := api.NewTxn(r.Context(), cns, hlp, opt)
 			if err != nil {
 				api.Render(w, err)
 				return
@@ -138,10 +155,12 @@ func (w *transactionalWrapper) WriteHeader(code int) {
 	}
 	w.wroteHeader = true
 
-	if code < http.StatusInternalServerError {
+	if err := w.txn.Err(); err != nil {
+		_ = w.txn.Rollback()
+		code = contextErrorStatus(err, code)
+	} else if code < http.StatusInternalServerError {
 		if err := w.txn.Commit(); err != nil {
-			w.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
-			return
+			code = contextErrorStatus(err, http.StatusServiceUnavailable)
 		}
 	}
 	w.ResponseWriter.WriteHeader(code)
@@ -151,3 +170,14 @@ func (w *transactionalWrapper) Write(buf []byte) (int, error) {
 	w.WriteHeader(http.StatusOK)
 	return w.ResponseWriter.Write(buf)
 }
+
+// contextErrorStatus returns the status code for requests which failed
+// because of err or fallback.
+func contextErrorStatus(err error, fallback int) int {
+	if errors.Is(err, context.Canceled) {
+		return schema.StatusClientClosedRequest
+	} else if errors.Is(err, context.DeadlineExceeded) || fallback < http.StatusInternalServerError {
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
@@ -3,12 +3,14 @@ package server
 import (
 	"net/http"
 	"strings"
+	"sync"
 
 	"github.com/go-chi/chi/v5"
 	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/riposo/riposo/internal/batch"
 	"github.com/riposo/riposo/internal/config"
+	"github.com/riposo/riposo/internal/openapi"
 	"github.com/riposo/riposo/pkg/api"
 	"github.com/riposo/riposo/pkg/auth"
 	"github.com/riposo/riposo/pkg/conn"
@@ -18,17 +20,30 @@ import (
 
 type mux struct {
 	*chi.Mux
+	rts *api.Routes
 	cns *conn.Set
 	hlp riposo.Helpers
+
 	cfg *config.Config
+	mu  sync.RWMutex
+
+	api *openapi.Document
 }
 
 func newMux(rts *api.Routes, hlp riposo.Helpers, cns *conn.Set, auth auth.Method, cfg *config.Config) http.Handler {
 	m := &mux{
 		Mux: chi.NewMux(),
+		rts: rts,
 		cns: cns,
 		hlp: hlp,
 		cfg: cfg,
+		api: openapi.Generate(rts, openapi.Info{
+			Title:       cfg.Project.Name,
+			Version:     cfg.Project.Version,
+			Docs:        cfg.Project.Docs,
+			Namespace:   "/v1",
+			AuthMethods: cfg.Auth.Methods,
+		}),
 	}
 
 	m.Use(chimw.RealIP)
@@ -62,13 +77,26 @@ func newMux(rts *api.Routes, hlp riposo.Helpers, cns *conn.Set, auth auth.Method
 		r.Get("/", m.Hello)
 		r.Get("/__heartbeat__", m.Heartbeat)
 		r.Get("/__lbheartbeat__", m.HeartbeatLB)
+		r.Get("/__api__", m.OpenAPI)
 
 		r.Group(func(r chi.Router) {
 			r.Use(chimw.StripSlashes)
-			r.Use(transactional(m.cns, m.hlp, auth))
+			r.Use(transactional(m.cns, m.hlp, auth, cfg.Server.RequestTimeout))
 
 			r.Mount("/", rts.Mux())
 			r.Method(http.MethodPost, "/batch", batch.Handler("/v1", rts.Mux()))
+
+			// admin endpoints, if enabled
+			if cfg.Admin.Enabled {
+				r.Group(func(r chi.Router) {
+					r.Use(m.adminOnly)
+					r.Post("/__flush__", m.Flush)
+					r.Post("/__flush_cache__", m.FlushCache)
+					r.Post("/__purge__", m.Purge)
+					r.Post("/__reload__", m.Reload)
+					r.Get("/__explain__", m.Explain)
+				})
+			}
 		})
 	})
 
@@ -83,27 +111,39 @@ func (*mux) HeartbeatLB(w http.ResponseWriter, _ *http.Request) {
 	api.Render(w, struct{}{})
 }
 
+func (m *mux) OpenAPI(w http.ResponseWriter, _ *http.Request) {
+	api.Render(w, m.api)
+}
+
 func (m *mux) Hello(w http.ResponseWriter, r *http.Request) {
 	if !strings.HasSuffix(r.URL.Path, "/") {
 		http.Redirect(w, r, "/v1/", http.StatusTemporaryRedirect)
 		return
 	}
 
+	cfg := m.conf()
 	resp := schema.Hello{
-		ProjectName:    m.cfg.Project.Name,
-		ProjectDocs:    m.cfg.Project.Docs,
-		ProjectVersion: m.cfg.Project.Version,
+		ProjectName:    cfg.Project.Name,
+		ProjectDocs:    cfg.Project.Docs,
+		ProjectVersion: cfg.Project.Version,
 		HTTPAPIVersion: riposo.APIVersion,
 		URL:            r.URL.String(),
-		Capabilities:   m.cfg.Capabilities,
+		Capabilities:   cfg.Capabilities,
 	}
-	if !m.cfg.EOS.Time.IsZero() {
-		resp.EOS = m.cfg.EOS.Time.Format("2006-01-02")
+	if !cfg.EOS.Time.IsZero() {
+		resp.EOS = cfg.EOS.Time.Format("2006-01-02")
 	}
-	resp.Settings.BatchMaxRequests = m.cfg.Batch.MaxRequests
+	resp.Settings.BatchMaxRequests = cfg.Batch.MaxRequests
 	api.Render(w, &resp)
 }
 
+func (m *mux) conf() *config.Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.cfg
+}
+
 // --------------------------------------------------------------------
 
 func configCORS(c *config.Config) cors.Options {
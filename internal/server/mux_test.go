@@ -1,9 +1,14 @@
 package server_test
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"time"
+
+	"github.com/riposo/riposo/internal/config"
 
 	. "github.com/bsm/ginkgo/v2"
 	. "github.com/bsm/gomega"
@@ -105,6 +110,114 @@ var _ = Describe("Muxer", func() {
 		})
 	})
 
+	Describe("GET /v1/__api__", func() {
+		It("responds", func() {
+			w := serve(httptest.NewRequest(http.MethodGet, "/v1/__api__", nil))
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			var doc struct {
+				OpenAPI string                     `json:"openapi"`
+				Info    map[string]string          `json:"info"`
+				Paths   map[string]json.RawMessage `json:"paths"`
+			}
+			Expect(json.Unmarshal(w.Body.Bytes(), &doc)).To(Succeed())
+			Expect(doc.OpenAPI).To(Equal("3.0.3"))
+			Expect(doc.Info).To(Equal(map[string]string{"title": "Example Project", "version": "0.11.2"}))
+			Expect(doc.Paths).To(HaveKey("/batch"))
+			Expect(doc.Paths).To(HaveKey("/buckets"))
+			Expect(doc.Paths).To(HaveKey("/buckets/{id}"))
+			Expect(doc.Paths).To(HaveKey("/failure"))
+		})
+	})
+
+	It("times out requests", func() {
+		subject = NewMux(func(c *config.Config) {
+			c.Server.RequestTimeout = 10 * time.Millisecond
+		})
+
+		w := serve(httptest.NewRequest(http.MethodGet, "/v1/slow", nil))
+		Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(w.Body.String()).To(MatchJSON(`{
+			"code": 503,
+			"errno": 201,
+			"error": "Service Unavailable",
+			"message": "Request timed out."
+		}`))
+	})
+
+	It("handles cancelled requests", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		w := serve(httptest.NewRequest(http.MethodGet, "/v1/buckets", nil).WithContext(ctx))
+		Expect(w.Code).To(Equal(499))
+		Expect(w.Body.String()).To(MatchJSON(`{
+			"code": 499,
+			"errno": 999,
+			"error": "Client Closed Request",
+			"message": "Client closed the request."
+		}`))
+	})
+
+	Describe("transaction hooks", func() {
+		BeforeEach(func() {
+			HookEvents = nil
+		})
+
+		It("runs commit hooks after commit", func() {
+			w := serve(httptest.NewRequest(http.MethodGet, "/v1/hooks?name=a", nil))
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(HookEvents).To(Equal([]string{"handle:a", "commit:a"}))
+		})
+
+		It("runs rollback hooks on failure", func() {
+			w := serve(httptest.NewRequest(http.MethodGet, "/v1/hooks?name=a&fail=server", nil))
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+			Expect(HookEvents).To(Equal([]string{"handle:a", "rollback:a"}))
+		})
+
+		It("rolls back when the request times out", func() {
+			subject = NewMux(func(c *config.Config) {
+				c.Server.RequestTimeout = 10 * time.Millisecond
+			})
+
+			w := serve(httptest.NewRequest(http.MethodGet, "/v1/hooks?name=a&fail=timeout", nil))
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(HookEvents).To(Equal([]string{"handle:a", "rollback:a"}))
+		})
+
+		It("runs hooks once the whole batch commits", func() {
+			w := serve(httptest.NewRequest(http.MethodPost, "/v1/batch", strings.NewReader(`{
+				"requests": [
+					{ "method": "GET", "path": "/hooks?name=a" },
+					{ "method": "GET", "path": "/hooks?name=b&fail=client" },
+					{ "method": "GET", "path": "/hooks?name=c" }
+				]
+			}`)))
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(HookEvents).To(Equal([]string{
+				"handle:a", "handle:b", "handle:c",
+				"commit:a", "commit:c", "rollback:b",
+			}))
+		})
+
+		It("runs rollback hooks of failed atomic batches", func() {
+			w := serve(httptest.NewRequest(http.MethodPost, "/v1/batch", strings.NewReader(`{
+				"atomic": true,
+				"requests": [
+					{ "method": "GET", "path": "/hooks?name=a" },
+					{ "method": "GET", "path": "/hooks?name=b&fail=client" },
+					{ "method": "GET", "path": "/hooks?name=c" }
+				]
+			}`)))
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(HookEvents).To(Equal([]string{
+				"handle:a", "handle:b",
+				"rollback:a", "rollback:b",
+			}))
+		})
+	})
+
 	Describe("GET /v1/__lbheartbeat__", func() {
 		It("responds", func() {
 			w := serve(httptest.NewRequest(http.MethodGet, "/v1/__lbheartbeat__", nil))
@@ -169,4 +282,126 @@ var _ = Describe("Muxer", func() {
 			}`))
 		})
 	})
+
+	Describe("admin endpoints", func() {
+		enableAdmin := func(c *config.Config) {
+			c.Admin.Enabled = true
+			c.Admin.Principals = []string{"account:admin"}
+			c.Permission.Defaults = map[string][]string{"bucket:create": {"account:admin"}}
+		}
+
+		handle := func(method, path, user string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest(method, path, nil)
+			if user != "" {
+				r.SetBasicAuth(user, "")
+			}
+			return serve(r)
+		}
+
+		It("are disabled by default", func() {
+			w := handle(http.MethodPost, "/v1/__flush__", "admin")
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("are restricted to admins", func() {
+			subject = NewMux(enableAdmin)
+
+			w := handle(http.MethodPost, "/v1/__flush__", "")
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+
+			w = handle(http.MethodPost, "/v1/__flush__", "bob")
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+		})
+
+		It("flushes", func() {
+			subject = NewMux(enableAdmin)
+
+			w := handle(http.MethodPost, "/v1/buckets", "admin")
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			w = handle(http.MethodGet, "/v1/buckets", "admin")
+			Expect(w.Body.String()).To(ContainSubstring(`"id"`))
+
+			w = handle(http.MethodPost, "/v1/__flush__", "admin")
+			Expect(w.Code).To(Equal(http.StatusAccepted))
+
+			w = handle(http.MethodGet, "/v1/buckets", "admin")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.Bytes()).To(MatchJSON(`{"data": []}`))
+		})
+
+		It("flushes cache", func() {
+			subject = NewMux(enableAdmin)
+
+			w := handle(http.MethodPost, "/v1/__flush_cache__", "admin")
+			Expect(w.Code).To(Equal(http.StatusAccepted))
+		})
+
+		It("purges", func() {
+			subject = NewMux(enableAdmin)
+
+			w := handle(http.MethodPut, "/v1/buckets/foo", "admin")
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			w = handle(http.MethodDelete, "/v1/buckets/foo", "admin")
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			w = handle(http.MethodPost, "/v1/__purge__?_before=bad", "admin")
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+
+			w = handle(http.MethodPost, "/v1/__purge__", "admin")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.Bytes()).To(MatchJSON(`{"purged": 1}`))
+		})
+
+		It("explains permissions", func() {
+			subject = NewMux(enableAdmin)
+
+			w := handle(http.MethodPut, "/v1/buckets/foo", "admin")
+			Expect(w.Code).To(Equal(http.StatusCreated))
+
+			w = handle(http.MethodGet, "/v1/__explain__?path=/buckets/foo", "admin")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.Bytes()).To(MatchJSON(`{
+				"path": "/buckets/foo",
+				"principal": "account:admin",
+				"principals": ["account:admin", "system.Authenticated", "system.Everyone"],
+				"permissions": {
+					"read": {
+						"allowed": true,
+						"grants": [{"permission": "write", "path": "/buckets/foo", "level": "bucket", "principal": "account:admin"}]
+					},
+					"write": {
+						"allowed": true,
+						"grants": [{"permission": "write", "path": "/buckets/foo", "level": "bucket", "principal": "account:admin"}]
+					},
+					"create": {
+						"allowed": true,
+						"grants": [{"permission": "bucket:create", "principal": "account:admin", "static": true}]
+					}
+				}
+			}`))
+
+			w = handle(http.MethodGet, "/v1/__explain__?path=/v1/buckets/foo&principal=account:bob", "admin")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.Bytes()).To(MatchJSON(`{
+				"path": "/buckets/foo",
+				"principal": "account:bob",
+				"principals": ["account:bob", "system.Authenticated", "system.Everyone"],
+				"permissions": {
+					"read": {"allowed": false, "grants": []},
+					"write": {"allowed": false, "grants": []},
+					"create": {"allowed": false, "grants": []}
+				}
+			}`))
+
+			w = handle(http.MethodGet, "/v1/__explain__?path=/buckets", "admin")
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("fails to reload without a parser", func() {
+			subject = NewMux(enableAdmin)
+
+			w := handle(http.MethodPost, "/v1/__reload__", "admin")
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		})
+	})
 })
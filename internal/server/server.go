@@ -5,16 +5,21 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/riposo/riposo/internal/config"
 	"github.com/riposo/riposo/internal/model/group"
 	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/attachment"
 	"github.com/riposo/riposo/pkg/auth"
 	"github.com/riposo/riposo/pkg/conn"
 	"github.com/riposo/riposo/pkg/plugin"
 	"github.com/riposo/riposo/pkg/riposo"
 	"github.com/riposo/riposo/pkg/rule"
+	"github.com/riposo/riposo/pkg/signer"
 	"go.uber.org/multierr"
 )
 
@@ -33,8 +38,27 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 		return nil, err
 	}
 
-	// init routes, install resources
+	// init attachments
+	blob, err := initAttachments(ctx, hlp, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// init signer
+	sgn, err := initSigner(cfg)
+	if err != nil {
+		_ = closeBlob(blob)
+		return nil, err
+	}
+
+	// init routes, install extensions and resources
 	rts := api.NewRoutes(cfg.APIConfig())
+	if blob != nil {
+		attachment.Install(rts, blob, cfg.Attachments.MaxSize)
+	}
+	if sgn != nil {
+		signer.Install(rts, sgn, cfg.Signer.Collections)
+	}
 	rts.Resource("/buckets", nil)
 	rts.Resource("/buckets/{bucket_id}/groups", group.Model{})
 	rts.Resource("/buckets/{bucket_id}/collections", nil)
@@ -43,6 +67,7 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 	// init plugins
 	plugins, err := plugin.Init(ctx, rts, hlp, cfg.Plugins)
 	if err != nil {
+		_ = closeBlob(blob)
 		return nil, err
 	}
 	cfg.Capabilities = plugins
@@ -51,6 +76,7 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 	rules, err := rule.Init(ctx, rts, hlp, cfg.Rules)
 	if err != nil {
 		_ = plugins.Close()
+		_ = closeBlob(blob)
 		return nil, err
 	}
 
@@ -59,6 +85,7 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 	if err != nil {
 		_ = rules.Close()
 		_ = plugins.Close()
+		_ = closeBlob(blob)
 		return nil, err
 	}
 
@@ -68,12 +95,16 @@ func New(ctx context.Context, cfg *config.Config) (*Server, error) {
 		_ = auth.Close()
 		_ = rules.Close()
 		_ = plugins.Close()
+		_ = closeBlob(blob)
 		return nil, err
 	}
 
 	// init mux
 	mux := newMux(rts, hlp, cns, auth, cfg)
 	cls := []io.Closer{cns, auth, rules, plugins}
+	if blob != nil {
+		cls = append(cls, blob)
+	}
 
 	return &Server{
 		srv: &http.Server{
@@ -134,5 +165,41 @@ func establishConns(ctx context.Context, hlp riposo.Helpers, cfg *config.Config)
 		cfg.Permission.URL,
 		cfg.Cache.URL,
 		hlp,
+		cfg.ConnOptions(),
 	)
 }
+
+func initAttachments(ctx context.Context, hlp riposo.Helpers, cfg *config.Config) (attachment.Blob, error) {
+	if !cfg.Attachments.Enabled {
+		return nil, nil
+	}
+
+	blobURL := cfg.Attachments.URL
+	if blobURL == "" {
+		dir := cfg.Temp.Dir
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		blobURL = (&url.URL{Scheme: "file", Path: filepath.Join(dir, "riposo-attachments")}).String()
+	}
+	return attachment.Connect(ctx, blobURL, hlp)
+}
+
+func initSigner(cfg *config.Config) (*signer.Signer, error) {
+	if !cfg.Signer.Enabled {
+		return nil, nil
+	}
+
+	x5u := cfg.Signer.X5U
+	if x5u == "" {
+		x5u = "/v1" + signer.ChainPattern
+	}
+	return signer.Load(cfg.Signer.Key, cfg.Signer.Chain, x5u)
+}
+
+func closeBlob(blob attachment.Blob) error {
+	if blob != nil {
+		return blob.Close()
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package api_test
 
 import (
 	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/params"
 	"github.com/riposo/riposo/pkg/riposo"
 	"github.com/riposo/riposo/pkg/schema"
 
@@ -33,7 +34,7 @@ var _ = Describe("Actions", func() {
 		Expect(txn.Rollback()).To(Succeed())
 	})
 
-	It("gets", func() {
+	It("gets with callbacks", func() {
 		Expect(subject.Get(txn, "/objects/EPR.ID")).To(Equal(&schema.Resource{
 			Data: &schema.Object{
 				ID:      "EPR.ID",
@@ -42,6 +43,65 @@ var _ = Describe("Actions", func() {
 			},
 			Permissions: schema.PermissionSet{"write": {"alice"}},
 		}))
+		Expect(cbs.Calls).To(Equal([]string{"AfterGet"}))
+		Expect(cbs.Paths).To(Equal([]string{"/objects/EPR.ID"}))
+	})
+
+	It("lists with callbacks", func() {
+		Expect(subject.List(txn, "/objects/*", &params.Params{Limit: 10})).To(Equal([]*schema.Object{
+			{ID: "EPR.ID", ModTime: 1515151515677, Extra: []byte(`{"meta":true}`)},
+		}))
+		Expect(cbs.Calls).To(Equal([]string{"BeforeList", "AfterList"}))
+		Expect(cbs.Paths).To(Equal([]string{"/objects/*"}))
+	})
+
+	It("allows callbacks to amend reads", func() {
+		Expect(subject.Create(txn, "/objects/*", &schema.Resource{
+			Data: &schema.Object{Extra: []byte(`{"secret":true}`)},
+		})).To(Succeed())
+
+		subject = NewActions(DefaultModel{}, []Callbacks{redactCallbacks{}})
+		Expect(subject.List(txn, "/objects/*", &params.Params{Limit: 10})).To(Equal([]*schema.Object{
+			{ID: "EPR.ID", ModTime: 1515151515677, Extra: []byte(`{"meta":true,"redacted":true}`)},
+		}))
+		Expect(subject.Get(txn, "/objects/EPR.ID")).To(HaveField("Data.Extra", []byte(`{"meta":true,"redacted":true}`)))
+
+		_, err := subject.Get(txn, "/objects/ITR.ID")
+		Expect(err).To(MatchError(schema.Forbidden))
+	})
+
+	It("applies get callbacks to write responses", func() {
+		exst, err := txn.Store.Get("/objects/EPR.ID", true)
+		Expect(err).NotTo(HaveOccurred())
+
+		subject = NewActions(DefaultModel{}, []Callbacks{redactCallbacks{}})
+		Expect(subject.Patch(txn, "/objects/EPR.ID", exst, &schema.Resource{
+			Data: &schema.Object{Extra: []byte(`{"patched":true}`)},
+		})).To(HaveField("Data.Extra", MatchJSON(`{"meta":true,"patched":true,"redacted":true}`)))
+
+		_, err = subject.Update(txn, "/objects/EPR.ID", exst, &schema.Resource{
+			Data: &schema.Object{Extra: []byte(`{"secret":true}`)},
+		})
+		Expect(err).To(MatchError(schema.Forbidden))
+	})
+
+	It("lists and counts with models which only implement Model", func() {
+		subject = NewActions(struct{ Model }{DefaultModel{}}, nil)
+		Expect(subject.List(txn, "/objects/*", &params.Params{Limit: 10})).To(HaveLen(1))
+		Expect(subject.Count(txn, "/objects/*", &params.Params{})).To(Equal(int64(1)))
+	})
+
+	It("applies list callbacks to counts and aggregations", func() {
+		Expect(subject.Create(txn, "/objects/*", &schema.Resource{
+			Data: &schema.Object{Extra: []byte(`{"secret":true}`)},
+		})).To(Succeed())
+		Expect(subject.Count(txn, "/objects/*", &params.Params{})).To(Equal(int64(2)))
+
+		subject = NewActions(DefaultModel{}, []Callbacks{redactCallbacks{}})
+		Expect(subject.Count(txn, "/objects/*", &params.Params{})).To(Equal(int64(1)))
+		Expect(subject.Aggregate(txn, "/objects/*", &params.Params{
+			Aggregate: &params.Aggregation{Func: params.AggregateCount},
+		})).To(ConsistOf(HaveField("Value", schema.ParseValue("1"))))
 	})
 
 	It("creates with callbacks", func() {
@@ -52,8 +112,8 @@ var _ = Describe("Actions", func() {
 			ModTime: 1515151515678,
 			Extra:   []byte(`{"created":true}`),
 		}))
-		Expect(cbs.Calls).To(Equal([]string{"BeforeCreate", "AfterCreate"}))
-		Expect(cbs.Paths).To(Equal([]string{"/objects/*"}))
+		Expect(cbs.Calls).To(Equal([]string{"BeforeCreate", "AfterCreate", "AfterGet"}))
+		Expect(cbs.Paths).To(Equal([]string{"/objects/*", "/objects/ITR.ID"}))
 	})
 
 	It("updates with callbacks", func() {
@@ -70,8 +130,8 @@ var _ = Describe("Actions", func() {
 			},
 			Permissions: schema.PermissionSet{"write": {"alice"}},
 		}))
-		Expect(cbs.Calls).To(Equal([]string{"BeforeUpdate", "AfterUpdate"}))
-		Expect(cbs.Paths).To(Equal([]string{"/objects/EPR.ID"}))
+		Expect(cbs.Calls).To(Equal([]string{"BeforeUpdate", "AfterUpdate", "AfterGet"}))
+		Expect(cbs.Paths).To(Equal([]string{"/objects/EPR.ID", "/objects/EPR.ID"}))
 	})
 
 	It("patches with callbacks", func() {
@@ -88,8 +148,8 @@ var _ = Describe("Actions", func() {
 			},
 			Permissions: schema.PermissionSet{"write": {"alice"}},
 		}))
-		Expect(cbs.Calls).To(Equal([]string{"BeforePatch", "AfterPatch"}))
-		Expect(cbs.Paths).To(Equal([]string{"/objects/EPR.ID"}))
+		Expect(cbs.Calls).To(Equal([]string{"BeforePatch", "AfterPatch", "AfterGet"}))
+		Expect(cbs.Paths).To(Equal([]string{"/objects/EPR.ID", "/objects/EPR.ID"}))
 	})
 
 	It("deletes with callbacks", func() {
@@ -115,3 +175,31 @@ var _ = Describe("Actions", func() {
 		Expect(cbs.Paths).To(Equal([]string{"/objects/*"}))
 	})
 })
+
+type redactCallbacks struct {
+	NoopCallbacks
+}
+
+func (redactCallbacks) OnGet(_ *Txn, _ riposo.Path) GetCallback   { return redactCallbacks{} }
+func (redactCallbacks) OnList(_ *Txn, _ riposo.Path) ListCallback { return redactCallbacks{} }
+
+func (redactCallbacks) AfterGet(res *schema.Resource) error {
+	if res.Data.Get("secret").Bool() {
+		return schema.Forbidden
+	}
+	return res.Data.Set("redacted", true)
+}
+
+func (redactCallbacks) BeforeList(pms *params.Params) error {
+	pms.Condition = append(pms.Condition, params.ParseFilter("has_secret", "false"))
+	return nil
+}
+
+func (redactCallbacks) AfterList(objs []*schema.Object) error {
+	for _, obj := range objs {
+		if err := obj.Set("redacted", true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/params"
 	"github.com/riposo/riposo/pkg/riposo"
 	"github.com/riposo/riposo/pkg/schema"
 )
@@ -8,6 +10,9 @@ import (
 // Actions wraps a model with a callback chain.
 type Actions interface {
 	Get(txn *Txn, path riposo.Path) (*schema.Resource, error)
+	List(txn *Txn, path riposo.Path, params *params.Params) ([]*schema.Object, error)
+	Count(txn *Txn, path riposo.Path, params *params.Params) (int64, error)
+	Aggregate(txn *Txn, path riposo.Path, params *params.Params) ([]storage.AggregateGroup, error)
 	Create(txn *Txn, path riposo.Path, payload *schema.Resource) error
 	Update(txn *Txn, path riposo.Path, exst *schema.Object, payload *schema.Resource) (*schema.Resource, error)
 	Patch(txn *Txn, path riposo.Path, exst *schema.Object, payload *schema.Resource) (*schema.Resource, error)
@@ -26,7 +31,123 @@ type actions struct {
 }
 
 func (a *actions) Get(txn *Txn, path riposo.Path) (*schema.Resource, error) {
-	return a.mod.Get(txn, path)
+	// get resource
+	res, err := a.mod.Get(txn, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// run get callbacks
+	if err := a.afterGet(txn, path, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// afterGet runs get callbacks on resources returned by reads and writes.
+func (a *actions) afterGet(txn *Txn, path riposo.Path, res *schema.Resource) error {
+	// prepare callbacks
+	callbacks := a.prepareCallbacks(func(cb Callbacks) interface{} {
+		return cb.OnGet(txn, path)
+	})
+	defer callbacks.Release()
+
+	// run after callbacks in reverse order
+	for i := len(callbacks.S) - 1; i >= 0; i-- {
+		if err := callbacks.S[i].(GetCallback).AfterGet(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List fetches one more object than the params limit to allow callers to
+// detect further pages.
+func (a *actions) List(txn *Txn, path riposo.Path, params *params.Params) ([]*schema.Object, error) {
+	// prepare callbacks
+	callbacks := a.prepareCallbacks(func(cb Callbacks) interface{} {
+		return cb.OnList(txn, path)
+	})
+	defer callbacks.Release()
+
+	// run before callbacks
+	for _, c := range callbacks.S {
+		if err := c.(ListCallback).BeforeList(params); err != nil {
+			return nil, err
+		}
+	}
+
+	// list objects
+	sort := params.KeysetSort()
+	lister, ok := a.mod.(Lister)
+	if !ok {
+		lister = DefaultModel{}
+	}
+	objs, err := lister.List(txn, path, storage.ListOptions{
+		Condition:  params.Condition,
+		Pagination: params.Token.Conditions(sort),
+		Sort:       sort,
+		Limit:      params.Limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// run after callbacks in reverse order
+	for i := len(callbacks.S) - 1; i >= 0; i-- {
+		if err := callbacks.S[i].(ListCallback).AfterList(objs); err != nil {
+			return nil, err
+		}
+	}
+
+	return objs, nil
+}
+
+// Count runs the before list callbacks before counting objects.
+func (a *actions) Count(txn *Txn, path riposo.Path, params *params.Params) (int64, error) {
+	if err := a.beforeList(txn, path, params); err != nil {
+		return 0, err
+	}
+
+	counter, ok := a.mod.(Counter)
+	if !ok {
+		counter = DefaultModel{}
+	}
+	return counter.Count(txn, path, storage.CountOptions{
+		Condition: params.Condition,
+	})
+}
+
+// Aggregate runs the before list callbacks before aggregating objects.
+func (a *actions) Aggregate(txn *Txn, path riposo.Path, params *params.Params) ([]storage.AggregateGroup, error) {
+	if err := a.beforeList(txn, path, params); err != nil {
+		return nil, err
+	}
+
+	aggregator, ok := a.mod.(Aggregator)
+	if !ok {
+		aggregator = DefaultModel{}
+	}
+	return aggregator.Aggregate(txn, path, storage.AggregateOptions{
+		Condition:   params.Condition,
+		Aggregation: *params.Aggregate,
+	})
+}
+
+func (a *actions) beforeList(txn *Txn, path riposo.Path, params *params.Params) error {
+	// prepare callbacks
+	callbacks := a.prepareCallbacks(func(cb Callbacks) interface{} {
+		return cb.OnList(txn, path)
+	})
+	defer callbacks.Release()
+
+	// run before callbacks
+	for _, c := range callbacks.S {
+		if err := c.(ListCallback).BeforeList(params); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (a *actions) Create(txn *Txn, path riposo.Path, payload *schema.Resource) error {
@@ -56,7 +177,8 @@ func (a *actions) Create(txn *Txn, path riposo.Path, payload *schema.Resource) e
 		}
 	}
 
-	return nil
+	// run get callbacks on the response
+	return a.afterGet(txn, path.WithObjectID(payload.Data.ID), payload)
 }
 
 func (a *actions) Update(txn *Txn, path riposo.Path, exst *schema.Object, payload *schema.Resource) (*schema.Resource, error) {
@@ -94,6 +216,10 @@ func (a *actions) Update(txn *Txn, path riposo.Path, exst *schema.Object, payloa
 		}
 	}
 
+	// run get callbacks on the response
+	if err := a.afterGet(txn, path, res); err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
@@ -132,6 +258,10 @@ func (a *actions) Patch(txn *Txn, path riposo.Path, exst *schema.Object, payload
 		}
 	}
 
+	// run get callbacks on the response
+	if err := a.afterGet(txn, path, res); err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
@@ -2,6 +2,8 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 	"time"
 
@@ -40,6 +42,7 @@ type Config struct {
 	Pagination struct {
 		TokenValidity time.Duration
 		MaxLimit      int
+		Secret        string // used to sign tokens, random if blank
 	}
 }
 
@@ -56,6 +59,13 @@ func (c *Config) norm() *Config {
 	if c.Pagination.MaxLimit == 0 {
 		c.Pagination.MaxLimit = 10_000
 	}
+	if c.Pagination.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+		c.Pagination.Secret = hex.EncodeToString(secret)
+	}
 	return c
 }
 
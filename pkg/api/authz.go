@@ -2,6 +2,7 @@ package api
 
 import (
 	"github.com/riposo/riposo/pkg/conn/permission"
+	"github.com/riposo/riposo/pkg/riposo"
 )
 
 // Authz represents an authorization guard and can verify access of user
@@ -32,6 +33,63 @@ func (v Authz) Verify(txn permission.Transaction, principals []string, target []
 	return containsOneOf(principals, allowed), nil
 }
 
+// Grant describes an entry which grants access to a principal.
+type Grant struct {
+	Permission string      `json:"permission"`
+	Path       riposo.Path `json:"path,omitempty"`  // blank for static grants
+	Level      string      `json:"level,omitempty"` // the resource level of path, e.g. "bucket"
+	Principal  string      `json:"principal"`
+	Static     bool        `json:"static,omitempty"` // granted by a static principal
+}
+
+// Decision is an explained access decision.
+type Decision struct {
+	Allowed bool    `json:"allowed"`
+	Grants  []Grant `json:"grants"`
+}
+
+// Explain explains Verify by returning all the grants through which any of the
+// user principals can access any of the target entities.
+func (v Authz) Explain(txn permission.Transaction, principals []string, target []permission.ACE) (*Decision, error) {
+	decision := &Decision{Grants: []Grant{}}
+	static := make(map[string]bool)
+
+	for _, ent := range target {
+		// check static principals first
+		if allowed, ok := v[ent.Perm]; ok && !static[ent.Perm] {
+			static[ent.Perm] = true
+			for _, principal := range allowed {
+				if containsOneOf(principals, []string{principal}) {
+					decision.Grants = append(decision.Grants, Grant{
+						Permission: ent.Perm,
+						Principal:  principal,
+						Static:     true,
+					})
+				}
+			}
+		}
+
+		// retrieve stored principals
+		stored, err := txn.GetACEPrincipals(ent)
+		if err != nil {
+			return nil, err
+		}
+		for _, principal := range stored {
+			if containsOneOf(principals, []string{principal}) {
+				decision.Grants = append(decision.Grants, Grant{
+					Permission: ent.Perm,
+					Path:       ent.Path,
+					Level:      ent.Path.ResourceName(),
+					Principal:  principal,
+				})
+			}
+		}
+	}
+
+	decision.Allowed = len(decision.Grants) != 0
+	return decision, nil
+}
+
 func containsOneOf(vv, ww []string) bool {
 	if len(ww) == 0 {
 		return false
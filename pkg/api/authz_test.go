@@ -29,7 +29,7 @@ var _ = Describe("Authz", func() {
 		}
 
 		var err error
-		tx, err = backend.Begin(ctx)
+		tx, err = backend.Begin(ctx, nil)
 		Expect(err).NotTo(HaveOccurred())
 	})
 
@@ -98,6 +98,29 @@ var _ = Describe("Authz", func() {
 		})).To(BeFalse())
 	})
 
+	It("explains", func() {
+		Expect(tx.AddACEPrincipal("alice", ACE("write", "/buckets/bat"))).To(Succeed())
+		Expect(tx.AddACEPrincipal("/buckets/bat/groups/g", ACE("read", "/buckets/bat/collections/cat"))).To(Succeed())
+
+		Expect(subject.Explain(tx, []string{"alice", "/buckets/bat/groups/g", "system.Authenticated"}, []permission.ACE{
+			ACE("static:create", "/buckets/bat"),
+			ACE("write", "/buckets/bat"),
+			ACE("read", "/buckets/bat/collections/cat"),
+			ACE("static:create", "/buckets/bat/collections/cat"),
+		})).To(Equal(&Decision{
+			Allowed: true,
+			Grants: []Grant{
+				{Permission: "static:create", Principal: "system.Authenticated", Static: true},
+				{Permission: "write", Path: "/buckets/bat", Level: "bucket", Principal: "alice"},
+				{Permission: "read", Path: "/buckets/bat/collections/cat", Level: "collection", Principal: "/buckets/bat/groups/g"},
+			},
+		}))
+
+		Expect(subject.Explain(tx, []string{"bob"}, []permission.ACE{
+			ACE("write", "/buckets/bat"),
+		})).To(Equal(&Decision{Grants: []Grant{}}))
+	})
+
 	It("refuses empty sets", func() {
 		Expect(tx.AddACEPrincipal("alice", ACE("write", "/bucket/bat"))).To(Succeed())
 		Expect(subject.Verify(tx, nil, []permission.ACE{ACE("write", "/bucket/bat")})).To(BeFalse())
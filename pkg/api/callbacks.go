@@ -1,12 +1,17 @@
 package api
 
 import (
+	"github.com/riposo/riposo/pkg/params"
 	"github.com/riposo/riposo/pkg/riposo"
 	"github.com/riposo/riposo/pkg/schema"
 )
 
 // Callbacks are a set of callbacks around a model.
 type Callbacks interface {
+	// OnGet is triggered on get.
+	OnGet(txn *Txn, path riposo.Path) GetCallback
+	// OnList is triggered on list.
+	OnList(txn *Txn, path riposo.Path) ListCallback
 	// OnCreate is triggered on create.
 	OnCreate(txn *Txn, path riposo.Path) CreateCallback
 	// OnUpdate is triggered on update.
@@ -19,6 +24,22 @@ type Callbacks interface {
 	OnDeleteAll(txn *Txn, path riposo.Path) DeleteAllCallback
 }
 
+// GetCallback instances run after get actions and on the resources returned by
+// create, update and patch actions. They may transform the resource or deny
+// access by returning an error.
+type GetCallback interface {
+	AfterGet(res *schema.Resource) error
+}
+
+// ListCallback instances run around list actions. They may amend the params
+// before objects are listed and transform the listed objects. Fields used
+// for sorting must not be modified, as they are encoded in pagination tokens.
+// BeforeList also runs before objects are counted or aggregated.
+type ListCallback interface {
+	BeforeList(params *params.Params) error
+	AfterList(objs []*schema.Object) error
+}
+
 // CreateCallback instances run around create actions.
 type CreateCallback interface {
 	BeforeCreate(payload *schema.Resource) error
@@ -52,6 +73,8 @@ type DeleteAllCallback interface {
 // NoopCallbacks is an embeddable noop callback type.
 type NoopCallbacks struct{}
 
+func (NoopCallbacks) OnGet(_ *Txn, _ riposo.Path) GetCallback             { return nil }
+func (NoopCallbacks) OnList(_ *Txn, _ riposo.Path) ListCallback           { return nil }
 func (NoopCallbacks) OnCreate(_ *Txn, _ riposo.Path) CreateCallback       { return nil }
 func (NoopCallbacks) OnUpdate(_ *Txn, _ riposo.Path) UpdateCallback       { return nil }
 func (NoopCallbacks) OnPatch(_ *Txn, _ riposo.Path) PatchCallback         { return nil }
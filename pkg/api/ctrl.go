@@ -42,6 +42,11 @@ func (c *controller) List(out http.Header, r *http.Request) interface{} {
 		return err
 	}
 
+	// aggregate objects, if requested
+	if params.Aggregate != nil {
+		return c.aggregate(req, params)
+	}
+
 	// paginate objects
 	objs, err := c.paginate(out, req, params, "")
 	if err != nil {
@@ -66,9 +71,7 @@ func (c *controller) Count(out http.Header, r *http.Request) interface{} {
 	}
 
 	// count objects
-	count, err := req.Txn.Store.CountAll(req.Path, storage.CountOptions{
-		Condition: params.Condition,
-	})
+	count, err := c.act.Count(req.Txn, req.Path, params)
 	if err != nil {
 		return err
 	}
@@ -374,12 +377,7 @@ var emptyObjects = []*schema.Object{}
 
 func (c *controller) paginate(out http.Header, req *request, params *params.Params, nonce string) ([]*schema.Object, error) {
 	// TODO: add back pooling?
-	objs, err := req.Txn.Store.ListAll(req.Path, storage.ListOptions{
-		Condition:  params.Condition,
-		Pagination: params.Token.Conditions(),
-		Sort:       params.Sort,
-		Limit:      params.Limit + 1,
-	})
+	objs, err := c.act.List(req.Txn, req.Path, params)
 	if err != nil {
 		return nil, err
 	}
@@ -388,8 +386,13 @@ func (c *controller) paginate(out http.Header, req *request, params *params.Para
 		lastObj := objs[params.Limit-1]
 		objs = objs[:params.Limit]
 
+		// results sorted by relevance are limited to a single page
+		if params.SortsByScore() {
+			return objs, nil
+		}
+
 		// generate next-page URL and set header
-		nurl, err := params.NextPageURL(req.HTTP.URL, nonce, lastObj)
+		nurl, err := params.NextPageURL(req.HTTP.URL, nonce, lastObj, []byte(c.cfg.Pagination.Secret))
 		if err != nil {
 			return nil, err
 		}
@@ -409,6 +412,26 @@ func (c *controller) paginate(out http.Header, req *request, params *params.Para
 	return objs, nil
 }
 
+func (c *controller) aggregate(req *request, params *params.Params) interface{} {
+	groups, err := c.act.Aggregate(req.Txn, req.Path, params)
+	if errors.Is(err, riposo.ErrNotSupported) {
+		return schema.InvalidQuery("_aggregate is not supported by the storage backend")
+	} else if err != nil {
+		return err
+	}
+
+	data := make([]map[string]schema.Value, 0, len(groups))
+	for _, group := range groups {
+		ent := make(map[string]schema.Value, len(group.Keys)+1)
+		for i, field := range params.Aggregate.GroupBy {
+			ent[field] = group.Keys[i]
+		}
+		ent[params.Aggregate.Func.String()] = group.Value
+		data = append(data, ent)
+	}
+	return &schema.Aggregates{Data: data}
+}
+
 func (c *controller) prepareBulkGet(out http.Header, req *request) (*params.Params, error) {
 	// obtain modTime
 	modTime, err := req.Txn.Store.ModTime(req.Path)
@@ -449,7 +472,7 @@ func (c *controller) parseBulkQuery(req *request) (*params.Params, error) {
 	}
 
 	// parse params
-	pms, err := params.Parse(req.HTTP.Form, c.cfg.Pagination.MaxLimit)
+	pms, err := params.Parse(req.HTTP.Form, c.cfg.Pagination.MaxLimit, []byte(c.cfg.Pagination.Secret))
 	if err != nil {
 		return nil, schema.InvalidQuery(err.Error())
 	}
@@ -1,6 +1,7 @@
 package api_test
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,7 @@ import (
 	"github.com/bsm/gomega/types"
 	"github.com/riposo/riposo/pkg/conn/permission"
 	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/params"
 	"github.com/riposo/riposo/pkg/riposo"
 	"github.com/riposo/riposo/pkg/schema"
 
@@ -62,6 +64,7 @@ var _ = Describe("Routes.Resource", func() {
 			Authz: Authz{"resource:create": {"principal:team"}},
 		}
 		cfg.Pagination.MaxLimit = 3
+		cfg.Pagination.Secret = "secret"
 
 		// init callbacks
 		callbacks = new(mockCallbacks)
@@ -78,7 +81,7 @@ var _ = Describe("Routes.Resource", func() {
 	})
 
 	Describe("GET /resources", func() {
-		const nextPageToken = "eyJsYXN0X29iamVjdCI6eyJsYXN0X21vZGlmaWVkIjoxNTE1MTUxNTE1Njc4fX0"
+		const nextPageToken = "eyJsYXN0X29iamVjdCI6eyJpZCI6ImJldGEiLCJsYXN0X21vZGlmaWVkIjoxNTE1MTUxNTE1Njc4fSwicXVlcnkiOiI4al9MemJNWk51TGlPM0ljIn0.nRhiSD8lyN7mFUCj7hu4YloOaxc587Wn1w1hilmapxo"
 		const nextPageURL = "/resources?_limit=2&_sort=last_modified&_token=" + nextPageToken
 
 		BeforeEach(seedThree)
@@ -115,6 +118,125 @@ var _ = Describe("Routes.Resource", func() {
 			}`))
 		})
 
+		It("paginates with ties and descending order", func() {
+			Expect(handle(http.MethodPost, "/resources", `{"data": {"id":"delta", "group": 1}}`).Code).To(Equal(http.StatusCreated))
+			Expect(handle(http.MethodPost, "/resources", `{"data": {"id":"aaa", "group": 1}}`).Code).To(Equal(http.StatusCreated))
+			Expect(handle(http.MethodPost, "/resources", `{"data": {"id":"zzz", "group": 2}}`).Code).To(Equal(http.StatusCreated))
+
+			var ids []string
+			for next := "/resources?_sort=-group&_limit=1"; next != ""; {
+				w := handle(http.MethodGet, next, "")
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				var res schema.Objects
+				Expect(json.Unmarshal(w.Body.Bytes(), &res)).To(Succeed())
+				for _, obj := range res.Data {
+					ids = append(ids, obj.ID)
+				}
+				next = w.Header().Get("Next-Page")
+			}
+			Expect(ids).To(Equal([]string{"alpha", "beta", "gamma", "zzz", "aaa", "delta"}))
+		})
+
+		It("paginates search results", func() {
+			Expect(handle(http.MethodPost, "/resources", `{"data": {"id":"fox1", "text": "quick fox"}}`).Code).To(Equal(http.StatusCreated))
+			Expect(handle(http.MethodPost, "/resources", `{"data": {"id":"dog1", "text": "lazy dog"}}`).Code).To(Equal(http.StatusCreated))
+			Expect(handle(http.MethodPost, "/resources", `{"data": {"id":"fox2", "text": "fox fox"}}`).Code).To(Equal(http.StatusCreated))
+			Expect(handle(http.MethodPost, "/resources", `{"data": {"id":"fox3", "text": "brown fox"}}`).Code).To(Equal(http.StatusCreated))
+
+			var ids []string
+			for next := "/resources?q=fox&_sort=-id&_limit=1"; next != ""; {
+				w := handle(http.MethodGet, next, "")
+				Expect(w.Code).To(Equal(http.StatusOK))
+
+				var res schema.Objects
+				Expect(json.Unmarshal(w.Body.Bytes(), &res)).To(Succeed())
+				for _, obj := range res.Data {
+					ids = append(ids, obj.ID)
+				}
+				next = w.Header().Get("Next-Page")
+			}
+			Expect(ids).To(Equal([]string{"fox3", "fox2", "fox1"}))
+
+			// relevance sorted results are not paginated
+			w := handle(http.MethodGet, "/resources?q=fox&_sort=-_score&_limit=2", "")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Next-Page")).To(BeEmpty())
+			Expect(w.Body.String()).To(HavePrefix(`{"data":[{"id":"fox2"`))
+		})
+
+		It("rejects invalid tokens", func() {
+			Expect(handle(http.MethodGet, strings.Replace(nextPageURL, "_token=eyJs", "_token=eyJt", 1), "")).To(MatchResponse(http.StatusBadRequest, nil, `{
+				"code": 400,
+				"errno": 107,
+				"error": "Invalid parameters",
+				"message": "querystring: _token has an invalid signature",
+				"details": [
+					{"location":"querystring", "description":"_token has an invalid signature"}
+				]
+			}`))
+
+			Expect(handle(http.MethodGet, strings.Replace(nextPageURL, "_sort=last_modified", "_sort=id", 1), "")).To(MatchResponse(http.StatusBadRequest, nil, `{
+				"code": 400,
+				"errno": 107,
+				"error": "Invalid parameters",
+				"message": "querystring: _token was issued for a different query",
+				"details": [
+					{"location":"querystring", "description":"_token was issued for a different query"}
+				]
+			}`))
+		})
+
+		It("filters", func() {
+			Expect(handle(http.MethodGet, "/resources?_sort=id&_filter=or(id(alpha),in(id,gamma,delta))", "")).To(MatchResponse(http.StatusOK, nil, `{
+				"data": [
+					{"id": "alpha", "last_modified": 1515151515677},
+					{"id": "gamma", "last_modified": 1515151515679}
+				]
+			}`))
+
+			Expect(handle(http.MethodGet, "/resources?_filter=or(id(alpha)", "")).To(MatchResponse(http.StatusBadRequest, nil, `{
+				"code": 400,
+				"errno": 107,
+				"error": "Invalid parameters",
+				"message": "querystring: _filter is invalid: unexpected end of expression",
+				"details": [
+					{"location":"querystring", "description":"_filter is invalid: unexpected end of expression"}
+				]
+			}`))
+		})
+
+		It("aggregates", func() {
+			Expect(handle(http.MethodPost, "/resources", `{"data": {"id":"delta", "num": 4}}`).Code).To(Equal(http.StatusCreated))
+			Expect(handle(http.MethodPost, "/resources", `{"data": {"id":"epsilon", "num": 5}}`).Code).To(Equal(http.StatusCreated))
+
+			Expect(handle(http.MethodGet, "/resources?_aggregate=count", "")).To(MatchResponse(http.StatusOK, nil, `{
+				"data": [{"count": 5}]
+			}`))
+			Expect(handle(http.MethodGet, "/resources?_aggregate=sum(num)&_group_by=num&has_num=true", "")).To(MatchResponse(http.StatusOK, nil, `{
+				"data": [{"num": 4, "sum": 4}, {"num": 5, "sum": 5}]
+			}`))
+			Expect(handle(http.MethodGet, "/resources?_aggregate=avg(num)&_filter=or(id(alpha),id(delta),id(epsilon))", "")).To(MatchResponse(http.StatusOK, nil, `{
+				"data": [{"avg": 4.5}]
+			}`))
+
+			// bob has no access to resources
+			txn.User = bob
+			Expect(handle(http.MethodGet, "/resources?_aggregate=count", "")).To(MatchResponse(http.StatusOK, nil, `{
+				"data": [{"count": 0}]
+			}`))
+
+			Expect(handle(http.MethodGet, "/resources?_aggregate=median(num)", "")).To(MatchResponse(http.StatusBadRequest, nil, `{
+				"code": 400,
+				"errno": 107,
+				"error": "Invalid parameters",
+				"message": "querystring: _aggregate has unknown function \"median\"",
+				"details": [
+					{"location":"querystring", "description":"_aggregate has unknown function \"median\""}
+				]
+			}`))
+		})
+
 		It("supports conditional rendering", func() {
 			// If-None-Match
 			r := newRequest(http.MethodGet, "/resources", ``)
@@ -156,10 +278,17 @@ var _ = Describe("Routes.Resource", func() {
 				"Total-Records": "3",
 			}, ""))
 		})
+
+		It("filters", func() {
+			Expect(handle(http.MethodHead, "/resources?_filter=or(id(alpha),id(beta))", ``)).To(MatchResponse(http.StatusOK, map[string]string{
+				"Total-Objects": "2",
+				"Total-Records": "2",
+			}, ""))
+		})
 	})
 
 	Describe("DELETE /resources", func() {
-		const nextPageToken = "eyJub25jZSI6InBhZ2luYXRpb24tdG9rZW4tRVBSLklEIiwibGFzdF9vYmplY3QiOnsibGFzdF9tb2RpZmllZCI6MTUxNTE1MTUxNTY3OH19"
+		const nextPageToken = "eyJub25jZSI6InBhZ2luYXRpb24tdG9rZW4tRVBSLklEIiwibGFzdF9vYmplY3QiOnsiaWQiOiJiZXRhIiwibGFzdF9tb2RpZmllZCI6MTUxNTE1MTUxNTY3OH0sInF1ZXJ5IjoiOGpfTHpiTVpOdUxpTzNJYyJ9.4yhs2rX5tAKmcWIpHKJ38o8VmrJCSHMkohhFcqNaA3w"
 		const nextPageURL = "/resources?_limit=2&_sort=last_modified&_token=" + nextPageToken
 
 		BeforeEach(seedThree)
@@ -188,8 +317,8 @@ var _ = Describe("Routes.Resource", func() {
 			}`))
 			Expect(handle(http.MethodGet, "/resources/alpha", ``).Code).To(Equal(http.StatusForbidden))
 			Expect(callbacks).To(Equal(&mockCallbacks{
-				Paths: []string{"/resources/*"},
-				Calls: []string{"BeforeDeleteAll", "AfterDeleteAll"},
+				Paths: []string{"/resources/alpha", "/resources/*", "/resources/*"},
+				Calls: []string{"AfterGet", "BeforeList", "AfterList", "BeforeDeleteAll", "AfterDeleteAll"},
 			}))
 		})
 
@@ -223,6 +352,18 @@ var _ = Describe("Routes.Resource", func() {
 			}`))
 		})
 
+		It("filters", func() {
+			Expect(handle(http.MethodDelete, "/resources?_sort=id&_filter=or(id(alpha),id(gamma))", ``)).To(MatchResponse(http.StatusOK, nil, `{
+				"data": [
+					{"id": "alpha", "last_modified": 1515151515681, "deleted": true},
+					{"id": "gamma", "last_modified": 1515151515681, "deleted": true}
+				]
+			}`))
+			Expect(handle(http.MethodHead, "/resources", ``)).To(MatchResponse(http.StatusOK, map[string]string{
+				"Total-Objects": "1",
+			}, ""))
+		})
+
 		It("supports conditional rendering", func() {
 			r := newRequest(http.MethodDelete, "/resources", ``)
 			r.Header.Set("If-Match", `"1616161616000"`)
@@ -412,8 +553,8 @@ var _ = Describe("Routes.Resource", func() {
 			}`))
 
 			Expect(callbacks).To(Equal(&mockCallbacks{
-				Paths: []string{"/resources/*"},
-				Calls: []string{"BeforeCreate", "AfterCreate"},
+				Paths: []string{"/resources/*", "/resources/EPR.ID", "/resources/EPR.ID"},
+				Calls: []string{"BeforeCreate", "AfterCreate", "AfterGet", "AfterGet"},
 			}))
 		})
 
@@ -488,8 +629,8 @@ var _ = Describe("Routes.Resource", func() {
 			}`))
 
 			Expect(callbacks).To(Equal(&mockCallbacks{
-				Paths: []string{"/resources/*"},
-				Calls: []string{"BeforeCreate"},
+				Paths: []string{"/resources/*", "/resources/alpha"},
+				Calls: []string{"BeforeCreate", "AfterGet"},
 			}))
 		})
 
@@ -559,8 +700,8 @@ var _ = Describe("Routes.Resource", func() {
 			}`))
 
 			Expect(callbacks).To(Equal(&mockCallbacks{
-				Paths: []string{"/resources/alpha"},
-				Calls: []string{"BeforeUpdate", "AfterUpdate"},
+				Paths: []string{"/resources/alpha", "/resources/alpha"},
+				Calls: []string{"BeforeUpdate", "AfterUpdate", "AfterGet"},
 			}))
 		})
 
@@ -612,8 +753,8 @@ var _ = Describe("Routes.Resource", func() {
 			}`))
 
 			Expect(callbacks).To(Equal(&mockCallbacks{
-				Paths: []string{"/resources/*"},
-				Calls: []string{"BeforeCreate", "AfterCreate"},
+				Paths: []string{"/resources/*", "/resources/beta", "/resources/beta"},
+				Calls: []string{"BeforeCreate", "AfterCreate", "AfterGet", "AfterGet"},
 			}))
 		})
 
@@ -742,8 +883,8 @@ var _ = Describe("Routes.Resource", func() {
 			}`))
 
 			Expect(callbacks).To(Equal(&mockCallbacks{
-				Paths: []string{"/resources/alpha"},
-				Calls: []string{"BeforePatch", "AfterPatch"},
+				Paths: []string{"/resources/alpha", "/resources/alpha"},
+				Calls: []string{"BeforePatch", "AfterPatch", "AfterGet"},
 			}))
 		})
 
@@ -877,8 +1018,8 @@ var _ = Describe("Routes.Resource", func() {
 			}`))
 			Expect(handle(http.MethodGet, "/resources/alpha", ``).Code).To(Equal(http.StatusForbidden))
 			Expect(callbacks).To(Equal(&mockCallbacks{
-				Paths: []string{"/resources/alpha"},
-				Calls: []string{"BeforeDelete", "AfterDelete"},
+				Paths: []string{"/resources/alpha", "/resources/alpha"},
+				Calls: []string{"AfterGet", "BeforeDelete", "AfterDelete"},
 			}))
 		})
 
@@ -1218,6 +1359,14 @@ type mockCallbacks struct {
 
 func (m *mockCallbacks) Reset()                   { *m = mockCallbacks{} }
 func (m *mockCallbacks) Match(_ riposo.Path) bool { return true }
+func (m *mockCallbacks) OnGet(_ *Txn, path riposo.Path) GetCallback {
+	m.Paths = append(m.Paths, string(path))
+	return m
+}
+func (m *mockCallbacks) OnList(_ *Txn, path riposo.Path) ListCallback {
+	m.Paths = append(m.Paths, string(path))
+	return m
+}
 func (m *mockCallbacks) OnCreate(_ *Txn, path riposo.Path) CreateCallback {
 	m.Paths = append(m.Paths, string(path))
 	return m
@@ -1239,6 +1388,18 @@ func (m *mockCallbacks) OnDeleteAll(_ *Txn, path riposo.Path) DeleteAllCallback
 	return m
 }
 
+func (m *mockCallbacks) AfterGet(res *schema.Resource) error {
+	m.Calls = append(m.Calls, "AfterGet")
+	return nil
+}
+func (m *mockCallbacks) BeforeList(params *params.Params) error {
+	m.Calls = append(m.Calls, "BeforeList")
+	return nil
+}
+func (m *mockCallbacks) AfterList(objs []*schema.Object) error {
+	m.Calls = append(m.Calls, "AfterList")
+	return nil
+}
 func (m *mockCallbacks) BeforeCreate(payload *schema.Resource) error {
 	m.Calls = append(m.Calls, "BeforeCreate")
 	return nil
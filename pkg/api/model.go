@@ -1,6 +1,7 @@
 package api
 
 import (
+	"github.com/riposo/riposo/pkg/conn/storage"
 	"github.com/riposo/riposo/pkg/riposo"
 	"github.com/riposo/riposo/pkg/schema"
 )
@@ -21,6 +22,28 @@ type Model interface {
 	DeleteAll(txn *Txn, path riposo.Path, objs []*schema.Object) (riposo.Epoch, []riposo.Path, error)
 }
 
+// Lister is an optional Model interface. Models which do not implement it
+// list objects via DefaultModel.
+type Lister interface {
+	// List retrieves matching objects.
+	List(txn *Txn, path riposo.Path, opt storage.ListOptions) ([]*schema.Object, error)
+}
+
+// Counter is an optional Model interface. Models which do not implement it
+// count objects via DefaultModel.
+type Counter interface {
+	// Count counts matching objects.
+	Count(txn *Txn, path riposo.Path, opt storage.CountOptions) (int64, error)
+}
+
+// Aggregator is an optional Model interface. Models which do not implement it
+// aggregate objects via DefaultModel.
+type Aggregator interface {
+	// Aggregate aggregates matching objects. It may return
+	// riposo.ErrNotSupported.
+	Aggregate(txn *Txn, path riposo.Path, opt storage.AggregateOptions) ([]storage.AggregateGroup, error)
+}
+
 // DefaultModel is an embeddable default model type.
 type DefaultModel struct{}
 
@@ -40,6 +63,22 @@ func (DefaultModel) Get(txn *Txn, path riposo.Path) (*schema.Resource, error) {
 	return &schema.Resource{Data: obj, Permissions: pms}, nil
 }
 
+func (DefaultModel) List(txn *Txn, path riposo.Path, opt storage.ListOptions) ([]*schema.Object, error) {
+	return txn.Store.ListAll(path, opt)
+}
+
+func (DefaultModel) Count(txn *Txn, path riposo.Path, opt storage.CountOptions) (int64, error) {
+	return txn.Store.CountAll(path, opt)
+}
+
+func (DefaultModel) Aggregate(txn *Txn, path riposo.Path, opt storage.AggregateOptions) ([]storage.AggregateGroup, error) {
+	ag, ok := txn.Store.(storage.Aggregator)
+	if !ok {
+		return nil, riposo.ErrNotSupported
+	}
+	return ag.Aggregate(path, opt)
+}
+
 func (DefaultModel) Create(txn *Txn, path riposo.Path, payload *schema.Resource) error {
 	// create new object
 	err := txn.Store.Create(path, payload.Data)
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -36,7 +37,13 @@ func Render(w http.ResponseWriter, v interface{}) {
 // renderError responds with an error.
 func renderError(w http.ResponseWriter, err error) {
 	resp := new(schema.Error)
-	if !errors.As(err, &resp) {
+	switch {
+	case errors.As(err, &resp):
+	case errors.Is(err, context.DeadlineExceeded):
+		resp = schema.RequestTimeout
+	case errors.Is(err, context.Canceled):
+		resp = schema.ClientClosedRequest
+	default:
 		resp = schema.InternalError(err)
 	}
 
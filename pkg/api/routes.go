@@ -2,9 +2,11 @@ package api
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/riposo/riposo/pkg/riposo"
 )
 
 type muxKey struct{}
@@ -25,6 +27,11 @@ type Routes struct {
 	mux *chi.Mux
 	cbs []Callbacks
 	cfg *Config
+
+	resources []string
+	actions   map[string]Actions
+	handlers  []RouteInfo
+	ops       map[string]*Operation
 }
 
 // NewRoutes inits a new routes instance.
@@ -46,11 +53,43 @@ func (r *Routes) Use(middleware Middleware) {
 // Method registers a new HTTP handler under a particular HTTP method.
 func (r *Routes) Method(method, pattern string, handler http.Handler) {
 	r.mux.Method(method, pattern, handler)
+	r.handlers = append(r.handlers, RouteInfo{Method: strings.ToUpper(method), Pattern: pattern})
 }
 
 // Handle registers a new API handler.
 func (r *Routes) Handle(pattern string, handler http.Handler) {
 	r.mux.Handle(pattern, handler)
+	r.handlers = append(r.handlers, RouteInfo{Pattern: pattern})
+}
+
+// Describe attaches operation metadata to a route. Metadata is used to
+// generate API documentation.
+func (r *Routes) Describe(method, pattern string, op *Operation) {
+	if r.ops == nil {
+		r.ops = make(map[string]*Operation)
+	}
+	r.ops[strings.ToUpper(method)+" "+pattern] = op
+}
+
+// Operation returns the operation metadata attached to a route (if any).
+func (r *Routes) Operation(method, pattern string) *Operation {
+	return r.ops[strings.ToUpper(method)+" "+pattern]
+}
+
+// Resources returns the prefixes of all registered resources.
+func (r *Routes) Resources() []string {
+	return r.resources
+}
+
+// Actions returns the actions of the resource registered under prefix or nil
+// if no such resource exists.
+func (r *Routes) Actions(prefix string) Actions {
+	return r.actions[prefix]
+}
+
+// Handlers returns all routes registered via Method or Handle.
+func (r *Routes) Handlers() []RouteInfo {
+	return r.handlers
 }
 
 // Callbacks registers resource callbacks.
@@ -58,6 +97,53 @@ func (r *Routes) Callbacks(callbacks Callbacks) {
 	r.cbs = append(r.cbs, callbacks)
 }
 
+// CheckPermission verifies that the current user has any of the given
+// permissions on path or any of its parents.
+func (r *Routes) CheckPermission(txn *Txn, path riposo.Path, perms ...string) error {
+	c := &controller{cfg: r.cfg}
+	return c.checkPermission(txn, path, perms...)
+}
+
+// Explain explains the read, write and create access of principals to path,
+// mirroring the checks performed by resource handlers.
+func (r *Routes) Explain(txn *Txn, path riposo.Path, principals []string) (map[string]*Decision, error) {
+	ents := poolEntSlice()
+	defer ents.Release()
+
+	decisions := make(map[string]*Decision, 3)
+	for _, access := range []string{"read", "write", "create"} {
+		ents.Reset()
+
+		switch access {
+		case "read":
+			path.Traverse(func(part riposo.Path) bool {
+				ents.Append("read", part)
+				ents.Append("write", part)
+				return true
+			})
+		case "write":
+			path.Traverse(func(part riposo.Path) bool {
+				ents.Append("write", part)
+				return true
+			})
+		case "create":
+			parentPath := path.Parent()
+			ents.Append(path.ResourceName()+":create", parentPath)
+			parentPath.Traverse(func(part riposo.Path) bool {
+				ents.Append("write", part)
+				return true
+			})
+		}
+
+		decision, err := r.cfg.Authz.Explain(txn.Perms, principals, ents.S)
+		if err != nil {
+			return nil, err
+		}
+		decisions[access] = decision
+	}
+	return decisions, nil
+}
+
 // Resource registers a new resource under a prefix.
 func (r *Routes) Resource(prefix string, model Model) {
 	if model == nil {
@@ -69,6 +155,11 @@ func (r *Routes) Resource(prefix string, model Model) {
 		cfg: r.cfg,
 	}
 
+	if r.actions == nil {
+		r.actions = make(map[string]Actions)
+	}
+	r.actions[prefix] = c.act
+	r.resources = append(r.resources, prefix)
 	r.mux.Route(prefix, func(ns chi.Router) {
 		ns.Method(http.MethodGet, "/", HandlerFunc(c.List))
 		ns.Method(http.MethodHead, "/", HandlerFunc(c.Count))
@@ -86,3 +177,21 @@ func (r *Routes) Resource(prefix string, model Model) {
 func (r *Routes) Mux() http.Handler {
 	return r.mux
 }
+
+// RouteInfo contains route information.
+type RouteInfo struct {
+	Method  string // blank if route accepts any method
+	Pattern string
+}
+
+// Operation contains descriptive metadata of an API operation.
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+
+	// Extra contains additional OpenAPI operation fields,
+	// e.g. "parameters", "requestBody" or "responses".
+	Extra map[string]interface{}
+}
@@ -36,22 +36,26 @@ type Txn struct {
 	Helpers riposo.Helpers
 	User    *User
 	Data    map[string]interface{}
+
+	hooks txnHooks
 }
 
-// NewTxn inits a new transaction.
-func NewTxn(ctx context.Context, cns *conn.Set, hlp riposo.Helpers) (*Txn, error) {
-	store, err := cns.Store().Begin(ctx)
+// NewTxn inits a new transaction. Options are applied to storage and
+// permission transactions only, cache transactions are always writable. Options
+// may be nil.
+func NewTxn(ctx context.Context, cns *conn.Set, hlp riposo.Helpers, opt *riposo.TxOptions) (*Txn, error) {
+	store, err := cns.Store().Begin(ctx, opt)
 	if err != nil {
 		return nil, err
 	}
 
-	perms, err := cns.Perms().Begin(ctx)
+	perms, err := cns.Perms().Begin(ctx, opt)
 	if err != nil {
 		_ = store.Rollback()
 		return nil, err
 	}
 
-	cache, err := cns.Cache().Begin(ctx)
+	cache, err := cns.Cache().Begin(ctx, nil)
 	if err != nil {
 		_ = store.Rollback()
 		_ = perms.Rollback()
@@ -61,7 +65,7 @@ func NewTxn(ctx context.Context, cns *conn.Set, hlp riposo.Helpers) (*Txn, error
 	return &Txn{
 		Context: ctx,
 		Store:   store,
-		Perms:   perms,
+		Perms:   newCachedPerms(perms),
 		Cache:   cache,
 		Helpers: hlp,
 		Data:    make(map[string]interface{}),
@@ -69,20 +73,173 @@ func NewTxn(ctx context.Context, cns *conn.Set, hlp riposo.Helpers) (*Txn, error
 	}, nil
 }
 
-// Commit is used internally to commit all transactions.
+// OnBeforeCommit registers a hook which runs before the transactions are
+// committed. Transactions are rolled back if a hook fails. Unlike other hooks,
+// these are kept when transactions are rolled back to a savepoint.
+func (t *Txn) OnBeforeCommit(fn func() error) {
+	t.hooks.before = append(t.hooks.before, fn)
+}
+
+// OnCommit registers a hook which runs once the storage transaction has been
+// committed, even if permission or cache transactions fail to commit
+// afterwards. Hooks registered after a savepoint are discarded when the
+// transactions are rolled back to it.
+func (t *Txn) OnCommit(fn func()) {
+	t.hooks.commit = append(t.hooks.commit, fn)
+}
+
+// OnRollback registers a hook which runs once all transactions have been
+// rolled back or the storage transaction failed to commit. Hooks registered
+// after a savepoint also run when the transactions are rolled back to it, but
+// only once the transactions are finalized.
+func (t *Txn) OnRollback(fn func()) {
+	t.hooks.rollback = append(t.hooks.rollback, fn)
+}
+
+// Commit is used internally to commit all transactions. All transactions are
+// rolled back if the context is done, once started, commits complete
+// regardless of the context. The storage transaction is committed first,
+// permission and cache transactions are only committed when it succeeds.
+// Commits are not atomic across backends, commit hooks run as soon as storage
+// writes are durable.
 func (t *Txn) Commit() error {
-	return multierr.Combine(
-		t.Store.Commit(),
+	if err := t.Err(); err != nil {
+		_ = t.Rollback()
+		return err
+	}
+
+	for _, fn := range t.hooks.before {
+		if err := fn(); err != nil {
+			_ = t.Rollback()
+			return err
+		}
+	}
+
+	if err := t.Store.Commit(); err != nil {
+		_ = t.Perms.Rollback()
+		_ = t.Cache.Rollback()
+		t.hooks.finalize(false)
+		return err
+	}
+
+	err := multierr.Combine(
 		t.Perms.Commit(),
 		t.Cache.Commit(),
 	)
+	t.hooks.finalize(true)
+	return err
 }
 
 // Rollback is used internally to rollback all transactions.
 func (t *Txn) Rollback() error {
-	return multierr.Combine(
+	err := multierr.Combine(
 		t.Store.Rollback(),
 		t.Perms.Rollback(),
 		t.Cache.Rollback(),
 	)
+	t.hooks.finalize(false)
+	return err
+}
+
+// Savepoint creates a named savepoint across all transactions. On failure,
+// savepoints which have already been created are released again.
+func (t *Txn) Savepoint(name string) error {
+	if err := t.Store.Savepoint(name); err != nil {
+		return err
+	}
+	if err := t.Perms.Savepoint(name); err != nil {
+		_ = t.Store.Release(name)
+		return err
+	}
+	if err := t.Cache.Savepoint(name); err != nil {
+		_ = t.Store.Release(name)
+		_ = t.Perms.Release(name)
+		return err
+	}
+	t.hooks.savepoint(name)
+	return nil
+}
+
+// RollbackTo rolls back all transactions to a named savepoint.
+func (t *Txn) RollbackTo(name string) error {
+	t.hooks.rollbackTo(name)
+	return multierr.Combine(
+		t.Store.RollbackTo(name),
+		t.Perms.RollbackTo(name),
+		t.Cache.RollbackTo(name),
+	)
+}
+
+// Release releases a named savepoint across all transactions.
+func (t *Txn) Release(name string) error {
+	t.hooks.release(name)
+	return multierr.Combine(
+		t.Store.Release(name),
+		t.Perms.Release(name),
+		t.Cache.Release(name),
+	)
+}
+
+// --------------------------------------------------------------------
+
+type txnHooks struct {
+	before     []func() error
+	commit     []func()
+	rollback   []func()
+	discarded  []func() // rollback hooks of savepoints which were rolled back
+	savepoints []hooksSavepoint
+}
+
+type hooksSavepoint struct {
+	name             string
+	commit, rollback int
+}
+
+func (h *txnHooks) savepoint(name string) {
+	h.savepoints = append(h.savepoints, hooksSavepoint{
+		name:     name,
+		commit:   len(h.commit),
+		rollback: len(h.rollback),
+	})
+}
+
+func (h *txnHooks) rollbackTo(name string) {
+	if i := h.find(name); i > -1 {
+		sp := h.savepoints[i]
+		h.discarded = append(h.discarded, h.rollback[sp.rollback:]...)
+		h.commit = h.commit[:sp.commit]
+		h.rollback = h.rollback[:sp.rollback]
+		h.savepoints = h.savepoints[:i+1]
+	}
+}
+
+func (h *txnHooks) release(name string) {
+	if i := h.find(name); i > -1 {
+		h.savepoints = h.savepoints[:i]
+	}
+}
+
+func (h *txnHooks) find(name string) int {
+	for i := len(h.savepoints) - 1; i > -1; i-- {
+		if h.savepoints[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (h *txnHooks) finalize(committed bool) {
+	hooks := h.rollback
+	if committed {
+		hooks = h.commit
+	}
+	discarded := h.discarded
+	*h = txnHooks{}
+
+	for _, fn := range hooks {
+		fn()
+	}
+	for _, fn := range discarded {
+		fn()
+	}
 }
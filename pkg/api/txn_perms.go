@@ -0,0 +1,188 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/riposo/riposo/pkg/conn/permission"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+)
+
+// cachedPerms caches principal and ACE lookups for the lifetime of a
+// transaction. Cached results are discarded on every write and whenever the
+// transaction is rolled back.
+type cachedPerms struct {
+	permission.Transaction
+	users      map[string][]string
+	aces       map[string][]string
+	accessible map[string][]riposo.Path
+}
+
+func newCachedPerms(tx permission.Transaction) *cachedPerms {
+	return &cachedPerms{Transaction: tx}
+}
+
+func (p *cachedPerms) reset() {
+	p.users = nil
+	p.aces = nil
+	p.accessible = nil
+}
+
+// GetUserPrincipals implements permission.Transaction interface.
+func (p *cachedPerms) GetUserPrincipals(userID string) ([]string, error) {
+	if principals, ok := p.users[userID]; ok {
+		return append([]string(nil), principals...), nil
+	}
+
+	principals, err := p.Transaction.GetUserPrincipals(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.users == nil {
+		p.users = make(map[string][]string)
+	}
+	p.users[userID] = append([]string(nil), principals...)
+	return principals, nil
+}
+
+// GetACEPrincipals implements permission.Transaction interface.
+func (p *cachedPerms) GetACEPrincipals(ent permission.ACE) ([]string, error) {
+	return p.cachedACEs([]permission.ACE{ent}, func() ([]string, error) {
+		return p.Transaction.GetACEPrincipals(ent)
+	})
+}
+
+// GetAllACEPrincipals implements permission.Transaction interface.
+func (p *cachedPerms) GetAllACEPrincipals(ents []permission.ACE) ([]string, error) {
+	// distinguish from single ACE lookups
+	return p.cachedACEs(append([]permission.ACE{{}}, ents...), func() ([]string, error) {
+		return p.Transaction.GetAllACEPrincipals(ents)
+	})
+}
+
+func (p *cachedPerms) cachedACEs(ents []permission.ACE, fetch func() ([]string, error)) ([]string, error) {
+	key := aceKey(nil, ents)
+	if principals, ok := p.aces[key]; ok {
+		return append([]string(nil), principals...), nil
+	}
+
+	principals, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.aces == nil {
+		p.aces = make(map[string][]string)
+	}
+	p.aces[key] = append([]string(nil), principals...)
+	return principals, nil
+}
+
+// GetAccessiblePaths implements permission.Transaction interface.
+func (p *cachedPerms) GetAccessiblePaths(dst []riposo.Path, principals []string, ents []permission.ACE) ([]riposo.Path, error) {
+	key := aceKey(principals, ents)
+	if paths, ok := p.accessible[key]; ok {
+		return append(dst, paths...), nil
+	}
+
+	paths, err := p.Transaction.GetAccessiblePaths(nil, principals, ents)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.accessible == nil {
+		p.accessible = make(map[string][]riposo.Path)
+	}
+	p.accessible[key] = paths
+	return append(dst, paths...), nil
+}
+
+// Commit implements permission.Transaction interface.
+func (p *cachedPerms) Commit() error {
+	p.reset()
+	return p.Transaction.Commit()
+}
+
+// Rollback implements permission.Transaction interface.
+func (p *cachedPerms) Rollback() error {
+	p.reset()
+	return p.Transaction.Rollback()
+}
+
+// RollbackTo implements permission.Transaction interface.
+func (p *cachedPerms) RollbackTo(name string) error {
+	p.reset()
+	return p.Transaction.RollbackTo(name)
+}
+
+// Flush implements permission.Transaction interface.
+func (p *cachedPerms) Flush() error {
+	p.reset()
+	return p.Transaction.Flush()
+}
+
+// AddUserPrincipal implements permission.Transaction interface.
+func (p *cachedPerms) AddUserPrincipal(principal string, userIDs []string) error {
+	p.reset()
+	return p.Transaction.AddUserPrincipal(principal, userIDs)
+}
+
+// RemoveUserPrincipal implements permission.Transaction interface.
+func (p *cachedPerms) RemoveUserPrincipal(principal string, userIDs []string) error {
+	p.reset()
+	return p.Transaction.RemoveUserPrincipal(principal, userIDs)
+}
+
+// PurgeUserPrincipals implements permission.Transaction interface.
+func (p *cachedPerms) PurgeUserPrincipals(principals []string) error {
+	p.reset()
+	return p.Transaction.PurgeUserPrincipals(principals)
+}
+
+// AddACEPrincipal implements permission.Transaction interface.
+func (p *cachedPerms) AddACEPrincipal(principal string, ent permission.ACE) error {
+	p.reset()
+	return p.Transaction.AddACEPrincipal(principal, ent)
+}
+
+// RemoveACEPrincipal implements permission.Transaction interface.
+func (p *cachedPerms) RemoveACEPrincipal(principal string, ent permission.ACE) error {
+	p.reset()
+	return p.Transaction.RemoveACEPrincipal(principal, ent)
+}
+
+// CreatePermissions implements permission.Transaction interface.
+func (p *cachedPerms) CreatePermissions(path riposo.Path, set schema.PermissionSet) error {
+	p.reset()
+	return p.Transaction.CreatePermissions(path, set)
+}
+
+// MergePermissions implements permission.Transaction interface.
+func (p *cachedPerms) MergePermissions(path riposo.Path, set schema.PermissionSet) error {
+	p.reset()
+	return p.Transaction.MergePermissions(path, set)
+}
+
+// DeletePermissions implements permission.Transaction interface.
+func (p *cachedPerms) DeletePermissions(paths []riposo.Path) error {
+	p.reset()
+	return p.Transaction.DeletePermissions(paths)
+}
+
+// aceKey returns a cache key for principals and ents.
+func aceKey(principals []string, ents []permission.ACE) string {
+	var sb strings.Builder
+	for _, principal := range principals {
+		sb.WriteString(principal)
+		sb.WriteByte(0)
+	}
+	sb.WriteByte(1)
+	for _, ent := range ents {
+		sb.WriteString(ent.Perm)
+		sb.WriteByte(0)
+		sb.WriteString(string(ent.Path))
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
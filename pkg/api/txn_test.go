@@ -0,0 +1,307 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+
+	"github.com/riposo/riposo/pkg/conn"
+	"github.com/riposo/riposo/pkg/conn/permission"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+
+	memc "github.com/riposo/riposo/internal/conn/memory/cache"
+	memp "github.com/riposo/riposo/internal/conn/memory/permission"
+	mems "github.com/riposo/riposo/internal/conn/memory/storage"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/api"
+)
+
+var _ = Describe("Txn", func() {
+	var subject *Txn
+	var backend *countingBackend
+
+	ACE := func(perm string, path riposo.Path) permission.ACE {
+		return permission.ACE{Perm: perm, Path: path}
+	}
+
+	BeforeEach(func() {
+		hlp := mock.Helpers()
+		backend = &countingBackend{Backend: memp.New()}
+
+		var err error
+		subject, err = NewTxn(context.Background(), conn.Use(
+			mems.New(mock.Clock(), hlp),
+			backend,
+			memc.New(),
+		), hlp, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(subject.Perms.AddUserPrincipal("team:a", []string{"alice"})).To(Succeed())
+		Expect(subject.Perms.CreatePermissions("/buckets/foo", schema.PermissionSet{
+			"write": {"alice"},
+			"read":  {"team:a"},
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(subject.Rollback()).To(Succeed())
+	})
+
+	It("caches permission lookups", func() {
+		ents := []permission.ACE{ACE("read", "/buckets/foo"), ACE("write", "/buckets/foo")}
+		for i := 0; i < 3; i++ {
+			Expect(subject.Perms.GetUserPrincipals("alice")).To(ConsistOf("alice", "team:a", riposo.Authenticated, riposo.Everyone))
+			Expect(subject.Perms.GetACEPrincipals(ACE("write", "/buckets/foo"))).To(ConsistOf("alice"))
+			Expect(subject.Perms.GetAllACEPrincipals(ents)).To(ConsistOf("alice", "team:a"))
+			Expect(subject.Perms.GetAccessiblePaths(nil, []string{"alice"}, []permission.ACE{
+				ACE("write", "/buckets/*"),
+			})).To(ConsistOf(riposo.Path("/buckets/foo")))
+		}
+		Expect(backend.tx.lookups).To(Equal(4))
+	})
+
+	It("invalidates on writes", func() {
+		Expect(subject.Perms.GetUserPrincipals("alice")).To(ConsistOf("alice", "team:a", riposo.Authenticated, riposo.Everyone))
+		Expect(subject.Perms.GetACEPrincipals(ACE("write", "/buckets/foo"))).To(ConsistOf("alice"))
+
+		Expect(subject.Perms.AddUserPrincipal("team:b", []string{"alice"})).To(Succeed())
+		Expect(subject.Perms.GetUserPrincipals("alice")).To(ConsistOf("alice", "team:a", "team:b", riposo.Authenticated, riposo.Everyone))
+
+		Expect(subject.Perms.MergePermissions("/buckets/foo", schema.PermissionSet{
+			"write": {"bob"},
+		})).To(Succeed())
+		Expect(subject.Perms.GetACEPrincipals(ACE("write", "/buckets/foo"))).To(ConsistOf("bob"))
+
+		Expect(subject.Perms.DeletePermissions([]riposo.Path{"/buckets/foo"})).To(Succeed())
+		Expect(subject.Perms.GetACEPrincipals(ACE("write", "/buckets/foo"))).To(BeEmpty())
+		Expect(backend.tx.lookups).To(Equal(5))
+	})
+
+	It("invalidates on rollback to savepoint", func() {
+		Expect(subject.Savepoint("sp")).To(Succeed())
+		Expect(subject.Perms.RemoveUserPrincipal("team:a", []string{"alice"})).To(Succeed())
+		Expect(subject.Perms.GetUserPrincipals("alice")).To(ConsistOf("alice", riposo.Authenticated, riposo.Everyone))
+
+		Expect(subject.RollbackTo("sp")).To(Succeed())
+		Expect(subject.Perms.GetUserPrincipals("alice")).To(ConsistOf("alice", "team:a", riposo.Authenticated, riposo.Everyone))
+	})
+
+	It("returns copies", func() {
+		principals, err := subject.Perms.GetUserPrincipals("alice")
+		Expect(err).NotTo(HaveOccurred())
+		principals[0] = "mallory"
+
+		Expect(subject.Perms.GetUserPrincipals("alice")).To(ConsistOf("alice", "team:a", riposo.Authenticated, riposo.Everyone))
+	})
+})
+
+var _ = Describe("Txn hooks", func() {
+	var subject *Txn
+
+	BeforeEach(func() {
+		subject = mock.Txn()
+	})
+
+	It("runs hooks once finalized", func() {
+		var events []string
+		record := func(event string) func() {
+			return func() { events = append(events, event) }
+		}
+
+		subject.OnCommit(record("commit:a"))
+		subject.OnRollback(record("rollback:a"))
+		Expect(subject.Savepoint("sp")).To(Succeed())
+		subject.OnCommit(record("commit:b"))
+		subject.OnRollback(record("rollback:b"))
+		Expect(subject.RollbackTo("sp")).To(Succeed())
+		subject.OnCommit(record("commit:c"))
+		Expect(subject.Release("sp")).To(Succeed())
+		Expect(events).To(BeEmpty())
+
+		Expect(subject.Commit()).To(Succeed())
+		Expect(events).To(Equal([]string{"commit:a", "commit:c", "rollback:b"}))
+
+		_ = subject.Rollback()
+		Expect(events).To(HaveLen(3))
+	})
+
+	It("runs rollback hooks on rollback", func() {
+		var events []string
+		subject.OnCommit(func() { events = append(events, "commit") })
+		subject.OnRollback(func() { events = append(events, "rollback") })
+
+		Expect(subject.Rollback()).To(Succeed())
+		Expect(events).To(Equal([]string{"rollback"}))
+	})
+})
+
+var _ = Describe("Txn commit", func() {
+	It("rolls back when the context is done", func() {
+		hlp := mock.Helpers()
+		cns := mock.Conns(hlp)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		subject, err := NewTxn(ctx, cns, hlp, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.Store.Create("/buckets/*", &schema.Object{ID: "foo"})).To(Succeed())
+		Expect(subject.Perms.AddUserPrincipal("team:a", []string{"alice"})).To(Succeed())
+
+		cancel()
+		Expect(subject.Commit()).To(MatchError(context.Canceled))
+
+		txn, err := NewTxn(context.Background(), cns, hlp, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer txn.Rollback()
+
+		Expect(txn.Store.Exists("/buckets/foo")).To(BeFalse())
+		Expect(txn.Perms.GetUserPrincipals("alice")).NotTo(ContainElement("team:a"))
+	})
+
+	It("runs before-commit hooks", func() {
+		hlp := mock.Helpers()
+		cns := mock.Conns(hlp)
+
+		subject, err := NewTxn(context.Background(), cns, hlp, nil)
+		Expect(err).NotTo(HaveOccurred())
+		subject.OnBeforeCommit(func() error {
+			return subject.Store.Create("/buckets/*", &schema.Object{ID: "foo"})
+		})
+		Expect(subject.Commit()).To(Succeed())
+
+		subject, err = NewTxn(context.Background(), cns, hlp, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(subject.Store.Create("/buckets/*", &schema.Object{ID: "bar"})).To(Succeed())
+		subject.OnBeforeCommit(func() error { return errors.New("hook failed") })
+		Expect(subject.Commit()).To(MatchError("hook failed"))
+
+		txn, err := NewTxn(context.Background(), cns, hlp, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer txn.Rollback()
+
+		Expect(txn.Store.Exists("/buckets/foo")).To(BeTrue())
+		Expect(txn.Store.Exists("/buckets/bar")).To(BeFalse())
+	})
+
+	It("runs hooks depending on the storage commit", func() {
+		hlp := mock.Helpers()
+		begin := func(store storage.Backend, perms permission.Backend) (*Txn, *[]string) {
+			txn, err := NewTxn(context.Background(), conn.Use(store, perms, memc.New()), hlp, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			var events []string
+			txn.OnCommit(func() { events = append(events, "commit") })
+			txn.OnRollback(func() { events = append(events, "rollback") })
+			return txn, &events
+		}
+
+		// storage committed, permissions failed
+		txn, events := begin(mems.New(mock.Clock(), hlp), failingPerms{Backend: memp.New()})
+		Expect(txn.Commit()).To(MatchError("commit failed"))
+		Expect(*events).To(Equal([]string{"commit"}))
+
+		// storage failed
+		txn, events = begin(failingStore{Backend: mems.New(mock.Clock(), hlp)}, memp.New())
+		Expect(txn.Commit()).To(MatchError("commit failed"))
+		Expect(*events).To(Equal([]string{"rollback"}))
+	})
+
+	It("releases partially created savepoints", func() {
+		hlp := mock.Helpers()
+		txn, err := NewTxn(context.Background(), conn.Use(mems.New(mock.Clock(), hlp), failingPerms{Backend: memp.New()}, memc.New()), hlp, nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer txn.Rollback()
+
+		Expect(txn.Savepoint("broken")).To(MatchError("savepoint failed"))
+		Expect(txn.Store.RollbackTo("broken")).To(MatchError(storage.ErrNoSavepoint))
+
+		Expect(txn.Savepoint("sp")).To(Succeed())
+		Expect(txn.Store.Create("/buckets/*", &schema.Object{ID: "foo"})).To(Succeed())
+		Expect(txn.RollbackTo("sp")).To(Succeed())
+		Expect(txn.Store.Exists("/buckets/foo")).To(BeFalse())
+	})
+})
+
+type failingStore struct{ storage.Backend }
+
+func (b failingStore) Begin(ctx context.Context, opt *riposo.TxOptions) (storage.Transaction, error) {
+	tx, err := b.Backend.Begin(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	return failingStoreTx{Transaction: tx}, nil
+}
+
+type failingStoreTx struct{ storage.Transaction }
+
+func (t failingStoreTx) Commit() error {
+	_ = t.Transaction.Rollback()
+	return errors.New("commit failed")
+}
+
+type failingPerms struct{ permission.Backend }
+
+func (b failingPerms) Begin(ctx context.Context, opt *riposo.TxOptions) (permission.Transaction, error) {
+	tx, err := b.Backend.Begin(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	return failingPermsTx{Transaction: tx}, nil
+}
+
+type failingPermsTx struct{ permission.Transaction }
+
+func (t failingPermsTx) Commit() error {
+	_ = t.Transaction.Rollback()
+	return errors.New("commit failed")
+}
+
+func (t failingPermsTx) Savepoint(name string) error {
+	if name == "broken" {
+		return errors.New("savepoint failed")
+	}
+	return t.Transaction.Savepoint(name)
+}
+
+type countingBackend struct {
+	permission.Backend
+	tx *countingTx
+}
+
+func (b *countingBackend) Begin(ctx context.Context, opt *riposo.TxOptions) (permission.Transaction, error) {
+	tx, err := b.Backend.Begin(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	b.tx = &countingTx{Transaction: tx}
+	return b.tx, nil
+}
+
+type countingTx struct {
+	permission.Transaction
+	lookups int
+}
+
+func (t *countingTx) GetUserPrincipals(userID string) ([]string, error) {
+	t.lookups++
+	return t.Transaction.GetUserPrincipals(userID)
+}
+
+func (t *countingTx) GetACEPrincipals(ent permission.ACE) ([]string, error) {
+	t.lookups++
+	return t.Transaction.GetACEPrincipals(ent)
+}
+
+func (t *countingTx) GetAllACEPrincipals(ents []permission.ACE) ([]string, error) {
+	t.lookups++
+	return t.Transaction.GetAllACEPrincipals(ents)
+}
+
+func (t *countingTx) GetAccessiblePaths(dst []riposo.Path, principals []string, ents []permission.ACE) ([]riposo.Path, error) {
+	t.lookups++
+	return t.Transaction.GetAccessiblePaths(dst, principals, ents)
+}
@@ -0,0 +1,111 @@
+// Package attachment implements file attachments for records.
+package attachment
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+	"github.com/tidwall/gjson"
+)
+
+// ErrNotFound is returned when a blob is not found.
+var ErrNotFound = errors.New("blob not found")
+
+// Field is the record field which holds the attachment metadata.
+const Field = "attachment"
+
+// Blob defines the abstract interface of a binary file store.
+type Blob interface {
+	// Put stores the contents of r under key, replacing existing content.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open opens the content stored under key.
+	// It returns ErrNotFound if no such content exists.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete deletes the content stored under key, missing keys are ignored.
+	Delete(ctx context.Context, key string) error
+	// Close closes the store.
+	Close() error
+}
+
+// Attachment contains attachment metadata.
+type Attachment struct {
+	Location string `json:"location"`
+	Filename string `json:"filename"`
+	Hash     string `json:"hash"`
+	Size     int64  `json:"size"`
+	Mimetype string `json:"mimetype"`
+}
+
+// Get extracts the attachment metadata from a record.
+// It returns nil if the record has no (valid) attachment.
+func Get(obj *schema.Object) *Attachment {
+	val := obj.Get(Field)
+	if !gjson.Result(val).IsObject() {
+		return nil
+	}
+
+	var att *Attachment
+	if err := json.Unmarshal([]byte(val.Raw), &att); err != nil || att == nil {
+		return nil
+	}
+	if len(att.Hash) != 64 {
+		return nil
+	} else if _, err := hex.DecodeString(att.Hash); err != nil {
+		return nil
+	}
+	return att
+}
+
+// Key returns the blob key of an attachment.
+func (a *Attachment) Key(path riposo.Path) string {
+	return strings.TrimPrefix(path.String(), "/") + "/" + a.Hash
+}
+
+// --------------------------------------------------------------------
+
+var (
+	registry   = make(map[string]Factory)
+	registryMu sync.RWMutex
+)
+
+// Factory initializes a new blob store at runtime.
+type Factory func(context.Context, *url.URL, riposo.Helpers) (Blob, error)
+
+// Register registers a new blob store by scheme.
+// It will panic if multiple stores are registered under the same scheme.
+func Register(scheme string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := registry[scheme]; ok {
+		panic("scheme " + scheme + " is already registered")
+	}
+	registry[scheme] = factory
+}
+
+// Connect connects a blob store via URL.
+func Connect(ctx context.Context, urlString string, hlp riposo.Helpers) (Blob, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	u, err := url.Parse(urlString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attachment URL %q", urlString)
+	}
+
+	factory, ok := registry[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unknown attachment type %q", u.Scheme)
+	}
+
+	return factory(ctx, u, hlp)
+}
@@ -0,0 +1,34 @@
+package attachment_test
+
+import (
+	"testing"
+
+	"github.com/riposo/riposo/pkg/schema"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/attachment"
+)
+
+var _ = Describe("Get", func() {
+	It("extracts attachments", func() {
+		Expect(Get(&schema.Object{Extra: []byte(`{}`)})).To(BeNil())
+		Expect(Get(&schema.Object{Extra: []byte(`{"attachment":"x"}`)})).To(BeNil())
+		Expect(Get(&schema.Object{Extra: []byte(`{"attachment":{"hash":"../../etc"}}`)})).To(BeNil())
+
+		att := Get(&schema.Object{Extra: []byte(`{"attachment":{"location":"/x","filename":"a.txt","hash":"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824","size":5,"mimetype":"text/plain"}}`)})
+		Expect(att).To(Equal(&Attachment{
+			Location: "/x",
+			Filename: "a.txt",
+			Hash:     "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			Size:     5,
+			Mimetype: "text/plain",
+		}))
+		Expect(att.Key("/buckets/b/collections/c/records/r")).To(Equal("buckets/b/collections/c/records/r/2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"))
+	})
+})
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "pkg/attachment")
+}
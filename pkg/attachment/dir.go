@@ -0,0 +1,82 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/riposo/riposo/pkg/riposo"
+)
+
+func init() {
+	Register("file", func(_ context.Context, u *url.URL, _ riposo.Helpers) (Blob, error) {
+		return NewDir(u.Path)
+	})
+}
+
+type dirBlob struct {
+	root string
+}
+
+// NewDir inits a blob store which keeps files in a local directory.
+func NewDir(root string) (Blob, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &dirBlob{root: root}, nil
+}
+
+// Put implements Blob interface.
+func (b *dirBlob) Put(_ context.Context, key string, r io.Reader) error {
+	name := b.fileName(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, then rename
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Open implements Blob interface.
+func (b *dirBlob) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(b.fileName(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete implements Blob interface.
+func (b *dirBlob) Delete(_ context.Context, key string) error {
+	if err := os.Remove(b.fileName(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Close implements Blob interface.
+func (*dirBlob) Close() error {
+	return nil
+}
+
+func (b *dirBlob) fileName(key string) string {
+	return filepath.Join(b.root, filepath.FromSlash(path.Clean("/"+key)))
+}
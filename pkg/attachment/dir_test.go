@@ -0,0 +1,65 @@
+package attachment_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/attachment"
+)
+
+var _ = Describe("NewDir", func() {
+	var subject Blob
+	var root string
+	var ctx = context.Background()
+
+	read := func(key string) (string, error) {
+		rc, err := subject.Open(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+
+		b, err := io.ReadAll(rc)
+		return string(b), err
+	}
+
+	BeforeEach(func() {
+		var err error
+		root = GinkgoT().TempDir()
+		subject, err = NewDir(filepath.Join(root, "blobs"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+	})
+
+	It("stores files", func() {
+		Expect(subject.Put(ctx, "a/b/c", strings.NewReader("hello"))).To(Succeed())
+		Expect(read("a/b/c")).To(Equal("hello"))
+		Expect(os.ReadFile(filepath.Join(root, "blobs", "a", "b", "c"))).To(Equal([]byte("hello")))
+
+		Expect(subject.Put(ctx, "a/b/c", strings.NewReader("world"))).To(Succeed())
+		Expect(read("a/b/c")).To(Equal("world"))
+	})
+
+	It("deletes files", func() {
+		Expect(subject.Put(ctx, "a/b/c", strings.NewReader("hello"))).To(Succeed())
+		Expect(subject.Delete(ctx, "a/b/c")).To(Succeed())
+		Expect(subject.Delete(ctx, "a/b/c")).To(Succeed())
+
+		_, err := read("a/b/c")
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("does not escape the root directory", func() {
+		Expect(subject.Put(ctx, "../../x", strings.NewReader("hello"))).To(Succeed())
+		Expect(filepath.Join(root, "x")).NotTo(BeAnExistingFile())
+		Expect(filepath.Join(root, "blobs", "x")).To(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,441 @@
+package attachment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/identity"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+	"github.com/tidwall/sjson"
+)
+
+// recordsPattern is the route pattern of the records resource.
+const recordsPattern = "/buckets/{bucket_id}/collections/{collection_id}/records"
+
+// Pattern is the route pattern of record attachments.
+const Pattern = recordsPattern + "/{id}/attachment"
+
+// Install installs attachment routes and callbacks. Uploaded files are stored
+// in blob and must not exceed maxSize bytes. Install must be called before
+// record resources are registered. Records are written via the actions of
+// the records resource, files are only removed once transactions commit.
+func Install(rts *api.Routes, blob Blob, maxSize int64) {
+	h := &handler{rts: rts, blob: blob, maxSize: maxSize}
+	tags := []string{"attachments"}
+
+	rts.Callbacks(&callbacks{blob: blob})
+	rts.Method(http.MethodGet, Pattern, http.HandlerFunc(h.Get))
+	rts.Method(http.MethodPost, Pattern, http.HandlerFunc(h.Upload))
+	rts.Method(http.MethodDelete, Pattern, http.HandlerFunc(h.Delete))
+	rts.Describe(http.MethodGet, Pattern, &api.Operation{Summary: "Download a record attachment", Tags: tags})
+	rts.Describe(http.MethodPost, Pattern, &api.Operation{Summary: "Upload a record attachment", Tags: tags})
+	rts.Describe(http.MethodDelete, Pattern, &api.Operation{Summary: "Delete a record attachment", Tags: tags})
+}
+
+type handler struct {
+	rts     *api.Routes
+	blob    Blob
+	maxSize int64
+}
+
+func (h *handler) Get(w http.ResponseWriter, r *http.Request) {
+	txn := api.GetTxn(r)
+	path := recordPath(r)
+
+	// ensure user has read or write permission
+	if err := h.rts.CheckPermission(txn, path, "read", "write"); err != nil {
+		api.Render(w, err)
+		return
+	}
+
+	// fetch record and attachment
+	att, err := h.fetch(txn, path)
+	if err != nil {
+		api.Render(w, err)
+		return
+	}
+
+	// conditional request check
+	etag := strconv.Quote(att.Hash)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// open file
+	rc, err := h.blob.Open(txn, att.Key(path))
+	if errors.Is(err, ErrNotFound) {
+		api.Render(w, schema.NotFound)
+		return
+	} else if err != nil {
+		api.Render(w, err)
+		return
+	}
+	defer rc.Close()
+
+	// set headers + respond
+	w.Header().Set("Content-Type", att.Mimetype)
+	w.Header().Set("Content-Length", strconv.FormatInt(att.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}))
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, rc)
+}
+
+func (h *handler) Upload(w http.ResponseWriter, r *http.Request) {
+	api.Render(w, h.upload(r))
+}
+
+func (h *handler) upload(r *http.Request) interface{} {
+	txn := api.GetTxn(r)
+	path := recordPath(r)
+
+	act, err := h.actions()
+	if err != nil {
+		return err
+	}
+
+	// validate ID
+	if !identity.IsValid(path.ObjectID()) {
+		return schema.InvalidPath("Invalid object id")
+	}
+
+	// fetch existing, ignore not-found errors
+	exst, err := txn.Store.Get(path, true)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	// ensure user has write permission, or may create records
+	if exst != nil {
+		if err := h.rts.CheckPermission(txn, path, "write"); err != nil {
+			return err
+		}
+	} else {
+		parentPath := path.Parent()
+		if err := h.rts.CheckPermission(txn, parentPath, "record:create", "write"); err != nil {
+			return err
+		}
+
+		// check if collection exists
+		if ok, err := txn.Store.Exists(parentPath); err != nil {
+			return err
+		} else if !ok {
+			return schema.MissingResource(parentPath.ObjectID(), parentPath.ResourceName())
+		}
+	}
+
+	// parse uploaded file
+	file, header, err := h.parseFile(r)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// calculate hash
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	att := &Attachment{
+		Location: r.URL.Path,
+		Filename: filepath.Base(header.Filename),
+		Hash:     hex.EncodeToString(hash.Sum(nil)),
+		Size:     size,
+		Mimetype: detectMimetype(header),
+	}
+
+	// store file
+	key := att.Key(path)
+	if err := h.blob.Put(txn, key, file); err != nil {
+		return err
+	}
+
+	var prev *Attachment
+	if exst != nil {
+		prev = Get(exst)
+	}
+	added := prev == nil || prev.Hash != att.Hash
+
+	// create or update record
+	res, err := h.save(txn, act, path, exst, att)
+	if err != nil {
+		// the file is not referenced by any record
+		if added {
+			_ = h.blob.Delete(txn, key)
+		}
+		return err
+	}
+
+	// delete the new file on rollback, the replaced file on commit
+	if added {
+		txn.OnRollback(func() { h.deleteBlobs(key) })
+	}
+	if prev != nil && added {
+		txn.OnCommit(func() { h.deleteBlobs(prev.Key(path)) })
+	}
+	return res
+}
+
+func (h *handler) save(txn *api.Txn, act api.Actions, path riposo.Path, exst *schema.Object, att *Attachment) (*schema.Resource, error) {
+	if exst == nil {
+		res := &schema.Resource{Data: &schema.Object{ID: path.ObjectID()}}
+		if err := res.Data.Set(Field, att); err != nil {
+			return nil, err
+		}
+		if err := act.Create(txn, path.WithObjectID("*"), res); err != nil {
+			return nil, err
+		}
+		res.StatusCode = http.StatusCreated
+		return res, nil
+	}
+
+	data := exst.Copy()
+	if err := data.Set(Field, att); err != nil {
+		return nil, err
+	}
+	res, err := act.Update(txn, path, exst, &schema.Resource{Data: data})
+	if err != nil {
+		return nil, err
+	}
+	res.StatusCode = http.StatusOK
+	return res, nil
+}
+
+func (h *handler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.delete(r); err != nil {
+		api.Render(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) delete(r *http.Request) error {
+	txn := api.GetTxn(r)
+	path := recordPath(r)
+
+	act, err := h.actions()
+	if err != nil {
+		return err
+	}
+
+	// ensure user has write permission
+	if err := h.rts.CheckPermission(txn, path, "write"); err != nil {
+		return err
+	}
+
+	// fetch existing
+	exst, err := txn.Store.Get(path, true)
+	if errors.Is(err, storage.ErrNotFound) {
+		return schema.InvalidResource(path)
+	} else if err != nil {
+		return err
+	}
+
+	att := Get(exst)
+	if att == nil {
+		return schema.NotFound
+	}
+
+	// remove attachment from record
+	data := exst.Copy()
+	if data.Extra, err = sjson.DeleteBytes(data.Extra, Field); err != nil {
+		return err
+	}
+	if _, err := act.Update(txn, path, exst, &schema.Resource{Data: data}); err != nil {
+		return err
+	}
+
+	// delete file once committed
+	txn.OnCommit(func() { h.deleteBlobs(att.Key(path)) })
+	return nil
+}
+
+// actions returns the actions of the records resource.
+func (h *handler) actions() (api.Actions, error) {
+	if act := h.rts.Actions(recordsPattern); act != nil {
+		return act, nil
+	}
+	return nil, schema.InternalError(errors.New("records resource is not registered"))
+}
+
+// deleteBlobs deletes files from transaction hooks, after the request
+// context may have ended.
+func (h *handler) deleteBlobs(keys ...string) {
+	deleteBlobs(h.blob, keys)
+}
+
+func (h *handler) fetch(txn *api.Txn, path riposo.Path) (*Attachment, error) {
+	obj, err := txn.Store.Get(path, false)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, schema.InvalidResource(path)
+	} else if err != nil {
+		return nil, err
+	}
+
+	att := Get(obj)
+	if att == nil {
+		return nil, schema.NotFound
+	}
+	return att, nil
+}
+
+func (h *handler) parseFile(r *http.Request) (multipart.File, *multipart.FileHeader, error) {
+	// allow some extra space for multipart boundaries and headers
+	r.Body = http.MaxBytesReader(nil, r.Body, h.maxSize+64*1024)
+
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		return nil, nil, schema.InvalidBody(Field, "Invalid multipart upload")
+	}
+
+	file, header, err := r.FormFile(Field)
+	if err != nil {
+		return nil, nil, schema.InvalidBody(Field, "Missing file")
+	}
+	if header.Size > h.maxSize {
+		_ = file.Close()
+		return nil, nil, schema.InvalidBody(Field, "File is too large")
+	}
+	return file, header, nil
+}
+
+func recordPath(r *http.Request) riposo.Path {
+	return riposo.NormPath(strings.TrimSuffix(r.URL.Path, "/"+Field))
+}
+
+func detectMimetype(header *multipart.FileHeader) string {
+	if ctype := header.Header.Get("Content-Type"); ctype != "" && ctype != "application/octet-stream" {
+		return ctype
+	}
+	if ctype := mime.TypeByExtension(filepath.Ext(header.Filename)); ctype != "" {
+		return ctype
+	}
+	return "application/octet-stream"
+}
+
+// --------------------------------------------------------------------
+
+func deleteBlobs(blob Blob, keys []string) {
+	for _, key := range keys {
+		_ = blob.Delete(context.Background(), key)
+	}
+}
+
+// callbacks track files of deleted records, including records of deleted
+// buckets and collections.
+type callbacks struct {
+	api.NoopCallbacks
+	blob Blob
+}
+
+func (c *callbacks) OnDelete(txn *api.Txn, path riposo.Path) api.DeleteCallback {
+	if !hasRecords(path) {
+		return nil
+	}
+	return &deleteCallback{txn: txn, blob: c.blob, path: path}
+}
+
+func (c *callbacks) OnDeleteAll(txn *api.Txn, path riposo.Path) api.DeleteAllCallback {
+	if !hasRecords(path) {
+		return nil
+	}
+	return &deleteCallback{txn: txn, blob: c.blob, path: path}
+}
+
+func hasRecords(path riposo.Path) bool {
+	switch path.ResourceName() {
+	case "bucket", "collection", "record":
+		return true
+	}
+	return false
+}
+
+type deleteCallback struct {
+	txn  *api.Txn
+	blob Blob
+	path riposo.Path
+	keys []string
+}
+
+func (c *deleteCallback) BeforeDelete(exst *schema.Object) error {
+	return c.collect(c.path, exst)
+}
+
+func (c *deleteCallback) AfterDelete(_ *schema.Object) error {
+	c.deleteOnCommit()
+	return nil
+}
+
+func (c *deleteCallback) BeforeDeleteAll(objs []*schema.Object) error {
+	for _, obj := range objs {
+		if err := c.collect(c.path.WithObjectID(obj.ID), obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *deleteCallback) AfterDeleteAll(_ riposo.Epoch, _ []riposo.Path) error {
+	c.deleteOnCommit()
+	return nil
+}
+
+// collect collects the file keys of a record or of all records nested
+// below a bucket or collection.
+func (c *deleteCallback) collect(path riposo.Path, obj *schema.Object) error {
+	switch path.ResourceName() {
+	case "record":
+		if att := Get(obj); att != nil {
+			c.keys = append(c.keys, att.Key(path))
+		}
+	case "bucket":
+		node := riposo.JoinPath(path.String(), "collections")
+		objs, err := c.txn.Store.ListAll(node, storage.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			if err := c.collect(node.WithObjectID(obj.ID), obj); err != nil {
+				return err
+			}
+		}
+	case "collection":
+		node := riposo.JoinPath(path.String(), "records")
+		objs, err := c.txn.Store.ListAll(node, storage.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			if err := c.collect(node.WithObjectID(obj.ID), obj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *deleteCallback) deleteOnCommit() {
+	if len(c.keys) == 0 {
+		return
+	}
+
+	blob, keys := c.blob, c.keys
+	c.txn.OnCommit(func() { deleteBlobs(blob, keys) })
+}
@@ -0,0 +1,279 @@
+package attachment_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn"
+	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/attachment"
+)
+
+var _ = Describe("Install", func() {
+	var rts *api.Routes
+	var cns *conn.Set
+	var txn *api.Txn
+	var blob Blob
+	var ctx = context.Background()
+
+	var (
+		alice = mock.User("account:alice")
+		bob   = mock.User("account:bob")
+	)
+
+	const (
+		recordPath = "/buckets/b/collections/c/records/r"
+		helloHash  = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+		worldHash  = "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"
+	)
+
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rts.Mux().ServeHTTP(w, r)
+		return w
+	}
+
+	upload := func(path, filename, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Disposition": {`form-data; name="attachment"; filename="` + filename + `"`},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.WriteString(part, content)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mw.Close()).To(Succeed())
+
+		req := mock.Request(txn, http.MethodPost, path, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		return serve(req)
+	}
+
+	handle := func(method, path string) *httptest.ResponseRecorder {
+		return serve(mock.Request(txn, method, path, nil))
+	}
+
+	begin := func() *api.Txn {
+		hlp := mock.Helpers()
+		txn, err := api.NewTxn(ctx, cns, hlp, nil)
+		Expect(err).NotTo(HaveOccurred())
+		txn.User = alice
+		return txn
+	}
+
+	commit := func() {
+		Expect(txn.Commit()).To(Succeed())
+		txn = begin()
+	}
+
+	exists := func(key string) bool {
+		rc, err := blob.Open(ctx, key)
+		if err != nil {
+			return false
+		}
+		_ = rc.Close()
+		return true
+	}
+
+	BeforeEach(func() {
+		var err error
+		blob, err = NewDir(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+
+		cns = mock.Conns(nil)
+		txn = begin()
+
+		Expect(txn.Store.Create("/buckets/b/collections/*", &schema.Object{ID: "c"})).To(Succeed())
+		Expect(txn.Perms.CreatePermissions("/buckets/b", schema.PermissionSet{"write": {"account:alice"}})).To(Succeed())
+
+		rts = api.NewRoutes(nil)
+		Install(rts, blob, 16)
+		rts.Resource("/buckets/{bucket_id}/collections/{collection_id}/records", nil)
+	})
+
+	AfterEach(func() {
+		Expect(txn.Rollback()).To(Succeed())
+		Expect(blob.Close()).To(Succeed())
+	})
+
+	It("uploads attachments", func() {
+		w := upload(recordPath+"/attachment", "hello.txt", "hello")
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Body.String()).To(MatchJSON(`{
+			"data": {
+				"id": "r",
+				"last_modified": 1515151515677,
+				"attachment": {
+					"location": "/buckets/b/collections/c/records/r/attachment",
+					"filename": "hello.txt",
+					"hash": "` + helloHash + `",
+					"size": 5,
+					"mimetype": "text/plain; charset=utf-8"
+				}
+			},
+			"permissions": {"write": ["account:alice"]}
+		}`))
+		Expect(exists("buckets/b/collections/c/records/r/" + helloHash)).To(BeTrue())
+
+		// replace
+		w = upload(recordPath+"/attachment", "world.txt", "world")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(exists("buckets/b/collections/c/records/r/" + helloHash)).To(BeTrue())
+		Expect(exists("buckets/b/collections/c/records/r/" + worldHash)).To(BeTrue())
+
+		commit()
+		Expect(exists("buckets/b/collections/c/records/r/" + helloHash)).To(BeFalse())
+		Expect(exists("buckets/b/collections/c/records/r/" + worldHash)).To(BeTrue())
+
+		obj, err := txn.Store.Get(recordPath, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Get(obj).Hash).To(Equal(worldHash))
+	})
+
+	It("removes uploads on rollback", func() {
+		Expect(upload(recordPath+"/attachment", "hello.txt", "hello").Code).To(Equal(http.StatusCreated))
+		commit()
+
+		Expect(txn.Savepoint("upload")).To(Succeed())
+		Expect(upload(recordPath+"/attachment", "world.txt", "world").Code).To(Equal(http.StatusOK))
+		Expect(exists("buckets/b/collections/c/records/r/" + worldHash)).To(BeTrue())
+
+		Expect(txn.RollbackTo("upload")).To(Succeed())
+		obj, err := txn.Store.Get(recordPath, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Get(obj).Hash).To(Equal(helloHash))
+
+		commit()
+		Expect(exists("buckets/b/collections/c/records/r/" + helloHash)).To(BeTrue())
+		Expect(exists("buckets/b/collections/c/records/r/" + worldHash)).To(BeFalse())
+	})
+
+	It("runs record callbacks", func() {
+		var called []string
+		rts = api.NewRoutes(nil)
+		rts.Callbacks(&recordCallbacks{called: &called})
+		Install(rts, blob, 16)
+		rts.Resource("/buckets/{bucket_id}/collections/{collection_id}/records", nil)
+
+		Expect(upload(recordPath+"/attachment", "hello.txt", "hello").Code).To(Equal(http.StatusCreated))
+		Expect(upload(recordPath+"/attachment", "world.txt", "world").Code).To(Equal(http.StatusOK))
+		Expect(handle(http.MethodDelete, recordPath+"/attachment").Code).To(Equal(http.StatusNoContent))
+		Expect(called).To(Equal([]string{"create", "update", "update"}))
+	})
+
+	It("validates uploads", func() {
+		Expect(upload(recordPath+"/attachment", "big.txt", strings.Repeat("x", 17)).Code).To(Equal(http.StatusBadRequest))
+		Expect(upload("/buckets/b/collections/x/records/r/attachment", "hello.txt", "hello").Code).To(Equal(http.StatusNotFound))
+
+		txn.User = bob
+		Expect(upload(recordPath+"/attachment", "hello.txt", "hello").Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("serves attachments", func() {
+		Expect(upload(recordPath+"/attachment", "hello.txt", "hello").Code).To(Equal(http.StatusCreated))
+
+		w := handle(http.MethodGet, recordPath+"/attachment")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(Equal("hello"))
+		Expect(w.Header()).To(HaveKeyWithValue("Content-Type", []string{"text/plain; charset=utf-8"}))
+		Expect(w.Header()).To(HaveKeyWithValue("Content-Disposition", []string{"attachment; filename=hello.txt"}))
+		Expect(w.Header()).To(HaveKeyWithValue("Etag", []string{`"` + helloHash + `"`}))
+
+		Expect(handle(http.MethodGet, "/buckets/b/collections/c/records/x/attachment").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("respects read permissions", func() {
+		Expect(upload(recordPath+"/attachment", "hello.txt", "hello").Code).To(Equal(http.StatusCreated))
+
+		txn.User = bob
+		Expect(handle(http.MethodGet, recordPath+"/attachment").Code).To(Equal(http.StatusForbidden))
+
+		txn.User = mock.User("")
+		Expect(handle(http.MethodGet, recordPath+"/attachment").Code).To(Equal(http.StatusUnauthorized))
+
+		Expect(txn.Perms.CreatePermissions(recordPath, schema.PermissionSet{"read": {"account:bob"}})).To(Succeed())
+		txn.User = bob
+		Expect(handle(http.MethodGet, recordPath+"/attachment").Code).To(Equal(http.StatusOK))
+	})
+
+	It("deletes attachments", func() {
+		Expect(upload(recordPath+"/attachment", "hello.txt", "hello").Code).To(Equal(http.StatusCreated))
+
+		Expect(handle(http.MethodDelete, recordPath+"/attachment").Code).To(Equal(http.StatusNoContent))
+		Expect(exists("buckets/b/collections/c/records/r/" + helloHash)).To(BeTrue())
+		commit()
+		Expect(exists("buckets/b/collections/c/records/r/" + helloHash)).To(BeFalse())
+		Expect(handle(http.MethodDelete, recordPath+"/attachment").Code).To(Equal(http.StatusNotFound))
+
+		w := handle(http.MethodGet, recordPath)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		var res struct{ Data map[string]interface{} }
+		Expect(json.Unmarshal(w.Body.Bytes(), &res)).To(Succeed())
+		Expect(res.Data).NotTo(HaveKey("attachment"))
+	})
+
+	It("deletes files with records", func() {
+		Expect(upload(recordPath+"/attachment", "hello.txt", "hello").Code).To(Equal(http.StatusCreated))
+		Expect(upload("/buckets/b/collections/c/records/s/attachment", "world.txt", "world").Code).To(Equal(http.StatusCreated))
+
+		Expect(handle(http.MethodDelete, recordPath).Code).To(Equal(http.StatusOK))
+		Expect(exists("buckets/b/collections/c/records/r/" + helloHash)).To(BeTrue())
+		commit()
+		Expect(exists("buckets/b/collections/c/records/r/" + helloHash)).To(BeFalse())
+		Expect(exists("buckets/b/collections/c/records/s/" + worldHash)).To(BeTrue())
+
+		Expect(handle(http.MethodDelete, "/buckets/b/collections/c/records").Code).To(Equal(http.StatusOK))
+		commit()
+		Expect(exists("buckets/b/collections/c/records/s/" + worldHash)).To(BeFalse())
+	})
+
+	It("deletes files with collections and buckets", func() {
+		rts.Resource("/buckets", nil)
+		rts.Resource("/buckets/{bucket_id}/collections", nil)
+		Expect(txn.Store.Create("/buckets/*", &schema.Object{ID: "b"})).To(Succeed())
+		Expect(txn.Store.Create("/buckets/b/collections/*", &schema.Object{ID: "d"})).To(Succeed())
+
+		Expect(upload(recordPath+"/attachment", "hello.txt", "hello").Code).To(Equal(http.StatusCreated))
+		Expect(upload("/buckets/b/collections/d/records/s/attachment", "world.txt", "world").Code).To(Equal(http.StatusCreated))
+		commit()
+
+		Expect(handle(http.MethodDelete, "/buckets/b/collections/c").Code).To(Equal(http.StatusOK))
+		Expect(exists("buckets/b/collections/c/records/r/" + helloHash)).To(BeTrue())
+		commit()
+		Expect(exists("buckets/b/collections/c/records/r/" + helloHash)).To(BeFalse())
+		Expect(exists("buckets/b/collections/d/records/s/" + worldHash)).To(BeTrue())
+
+		Expect(handle(http.MethodDelete, "/buckets/b").Code).To(Equal(http.StatusOK))
+		commit()
+		Expect(exists("buckets/b/collections/d/records/s/" + worldHash)).To(BeFalse())
+	})
+})
+
+type recordCallbacks struct {
+	api.NoopCallbacks
+	called *[]string
+}
+
+func (c *recordCallbacks) OnCreate(_ *api.Txn, _ riposo.Path) api.CreateCallback {
+	*c.called = append(*c.called, "create")
+	return nil
+}
+
+func (c *recordCallbacks) OnUpdate(_ *api.Txn, _ riposo.Path) api.UpdateCallback {
+	*c.called = append(*c.called, "update")
+	return nil
+}
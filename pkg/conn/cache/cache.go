@@ -20,6 +20,10 @@ var (
 
 	// ErrTxDone is returned when a transaction has expired.
 	ErrTxDone = errors.New("transaction has already been committed or rolled back")
+	// ErrNoSavepoint is returned when a savepoint does not exist.
+	ErrNoSavepoint = errors.New("savepoint does not exist")
+	// ErrReadOnly is returned when trying to write within a read-only transaction.
+	ErrReadOnly = errors.New("transaction is read-only")
 
 	// errInvalidKey is returned when an key is invalid.
 	errInvalidKey = errors.New("key is invalid")
@@ -30,8 +34,10 @@ type Backend interface {
 	// Ping returns an error if offline.
 	Ping(context.Context) error
 
-	// Begin starts a new Transaction.
-	Begin(context.Context) (Transaction, error)
+	// Begin starts a new Transaction. Options may be nil. Transactions are bound
+	// to the context, operations fail once the context is done. Commits are not
+	// bound to the context and complete once started.
+	Begin(context.Context, *riposo.TxOptions) (Transaction, error)
 
 	// Close closes the backend.
 	Close() error
@@ -44,6 +50,14 @@ type Transaction interface {
 	Commit() error
 	// Rollback rolls back the transaction.
 	Rollback() error
+	// Savepoint creates a named savepoint within the transaction.
+	Savepoint(name string) error
+	// RollbackTo rolls back all changes made after a named savepoint.
+	// May return ErrNoSavepoint.
+	RollbackTo(name string) error
+	// Release destroys a named savepoint, retaining all changes made after it.
+	// May return ErrNoSavepoint.
+	Release(name string) error
 
 	// Flush deletes all stored data.
 	Flush() error
@@ -56,6 +70,13 @@ type Transaction interface {
 	Del(key string) error
 }
 
+// Scanner is an optional interface implemented by transactions which can
+// iterate over all stored keys, e.g. when migrating between backends.
+type Scanner interface {
+	// Scan calls fn for every key that has not expired, in key order.
+	Scan(fn func(key string, val []byte, exp time.Time) error) error
+}
+
 var (
 	registry   = make(map[string]Factory)
 	registryMu sync.RWMutex
@@ -78,15 +99,14 @@ func Register(scheme string, factory Factory) {
 
 // Connect connects a backend via URL.
 func Connect(ctx context.Context, urlString string, hlp riposo.Helpers) (Backend, error) {
-	registryMu.RLock()
-	defer registryMu.RUnlock()
-
 	u, err := url.Parse(urlString)
 	if err != nil {
 		return nil, fmt.Errorf("invalid cache URL %q", urlString)
 	}
 
+	registryMu.RLock()
 	factory, ok := registry[u.Scheme]
+	registryMu.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("unknown cache type %q", u.Scheme)
 	}
@@ -29,7 +29,7 @@ func BehavesLikeBackend(link *LikeBackend) {
 		subject = link.Backend
 
 		var err error
-		tx, err = subject.Begin(ctx)
+		tx, err = subject.Begin(ctx, nil)
 		Ω.Expect(err).NotTo(Ω.HaveOccurred())
 	})
 
@@ -62,7 +62,7 @@ func BehavesLikeBackend(link *LikeBackend) {
 		Ω.Expect(err).To(Ω.MatchError(cache.ErrNotFound))
 		Ω.Expect(tx.Rollback()).To(Ω.Succeed())
 
-		tx2, err := subject.Begin(ctx)
+		tx2, err := subject.Begin(ctx, nil)
 		Ω.Expect(err).NotTo(Ω.HaveOccurred())
 		defer tx2.Rollback()
 
@@ -79,6 +79,34 @@ func BehavesLikeBackend(link *LikeBackend) {
 		Ω.Expect(tx.(testableTx).NumEntries()).To(Ω.BeNumerically("==", 0))
 	})
 
+	Ψ.It("supports savepoints", func() {
+		exp := time.Now().Add(time.Hour)
+		Ω.Expect(tx.Set("k1", []byte("v1"), exp)).To(Ω.Succeed())
+		Ω.Expect(tx.Savepoint("sp1")).To(Ω.Succeed())
+		Ω.Expect(tx.Set("k1", []byte("v2"), exp)).To(Ω.Succeed())
+		Ω.Expect(tx.Set("k2", []byte("v2"), exp)).To(Ω.Succeed())
+		Ω.Expect(tx.Savepoint("sp2")).To(Ω.Succeed())
+		Ω.Expect(tx.Del("k1")).To(Ω.Succeed())
+
+		// rollback to sp2
+		Ω.Expect(tx.RollbackTo("sp2")).To(Ω.Succeed())
+		Ω.Expect(tx.Get("k1")).To(Ω.Equal([]byte("v2")))
+		Ω.Expect(tx.Get("k2")).To(Ω.Equal([]byte("v2")))
+
+		// rollback to sp1
+		Ω.Expect(tx.Flush()).To(Ω.Succeed())
+		Ω.Expect(tx.RollbackTo("sp1")).To(Ω.Succeed())
+		Ω.Expect(tx.Get("k1")).To(Ω.Equal([]byte("v1")))
+		_, err := tx.Get("k2")
+		Ω.Expect(err).To(Ω.MatchError(cache.ErrNotFound))
+
+		// release sp1, keep changes
+		Ω.Expect(tx.Set("k2", []byte("v3"), exp)).To(Ω.Succeed())
+		Ω.Expect(tx.Release("sp1")).To(Ω.Succeed())
+		Ω.Expect(tx.Get("k2")).To(Ω.Equal([]byte("v3")))
+		Ω.Expect(tx.RollbackTo("sp2")).To(Ω.MatchError(cache.ErrNoSavepoint))
+	})
+
 	Ψ.It("gets/sets", func() {
 		Ω.Expect(tx.Set("key", []byte("val"), time.Now().Add(time.Hour))).To(Ω.Succeed())
 		Ω.Expect(tx.Get("key")).To(Ω.Equal([]byte("val")))
@@ -116,4 +144,24 @@ func BehavesLikeBackend(link *LikeBackend) {
 		Ω.Expect(tx.Set("key", []byte("val"), time.Now().Add(-time.Second))).To(Ω.Succeed())
 		Ω.Expect(tx.Del("key")).To(Ω.MatchError(cache.ErrNotFound))
 	})
+
+	Ψ.It("scans", func() {
+		sc, ok := tx.(cache.Scanner)
+		if !ok {
+			Ψ.Skip("not supported")
+		}
+
+		exp := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+		Ω.Expect(tx.Set("k2", []byte("v2"), exp)).To(Ω.Succeed())
+		Ω.Expect(tx.Set("k1", []byte("v1"), exp)).To(Ω.Succeed())
+		Ω.Expect(tx.Set("k3", []byte("v3"), time.Now().Add(-time.Second))).To(Ω.Succeed())
+
+		var keys []string
+		Ω.Expect(sc.Scan(func(key string, val []byte, ttl time.Time) error {
+			Ω.Expect(ttl).To(Ω.BeTemporally("~", exp, time.Millisecond))
+			keys = append(keys, key+"="+string(val))
+			return nil
+		})).To(Ω.Succeed())
+		Ω.Expect(keys).To(Ω.Equal([]string{"k1=v1", "k2=v2"}))
+	})
 }
@@ -11,6 +11,9 @@ import (
 	"go.uber.org/multierr"
 )
 
+// ErrNotSupported is returned when a backend does not support a feature.
+var ErrNotSupported = riposo.ErrNotSupported
+
 // Set exposes connections.
 type Set struct {
 	store storage.Backend
@@ -27,20 +30,42 @@ func Use(store storage.Backend, perms permission.Backend, cache cache.Backend) *
 	}
 }
 
-// Connect connects to all backends.
-func Connect(ctx context.Context, storeURL, permsURL, cacheURL string, hlp riposo.Helpers) (*Set, error) {
-	store, err := storage.Connect(ctx, storeURL, hlp)
+// Options configure connections.
+type Options struct {
+	// StorageWrappers are the names of wrappers applied to the storage
+	// backend, in order.
+	StorageWrappers []string
+	// PermissionWrappers are the names of wrappers applied to the permission
+	// backend, in order.
+	PermissionWrappers []string
+	// CacheWrappers are the names of wrappers applied to the cache backend,
+	// in order.
+	CacheWrappers []string
+}
+
+func (o *Options) norm() *Options {
+	if o == nil {
+		return new(Options)
+	}
+	return o
+}
+
+// Connect connects to all backends. Options may be nil.
+func Connect(ctx context.Context, storeURL, permsURL, cacheURL string, hlp riposo.Helpers, opt *Options) (*Set, error) {
+	opt = opt.norm()
+
+	store, err := connectStorage(ctx, storeURL, hlp, opt)
 	if err != nil {
 		return nil, err
 	}
 
-	perms, err := permission.Connect(ctx, permsURL, hlp)
+	perms, err := connectPermission(ctx, permsURL, hlp, opt)
 	if err != nil {
 		_ = store.Close()
 		return nil, err
 	}
 
-	cache, err := cache.Connect(ctx, cacheURL, hlp)
+	cache, err := connectCache(ctx, cacheURL, hlp, opt)
 	if err != nil {
 		_ = store.Close()
 		_ = perms.Close()
@@ -54,6 +79,48 @@ func Connect(ctx context.Context, storeURL, permsURL, cacheURL string, hlp ripos
 	}, nil
 }
 
+func connectStorage(ctx context.Context, url string, hlp riposo.Helpers, opt *Options) (storage.Backend, error) {
+	b, err := storage.Connect(ctx, url, hlp)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := storage.Wrap(b, opt.StorageWrappers...)
+	if err != nil {
+		_ = b.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func connectPermission(ctx context.Context, url string, hlp riposo.Helpers, opt *Options) (permission.Backend, error) {
+	b, err := permission.Connect(ctx, url, hlp)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := permission.Wrap(b, opt.PermissionWrappers...)
+	if err != nil {
+		_ = b.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func connectCache(ctx context.Context, url string, hlp riposo.Helpers, opt *Options) (cache.Backend, error) {
+	b, err := cache.Connect(ctx, url, hlp)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := cache.Wrap(b, opt.CacheWrappers...)
+	if err != nil {
+		_ = b.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
 // Store returns the main storage backend.
 func (s *Set) Store() storage.Backend { return s.store }
 
@@ -72,11 +139,45 @@ func (s *Set) Close() error {
 	)
 }
 
+// Subscribe registers a handler which is called with the changes of every
+// transaction committed to the storage backend, including transactions
+// committed by other instances if the backend supports it. It returns a
+// function which cancels the subscription. May return ErrNotSupported.
+func (s *Set) Subscribe(fn func([]storage.Change)) (func(), error) {
+	notifier, ok := s.store.(storage.Notifier)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return notifier.Subscribe(fn)
+}
+
 // Heartbeat returns heartbeat info.
 func (s *Set) Heartbeat(ctx context.Context) *schema.Heartbeat {
-	return &schema.Heartbeat{
+	hb := &schema.Heartbeat{
 		Storage:    s.store.Ping(ctx) == nil,
 		Permission: s.perms.Ping(ctx) == nil,
 		Cache:      s.cache.Ping(ctx) == nil,
 	}
+	for name, backend := range map[string]interface{}{
+		"storage":    s.store,
+		"permission": s.perms,
+		"cache":      s.cache,
+	} {
+		if pooled, ok := backend.(Pooled); ok {
+			if stats := pooled.PoolStats(); stats != nil {
+				if hb.Pools == nil {
+					hb.Pools = make(map[string]*schema.PoolStats, 3)
+				}
+				hb.Pools[name] = stats
+			}
+		}
+	}
+	return hb
+}
+
+// Pooled is an optional interface, implemented by backends which maintain a
+// connection pool. Backends may return nil stats, e.g. when wrapping backends
+// without a pool.
+type Pooled interface {
+	PoolStats() *schema.PoolStats
 }
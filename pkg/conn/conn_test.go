@@ -1,12 +1,107 @@
 package conn_test
 
 import (
+	"context"
 	"testing"
 
+	"github.com/riposo/riposo/pkg/conn/cache"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+
+	memc "github.com/riposo/riposo/internal/conn/memory/cache"
+	memp "github.com/riposo/riposo/internal/conn/memory/permission"
+	mems "github.com/riposo/riposo/internal/conn/memory/storage"
+
 	. "github.com/bsm/ginkgo/v2"
 	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/conn"
 )
 
+var _ = Describe("Set", func() {
+	var subject *Set
+
+	BeforeEach(func() {
+		hlp := mock.Helpers()
+		subject = Use(
+			mems.New(mock.Clock(), hlp),
+			memp.New(),
+			&pooledCache{Backend: memc.New()},
+		)
+	})
+
+	AfterEach(func() {
+		Expect(subject.Close()).To(Succeed())
+	})
+
+	It("subscribes to changes", func() {
+		var received []storage.Change
+		cancel, err := subject.Subscribe(func(changes []storage.Change) {
+			received = append(received, changes...)
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer cancel()
+
+		tx, err := subject.Store().Begin(context.Background(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tx.Create("/objects/*", &schema.Object{ID: "a"})).To(Succeed())
+		Expect(tx.Commit()).To(Succeed())
+		Expect(received).To(ConsistOf(HaveField("Path", riposo.Path("/objects/a"))))
+
+		_, err = Use(&plainStorage{Backend: subject.Store()}, subject.Perms(), subject.Cache()).
+			Subscribe(func([]storage.Change) {})
+		Expect(err).To(MatchError(ErrNotSupported))
+	})
+
+	It("reports heartbeats", func() {
+		Expect(subject.Heartbeat(context.Background())).To(Equal(&schema.Heartbeat{
+			Storage:    true,
+			Permission: true,
+			Cache:      true,
+			Pools: map[string]*schema.PoolStats{
+				"cache": {MaxOpen: 10, Open: 2, Idle: 2},
+			},
+		}))
+
+		wrapped := Use(subject.Store(), subject.Perms(), cache.Passthrough{Backend: memc.New()})
+		Expect(wrapped.Heartbeat(context.Background()).Pools).To(BeNil())
+	})
+
+	It("connects with wrappers", func() {
+		ctx := context.Background()
+		cns, err := Connect(ctx, "memory:", "memory:", "memory:", mock.Helpers(), &Options{
+			CacheWrappers: []string{"test.pass"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		defer cns.Close()
+		Expect(cns.Cache()).To(BeAssignableToTypeOf(cache.Passthrough{}))
+
+		_, err = Connect(ctx, "memory:", "memory:", "memory:", mock.Helpers(), &Options{
+			StorageWrappers: []string{"unknown"},
+		})
+		Expect(err).To(MatchError(`unknown storage wrapper "unknown"`))
+	})
+})
+
+func init() {
+	cache.RegisterWrapper("test.pass", func(b cache.Backend) cache.Backend {
+		return cache.Passthrough{Backend: b}
+	})
+}
+
+type plainStorage struct {
+	storage.Backend
+}
+
+type pooledCache struct {
+	cache.Backend
+}
+
+func (*pooledCache) PoolStats() *schema.PoolStats {
+	return &schema.PoolStats{MaxOpen: 10, Open: 2, Idle: 2}
+}
+
 func TestSuite(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "pkg/conn")
@@ -13,8 +13,14 @@ import (
 	"github.com/riposo/riposo/pkg/schema"
 )
 
-// ErrTxDone is returned when a transaction has expired.
-var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+var (
+	// ErrTxDone is returned when a transaction has expired.
+	ErrTxDone = errors.New("transaction has already been committed or rolled back")
+	// ErrNoSavepoint is returned when a savepoint does not exist.
+	ErrNoSavepoint = errors.New("savepoint does not exist")
+	// ErrReadOnly is returned when trying to write within a read-only transaction.
+	ErrReadOnly = errors.New("transaction is read-only")
+)
 
 // ACE is a permission/path tuple.
 type ACE struct {
@@ -27,8 +33,10 @@ type Backend interface {
 	// Ping returns an error if offline.
 	Ping(context.Context) error
 
-	// Begin starts a new Transaction.
-	Begin(context.Context) (Transaction, error)
+	// Begin starts a new Transaction. Options may be nil. Transactions are bound
+	// to the context, operations fail once the context is done. Commits are not
+	// bound to the context and complete once started.
+	Begin(context.Context, *riposo.TxOptions) (Transaction, error)
 
 	// Close closes the backend.
 	Close() error
@@ -41,6 +49,14 @@ type Transaction interface {
 	Commit() error
 	// Rollback rolls back the transaction.
 	Rollback() error
+	// Savepoint creates a named savepoint within the transaction.
+	Savepoint(name string) error
+	// RollbackTo rolls back all changes made after a named savepoint.
+	// May return ErrNoSavepoint.
+	RollbackTo(name string) error
+	// Release destroys a named savepoint, retaining all changes made after it.
+	// May return ErrNoSavepoint.
+	Release(name string) error
 
 	// Flush deletes all stored data.
 	Flush() error
@@ -65,6 +81,9 @@ type Transaction interface {
 
 	// GetPermissions gets all permissions for a single path.
 	GetPermissions(path riposo.Path) (schema.PermissionSet, error)
+	// GetAllPermissions gets all permissions for multiple paths. The resulting
+	// slice is aligned with the requested paths.
+	GetAllPermissions(paths []riposo.Path) ([]schema.PermissionSet, error)
 	// CreatePermissions creates permissions of a single path.
 	CreatePermissions(path riposo.Path, set schema.PermissionSet) error
 	// MergePermissions merges permissions of a single path.
@@ -83,6 +102,16 @@ type Transaction interface {
 	GetAccessiblePaths(dst []riposo.Path, principals []string, ents []ACE) ([]riposo.Path, error)
 }
 
+// Scanner is an optional interface implemented by transactions which can
+// iterate over all stored data, e.g. when migrating between backends.
+type Scanner interface {
+	// ScanUserPrincipals calls fn for every user with principals assigned to it,
+	// in user ID order.
+	ScanUserPrincipals(fn func(userID string, principals []string) error) error
+	// ScanPermissions calls fn for every path with permissions, in path order.
+	ScanPermissions(fn func(path riposo.Path, set schema.PermissionSet) error) error
+}
+
 var (
 	registry   = make(map[string]Factory)
 	registryMu sync.RWMutex
@@ -105,15 +134,14 @@ func Register(scheme string, factory Factory) {
 
 // Connect connects a backend via URL.
 func Connect(ctx context.Context, urlString string, hlp riposo.Helpers) (Backend, error) {
-	registryMu.RLock()
-	defer registryMu.RUnlock()
-
 	u, err := url.Parse(urlString)
 	if err != nil {
 		return nil, fmt.Errorf("invalid permission URL %q", urlString)
 	}
 
+	registryMu.RLock()
 	factory, ok := registry[u.Scheme]
+	registryMu.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("unknown permission type %q", u.Scheme)
 	}
@@ -3,6 +3,7 @@ package testdata
 import (
 	"context"
 	"sort"
+	"strings"
 
 	Ψ "github.com/bsm/ginkgo/v2"
 	Ω "github.com/bsm/gomega"
@@ -35,7 +36,7 @@ func BehavesLikeBackend(link *LikeBackend) {
 		subject = link.Backend
 
 		var err error
-		tx, err = subject.Begin(ctx)
+		tx, err = subject.Begin(ctx, nil)
 		Ω.Expect(err).NotTo(Ω.HaveOccurred())
 	})
 
@@ -82,7 +83,7 @@ func BehavesLikeBackend(link *LikeBackend) {
 		))
 		Ω.Expect(tx.Rollback()).To(Ω.Succeed())
 
-		tx2, err := subject.Begin(ctx)
+		tx2, err := subject.Begin(ctx, nil)
 		Ω.Expect(err).NotTo(Ω.HaveOccurred())
 		defer tx2.Rollback()
 
@@ -97,6 +98,38 @@ func BehavesLikeBackend(link *LikeBackend) {
 		Ω.Expect(tx.(testableTx).NumEntries()).To(Ω.BeNumerically("==", 0))
 	})
 
+	Ψ.It("supports savepoints", func() {
+		Ω.Expect(tx.AddUserPrincipal("foo", []string{"alice"})).To(Ω.Succeed())
+		Ω.Expect(tx.Savepoint("sp1")).To(Ω.Succeed())
+		Ω.Expect(tx.AddUserPrincipal("bar", []string{"alice"})).To(Ω.Succeed())
+		Ω.Expect(tx.AddACEPrincipal("alice", ACE("read", "/buckets/bat"))).To(Ω.Succeed())
+		Ω.Expect(tx.Savepoint("sp2")).To(Ω.Succeed())
+		Ω.Expect(tx.RemoveUserPrincipal("foo", []string{"alice"})).To(Ω.Succeed())
+
+		// rollback to sp2
+		Ω.Expect(tx.RollbackTo("sp2")).To(Ω.Succeed())
+		Ω.Expect(tx.GetUserPrincipals("alice")).To(Ω.ConsistOf(
+			"alice", "system.Authenticated", "system.Everyone",
+			"foo", "bar",
+		))
+		Ω.Expect(tx.GetACEPrincipals(ACE("read", "/buckets/bat"))).To(Ω.ConsistOf("alice"))
+
+		// rollback to sp1
+		Ω.Expect(tx.Flush()).To(Ω.Succeed())
+		Ω.Expect(tx.RollbackTo("sp1")).To(Ω.Succeed())
+		Ω.Expect(tx.GetUserPrincipals("alice")).To(Ω.ConsistOf(
+			"alice", "system.Authenticated", "system.Everyone",
+			"foo",
+		))
+		Ω.Expect(tx.GetACEPrincipals(ACE("read", "/buckets/bat"))).To(Ω.BeEmpty())
+
+		// release sp1, keep changes
+		Ω.Expect(tx.AddUserPrincipal("baz", []string{"alice"})).To(Ω.Succeed())
+		Ω.Expect(tx.Release("sp1")).To(Ω.Succeed())
+		Ω.Expect(tx.GetUserPrincipals("alice")).To(Ω.ContainElement("baz"))
+		Ω.Expect(tx.RollbackTo("sp2")).To(Ω.MatchError(permission.ErrNoSavepoint))
+	})
+
 	Ψ.It("manages user principals", func() {
 		Ω.Expect(tx.AddUserPrincipal("b", []string{"alice", "bob"})).To(Ω.Succeed())
 		Ω.Expect(tx.AddUserPrincipal("a", []string{"alice", "bob"})).To(Ω.Succeed())
@@ -245,6 +278,67 @@ func BehavesLikeBackend(link *LikeBackend) {
 		}))
 	})
 
+	Ψ.It("gets permissions in batches", func() {
+		Ω.Expect(tx.GetAllPermissions(nil)).To(Ω.BeEmpty())
+
+		Ω.Expect(tx.CreatePermissions("/accounts/ant", schema.PermissionSet{
+			"read": []string{"x"},
+		})).To(Ω.Succeed())
+		Ω.Expect(tx.CreatePermissions("/buckets/bat", schema.PermissionSet{
+			"read":  []string{"z"},
+			"write": []string{"x", "y"},
+		})).To(Ω.Succeed())
+
+		sets, err := tx.GetAllPermissions([]riposo.Path{"/buckets/bat", "/collections/cod", "/accounts/ant"})
+		Ω.Expect(err).NotTo(Ω.HaveOccurred())
+		Ω.Expect(sets).To(Ω.HaveLen(3))
+		Ω.Expect(sets[0]).To(MatchPermissions(schema.PermissionSet{
+			"read":  []string{"z"},
+			"write": []string{"x", "y"},
+		}))
+		Ω.Expect(sets[1]).To(MatchPermissions(nil))
+		Ω.Expect(sets[2]).To(MatchPermissions(schema.PermissionSet{
+			"read": []string{"x"},
+		}))
+	})
+
+	Ψ.It("scans", func() {
+		sc, ok := tx.(permission.Scanner)
+		if !ok {
+			Ψ.Skip("not supported")
+		}
+
+		Ω.Expect(tx.AddUserPrincipal("g1", []string{"bob", "alice"})).To(Ω.Succeed())
+		Ω.Expect(tx.AddUserPrincipal("g2", []string{"alice"})).To(Ω.Succeed())
+		Ω.Expect(tx.CreatePermissions("/buckets/bat", schema.PermissionSet{
+			"read":  []string{"z"},
+			"write": []string{"x", "y"},
+		})).To(Ω.Succeed())
+		Ω.Expect(tx.CreatePermissions("/accounts/ant", schema.PermissionSet{
+			"read": []string{"x"},
+		})).To(Ω.Succeed())
+
+		var users []string
+		Ω.Expect(sc.ScanUserPrincipals(func(userID string, principals []string) error {
+			users = append(users, userID+":"+strings.Join(principals, ","))
+			return nil
+		})).To(Ω.Succeed())
+		Ω.Expect(users).To(Ω.Equal([]string{"alice:g1,g2", "bob:g1"}))
+
+		var paths []riposo.Path
+		var sets []schema.PermissionSet
+		Ω.Expect(sc.ScanPermissions(func(path riposo.Path, set schema.PermissionSet) error {
+			paths = append(paths, path)
+			sets = append(sets, set)
+			return nil
+		})).To(Ω.Succeed())
+		Ω.Expect(paths).To(Ω.Equal([]riposo.Path{"/accounts/ant", "/buckets/bat"}))
+		Ω.Expect(sets[1]).To(MatchPermissions(schema.PermissionSet{
+			"read":  []string{"z"},
+			"write": []string{"x", "y"},
+		}))
+	})
+
 	Ψ.It("merges permissions", func() {
 		Ω.Expect(tx.AddACEPrincipal("x", ACE("write", "/buckets/bat"))).To(Ω.Succeed())
 		Ω.Expect(tx.AddACEPrincipal("y", ACE("write", "/buckets/bat"))).To(Ω.Succeed())
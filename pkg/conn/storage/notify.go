@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"sync"
+
+	"github.com/riposo/riposo/pkg/riposo"
+)
+
+// Change describes a committed object change. Deletions are recursive, nested
+// objects are not necessarily reported individually. Flushes are reported as
+// deletions of the blank root path.
+type Change struct {
+	Path    riposo.Path  `json:"path"`
+	ModTime riposo.Epoch `json:"last_modified"`
+	Deleted bool         `json:"deleted,omitempty"`
+}
+
+// Notifier is an optional interface implemented by backends which can notify
+// subscribers about committed changes.
+type Notifier interface {
+	// Subscribe registers a handler which is called with the changes of every
+	// committed transaction. Handlers must not block. It returns a function
+	// which cancels the subscription.
+	Subscribe(func([]Change)) (func(), error)
+}
+
+// Hub is an in-process Notifier.
+type Hub struct {
+	subs map[int]func([]Change)
+	next int
+	mu   sync.RWMutex
+}
+
+// Subscribe implements Notifier interface.
+func (h *Hub) Subscribe(fn func([]Change)) (func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs == nil {
+		h.subs = make(map[int]func([]Change))
+	}
+	id := h.next
+	h.subs[id] = fn
+	h.next++
+
+	return func() {
+		h.mu.Lock()
+		delete(h.subs, id)
+		h.mu.Unlock()
+	}, nil
+}
+
+// Publish publishes changes to all subscribers.
+func (h *Hub) Publish(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	h.mu.RLock()
+	subs := make([]func([]Change), 0, len(h.subs))
+	for _, fn := range h.subs {
+		subs = append(subs, fn)
+	}
+	h.mu.RUnlock()
+
+	for _, fn := range subs {
+		fn(changes)
+	}
+}
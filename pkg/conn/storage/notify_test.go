@@ -0,0 +1,48 @@
+package storage_test
+
+import (
+	"github.com/riposo/riposo/pkg/riposo"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/conn/storage"
+)
+
+var _ = Describe("Hub", func() {
+	var subject *Hub
+
+	BeforeEach(func() {
+		subject = new(Hub)
+	})
+
+	It("publishes to subscribers", func() {
+		var r1, r2 []Change
+		cancel1, err := subject.Subscribe(func(cs []Change) { r1 = append(r1, cs...) })
+		Expect(err).NotTo(HaveOccurred())
+		_, err = subject.Subscribe(func(cs []Change) { r2 = append(r2, cs...) })
+		Expect(err).NotTo(HaveOccurred())
+
+		subject.Publish([]Change{{Path: "/objects/a", ModTime: 1}})
+		subject.Publish(nil)
+		Expect(r1).To(Equal([]Change{{Path: "/objects/a", ModTime: 1}}))
+		Expect(r2).To(Equal(r1))
+
+		cancel1()
+		subject.Publish([]Change{{Path: riposo.Path("/objects/b"), ModTime: 2, Deleted: true}})
+		Expect(r1).To(HaveLen(1))
+		Expect(r2).To(HaveLen(2))
+	})
+
+	It("allows to cancel from handlers", func() {
+		var cancel func()
+		var calls int
+		cancel, _ = subject.Subscribe(func([]Change) {
+			calls++
+			cancel()
+		})
+
+		subject.Publish([]Change{{Path: "/objects/a"}})
+		subject.Publish([]Change{{Path: "/objects/b"}})
+		Expect(calls).To(Equal(1))
+	})
+})
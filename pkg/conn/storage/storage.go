@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
 	"sync"
 
 	"github.com/riposo/riposo/pkg/params"
@@ -23,6 +24,10 @@ var (
 	ErrInvalidPath = errors.New("invalid path")
 	// ErrTxDone is returned when a transaction has expired.
 	ErrTxDone = errors.New("transaction has already been committed or rolled back")
+	// ErrNoSavepoint is returned when a savepoint does not exist.
+	ErrNoSavepoint = errors.New("savepoint does not exist")
+	// ErrReadOnly is returned when trying to write within a read-only transaction.
+	ErrReadOnly = errors.New("transaction is read-only")
 )
 
 // Backend defines the abstract storage interface.
@@ -30,8 +35,10 @@ type Backend interface {
 	// Ping returns an error if offline.
 	Ping(context.Context) error
 
-	// Begin starts a new Transaction.
-	Begin(context.Context) (Transaction, error)
+	// Begin starts a new Transaction. Options may be nil. Transactions are bound
+	// to the context, operations fail once the context is done. Commits are not
+	// bound to the context and complete once started.
+	Begin(context.Context, *riposo.TxOptions) (Transaction, error)
 
 	// Close closes the backend.
 	Close() error
@@ -44,6 +51,14 @@ type Transaction interface {
 	Commit() error
 	// Rollback rolls back the transaction.
 	Rollback() error
+	// Savepoint creates a named savepoint within the transaction.
+	Savepoint(name string) error
+	// RollbackTo rolls back all changes made after a named savepoint.
+	// May return ErrNoSavepoint.
+	RollbackTo(name string) error
+	// Release destroys a named savepoint, retaining all changes made after it.
+	// May return ErrNoSavepoint.
+	Release(name string) error
 
 	// Flush removes every object from this backend.
 	Flush() error
@@ -78,6 +93,22 @@ type Transaction interface {
 	Delete(path riposo.Path) (*schema.Object, error)
 }
 
+// Restorer is an optional interface implemented by transactions which can
+// restore objects with their original state, e.g. when importing backups.
+type Restorer interface {
+	// Restore stores an object under a path, preserving its ModTime and Deleted
+	// flag. May return ErrObjectExists.
+	Restore(path riposo.Path, obj *schema.Object) error
+}
+
+// Aggregator is an optional interface implemented by transactions which can
+// aggregate objects.
+type Aggregator interface {
+	// Aggregate aggregates matching objects within a path and returns the
+	// resulting groups, sorted by their keys.
+	Aggregate(path riposo.Path, opt AggregateOptions) ([]AggregateGroup, error)
+}
+
 var (
 	registry   = make(map[string]Factory)
 	registryMu sync.RWMutex
@@ -98,17 +129,16 @@ func Register(scheme string, factory Factory) {
 	registry[scheme] = factory
 }
 
-// Connect connects a backend via URL.
+// Connect connects a backend via URL. Wrapping backends may be registered
+// under a prefix and combined with the scheme of the wrapped backend, e.g.
+// "encrypted+postgres".
 func Connect(ctx context.Context, urlString string, hlp riposo.Helpers) (Backend, error) {
-	registryMu.RLock()
-	defer registryMu.RUnlock()
-
 	u, err := url.Parse(urlString)
 	if err != nil {
 		return nil, fmt.Errorf("invalid storage URL %q", urlString)
 	}
 
-	factory, ok := registry[u.Scheme]
+	factory, ok := lookup(u.Scheme)
 	if !ok {
 		return nil, fmt.Errorf("unknown storage type %q", u.Scheme)
 	}
@@ -116,6 +146,20 @@ func Connect(ctx context.Context, urlString string, hlp riposo.Helpers) (Backend
 	return factory(ctx, u, hlp)
 }
 
+func lookup(scheme string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if factory, ok := registry[scheme]; ok {
+		return factory, true
+	}
+	if prefix, _, ok := strings.Cut(scheme, "+"); ok {
+		factory, ok := registry[prefix]
+		return factory, ok
+	}
+	return nil, false
+}
+
 // --------------------------------------------------------------------
 
 // Inclusion allows to include objects by state.
@@ -146,3 +190,19 @@ type ListOptions struct {
 	// Limits the number of objects returned.
 	Limit int
 }
+
+// AggregateOptions contain options for aggregations.
+type AggregateOptions struct {
+	// Condition are AND'ed.
+	Condition params.Condition
+	// Aggregation to apply.
+	Aggregation params.Aggregation
+}
+
+// AggregateGroup is the aggregation result of a group of objects.
+type AggregateGroup struct {
+	// Keys contain the values of the grouped fields, in order.
+	Keys []schema.Value
+	// Value is the aggregated value, null if there were no values to aggregate.
+	Value schema.Value
+}
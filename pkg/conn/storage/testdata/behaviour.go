@@ -50,7 +50,7 @@ func BehavesLikeBackend(link *LikeBackend) {
 		subject = link.Backend
 
 		var err error
-		tx, err = subject.Begin(ctx)
+		tx, err = subject.Begin(ctx, nil)
 		Ω.Expect(err).NotTo(Ω.HaveOccurred())
 	})
 
@@ -96,7 +96,7 @@ func BehavesLikeBackend(link *LikeBackend) {
 		Ω.Expect(tx.Rollback()).To(Ω.Succeed())
 
 		// try with other transaction
-		tx2, err := subject.Begin(ctx)
+		tx2, err := subject.Begin(ctx, nil)
 		Ω.Expect(err).NotTo(Ω.HaveOccurred())
 		defer tx2.Rollback()
 
@@ -116,6 +116,34 @@ func BehavesLikeBackend(link *LikeBackend) {
 		Ω.Expect(NumEntries()).To(Ω.Equal(0))
 	})
 
+	Ψ.It("supports savepoints", func() {
+		Ω.Expect(tx.Create("/objects/*", &schema.Object{})).To(Ω.Succeed())
+		Ω.Expect(tx.Savepoint("sp1")).To(Ω.Succeed())
+		Ω.Expect(tx.Create("/objects/*", &schema.Object{})).To(Ω.Succeed())
+		Ω.Expect(tx.Savepoint("sp2")).To(Ω.Succeed())
+		mustDelete("/objects/EPR.ID")
+		Ω.Expect(NumEntries()).To(Ω.Equal(1))
+
+		// rollback to sp2
+		Ω.Expect(tx.RollbackTo("sp2")).To(Ω.Succeed())
+		Ω.Expect(NumEntries()).To(Ω.Equal(2))
+		Ω.Expect(tx.Exists("/objects/EPR.ID")).To(Ω.BeTrue())
+		Ω.Expect(tx.Exists("/objects/ITR.ID")).To(Ω.BeTrue())
+
+		// rollback to sp1
+		Ω.Expect(tx.Flush()).To(Ω.Succeed())
+		Ω.Expect(tx.RollbackTo("sp1")).To(Ω.Succeed())
+		Ω.Expect(NumEntries()).To(Ω.Equal(1))
+		Ω.Expect(tx.Exists("/objects/EPR.ID")).To(Ω.BeTrue())
+		Ω.Expect(tx.Exists("/objects/ITR.ID")).To(Ω.BeFalse())
+
+		// release sp1, keep changes
+		Ω.Expect(tx.Create("/objects/*", &schema.Object{})).To(Ω.Succeed())
+		Ω.Expect(tx.Release("sp1")).To(Ω.Succeed())
+		Ω.Expect(NumEntries()).To(Ω.Equal(2))
+		Ω.Expect(tx.RollbackTo("sp2")).To(Ω.MatchError(storage.ErrNoSavepoint))
+	})
+
 	Ψ.It("gets mod-times", func() {
 		// only accept node paths
 		_, err := tx.ModTime("/objects/foo")
@@ -248,6 +276,42 @@ func BehavesLikeBackend(link *LikeBackend) {
 		Ω.Expect(o2.ModTime).To(Ω.BeNumerically(">", o1.ModTime))
 	})
 
+	Ψ.It("restores objects", func() {
+		rs, ok := tx.(storage.Restorer)
+		if !ok {
+			Ψ.Skip("not supported")
+		}
+
+		// only accept node paths
+		Ω.Expect(rs.Restore("/objects/foo", &schema.Object{ID: "foo"})).To(Ω.MatchError(storage.ErrInvalidPath))
+		Ω.Expect(rs.Restore("/objects/*", &schema.Object{})).To(Ω.MatchError(storage.ErrInvalidPath))
+
+		// restore live and deleted objects
+		Ω.Expect(rs.Restore("/objects/*", &schema.Object{ID: "o1", ModTime: 1515151515677, Extra: []byte(`{"a":1}`)})).To(Ω.Succeed())
+		Ω.Expect(rs.Restore("/objects/*", &schema.Object{ID: "o2", ModTime: 1515151515678, Deleted: true})).To(Ω.Succeed())
+		Ω.Expect(NumEntries()).To(Ω.Equal(1))
+
+		Ω.Expect(tx.Get("/objects/o1", false)).To(Ω.Equal(&schema.Object{
+			ID:      "o1",
+			ModTime: 1515151515677,
+			Extra:   []byte(`{"a":1}`),
+		}))
+		Ω.Expect(tx.ListAll("/objects/*", storage.ListOptions{
+			Include: storage.IncludeAll,
+		})).To(Ω.ConsistOf(
+			Ω.HaveField("ModTime", riposo.Epoch(1515151515677)),
+			Ω.And(Ω.HaveField("ModTime", riposo.Epoch(1515151515678)), Ω.HaveField("Deleted", true)),
+		))
+
+		// duplicate
+		Ω.Expect(rs.Restore("/objects/*", &schema.Object{ID: "o1", ModTime: 1515151515679})).To(Ω.MatchError(storage.ErrObjectExists))
+
+		// previously deleted
+		Ω.Expect(rs.Restore("/objects/*", &schema.Object{ID: "o2", ModTime: 1515151515679})).To(Ω.Succeed())
+		Ω.Expect(NumEntries()).To(Ω.Equal(2))
+		Ω.Expect(tx.Get("/objects/o2", false)).To(Ω.HaveField("ModTime", riposo.Epoch(1515151515679)))
+	})
+
 	Ψ.It("creates in parallel", func() {
 		Ω.Expect(tx.Rollback()).To(Ω.Succeed())
 
@@ -259,7 +323,7 @@ func BehavesLikeBackend(link *LikeBackend) {
 				defer Ψ.GinkgoRecover()
 				defer wg.Done()
 
-				tx2, err := subject.Begin(ctx)
+				tx2, err := subject.Begin(ctx, nil)
 				Ω.Expect(err).NotTo(Ω.HaveOccurred())
 				defer tx2.Rollback()
 
@@ -271,7 +335,7 @@ func BehavesLikeBackend(link *LikeBackend) {
 		}
 		wg.Wait()
 
-		tx2, err := subject.Begin(ctx)
+		tx2, err := subject.Begin(ctx, nil)
 		Ω.Expect(err).NotTo(Ω.HaveOccurred())
 		defer tx2.Rollback()
 
@@ -524,6 +588,57 @@ func BehavesLikeBackend(link *LikeBackend) {
 		Ω.Expect(NumEntries()).To(Ω.Equal(0))
 	})
 
+	Ψ.It("notifies subscribers", func() {
+		nf, ok := subject.(storage.Notifier)
+		if !ok {
+			Ψ.Skip("not supported")
+		}
+
+		var mu sync.Mutex
+		var received []storage.Change
+		cancel, err := nf.Subscribe(func(changes []storage.Change) {
+			mu.Lock()
+			received = append(received, changes...)
+			mu.Unlock()
+		})
+		Ω.Expect(err).NotTo(Ω.HaveOccurred())
+		defer cancel()
+
+		Received := func() []storage.Change {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]storage.Change(nil), received...)
+		}
+
+		// rolled back transactions do not notify
+		Ω.Expect(tx.Create("/objects/*", &schema.Object{ID: "a"})).To(Ω.Succeed())
+		Ω.Expect(tx.Rollback()).To(Ω.Succeed())
+
+		// changes are published on commit, savepoints are respected
+		tx, err = subject.Begin(ctx, nil)
+		Ω.Expect(err).NotTo(Ω.HaveOccurred())
+		Ω.Expect(tx.Create("/objects/*", &schema.Object{ID: "b"})).To(Ω.Succeed())
+		Ω.Expect(tx.Savepoint("sp")).To(Ω.Succeed())
+		Ω.Expect(tx.Create("/objects/*", &schema.Object{ID: "c"})).To(Ω.Succeed())
+		Ω.Expect(tx.RollbackTo("sp")).To(Ω.Succeed())
+		mustDelete("/objects/b")
+		Ω.Expect(Received()).To(Ω.BeEmpty())
+		Ω.Expect(tx.Commit()).To(Ω.Succeed())
+
+		Ω.Eventually(Received).Should(Ω.ConsistOf(
+			Ω.And(Ω.HaveField("Path", riposo.Path("/objects/b")), Ω.HaveField("Deleted", false), Ω.HaveField("ModTime", BeRecent())),
+			Ω.And(Ω.HaveField("Path", riposo.Path("/objects/b")), Ω.HaveField("Deleted", true), Ω.HaveField("ModTime", BeRecent())),
+		))
+
+		// flushes are published as root deletions
+		tx, err = subject.Begin(ctx, nil)
+		Ω.Expect(err).NotTo(Ω.HaveOccurred())
+		Ω.Expect(tx.Flush()).To(Ω.Succeed())
+		Ω.Expect(tx.Commit()).To(Ω.Succeed())
+
+		Ω.Eventually(Received).Should(Ω.ContainElement(storage.Change{Path: "", Deleted: true}))
+	})
+
 	Ψ.Describe("transactions", func() {
 		Ψ.BeforeEach(func() {
 			if link.SkipACID {
@@ -537,7 +652,7 @@ func BehavesLikeBackend(link *LikeBackend) {
 			Ω.Expect(tx.Rollback()).To(Ω.Succeed())
 
 			var err error
-			tx, err = subject.Begin(ctx)
+			tx, err = subject.Begin(ctx, nil)
 			Ω.Expect(err).NotTo(Ω.HaveOccurred())
 			Ω.Expect(NumEntries()).To(Ω.Equal(0))
 		})
@@ -651,6 +766,79 @@ func BehavesLikeBackend(link *LikeBackend) {
 			})
 		})
 
+		Ψ.Describe("aggregation", func() {
+			val := schema.ParseValue
+			aggregate := func(aggregate, groupBy string, cond ...params.Filter) ([]storage.AggregateGroup, error) {
+				agg, err := params.ParseAggregation(aggregate, groupBy)
+				Ω.Expect(err).NotTo(Ω.HaveOccurred())
+
+				return tx.(storage.Aggregator).Aggregate("/objects/*", storage.AggregateOptions{
+					Condition:   cond,
+					Aggregation: *agg,
+				})
+			}
+
+			Ψ.BeforeEach(func() {
+				if _, ok := tx.(storage.Aggregator); !ok {
+					Ψ.Skip("aggregation is not supported")
+				}
+			})
+
+			Ψ.It("counts", func() {
+				Ω.Expect(aggregate("count", "")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{}, Value: val("2")},
+				}))
+				Ω.Expect(aggregate("count", "", params.ParseFilter("gt_num", "40"))).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{}, Value: val("1")},
+				}))
+				Ω.Expect(aggregate("count", "", params.ParseFilter("gt_num", "80"))).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{}, Value: val("0")},
+				}))
+			})
+
+			Ψ.It("groups", func() {
+				Ω.Expect(aggregate("count", "str")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{val(`"k"`)}, Value: val("1")},
+					{Keys: []schema.Value{val("")}, Value: val("1")},
+				}))
+				Ω.Expect(aggregate("count", "sub.ok")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{val("true")}, Value: val("2")},
+				}))
+				Ω.Expect(aggregate("count", "id,sub.ok")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{val(`"EPR.ID"`), val("true")}, Value: val("1")},
+					{Keys: []schema.Value{val(`"ITR.ID"`), val("true")}, Value: val("1")},
+				}))
+				Ω.Expect(aggregate("count", "str", params.ParseFilter("gt_num", "80"))).To(Ω.BeEmpty())
+			})
+
+			Ψ.It("aggregates numeric values", func() {
+				Ω.Expect(aggregate("sum(num)", "")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{}, Value: val("99")},
+				}))
+				Ω.Expect(aggregate("avg(num)", "")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{}, Value: val("49.5")},
+				}))
+				Ω.Expect(aggregate("min(num)", "sub.ok")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{val("true")}, Value: val("33")},
+				}))
+				Ω.Expect(aggregate("max(num)", "str")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{val(`"k"`)}, Value: val("33")},
+					{Keys: []schema.Value{val("")}, Value: val("66")},
+				}))
+				Ω.Expect(aggregate("sum(sub.num)", "")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{}, Value: val("11")},
+				}))
+
+				// non-numeric
+				Ω.Expect(aggregate("sum(str)", "")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{}, Value: val("")},
+				}))
+				Ω.Expect(aggregate("max(mix)", "")).To(Ω.Equal([]storage.AggregateGroup{
+					{Keys: []schema.Value{}, Value: val("")},
+				}))
+			})
+		})
+
 		Ψ.Describe("conditions", func() {
 			filter := func(f, v string) ([]string, error) { return FilterScope(tx, f, v) }
 			succeed := func() types.GomegaMatcher {
@@ -1164,6 +1352,89 @@ func BehavesLikeBackend(link *LikeBackend) {
 				// Missing - always false
 				Ω.Expect(filter("contains_any_unk", `xx`)).To(Ω.BeEmpty())
 			})
+
+			Ψ.It("filters via AND/OR", func() {
+				expr := func(s string) ([]string, error) {
+					flt, err := params.ParseExpr(s)
+					Ω.Expect(err).NotTo(Ω.HaveOccurred())
+					return ListScope(tx, storage.ListOptions{Condition: params.Condition{flt}})
+				}
+
+				Ω.Expect(expr(`or(eq(str,"k"),gt_num(50))`)).To(Ω.ConsistOf("EPR.ID", "ITR.ID"))
+				Ω.Expect(expr(`or(eq(str,"k"),gt_num(70))`)).To(Ω.ConsistOf("EPR.ID"))
+				Ω.Expect(expr(`or(eq(str,"x"),gt_num(70))`)).To(Ω.BeEmpty())
+				Ω.Expect(expr(`and(eq(str,"k"),gt_num(20))`)).To(Ω.ConsistOf("EPR.ID"))
+				Ω.Expect(expr(`and(eq(str,"k"),gt_num(50))`)).To(Ω.BeEmpty())
+				Ω.Expect(expr(`or(and(has_str,max_num(33)),and(mix,sub.ok))`)).To(Ω.ConsistOf("EPR.ID", "ITR.ID"))
+				Ω.Expect(expr(`or(and(has_str,min_num(34)),and(mix,sub.ok))`)).To(Ω.ConsistOf("ITR.ID"))
+				Ω.Expect(expr(`or(id("ITR.ID"),in(id,"EPR.ID","XXX"))`)).To(Ω.ConsistOf("EPR.ID", "ITR.ID"))
+				Ω.Expect(expr(`and(or(id("ITR.ID"),num(33)),or(yes,non))`)).To(Ω.ConsistOf("EPR.ID"))
+
+				// combined with other filters
+				flt, err := params.ParseExpr(`or(str(k),mix)`)
+				Ω.Expect(err).NotTo(Ω.HaveOccurred())
+				Ω.Expect(ListScope(tx, storage.ListOptions{Condition: params.Condition{
+					flt,
+					params.ParseFilter("gt_num", "40"),
+				}})).To(Ω.ConsistOf("ITR.ID"))
+				Ω.Expect(tx.CountAll("/objects/*", storage.CountOptions{Condition: params.Condition{flt}})).To(Ω.Equal(int64(2)))
+
+				// empty
+				Ω.Expect(ListScope(tx, storage.ListOptions{Condition: params.Condition{
+					{Operator: params.OperatorOR},
+				}})).To(Ω.BeEmpty())
+				Ω.Expect(ListScope(tx, storage.ListOptions{Condition: params.Condition{
+					{Operator: params.OperatorAND},
+				}})).To(Ω.HaveLen(2))
+			})
+
+			Ψ.It("filters via SEARCH", func() {
+				if op := params.OperatorSEARCH; skipFilter(op) {
+					Ψ.Skip(fmt.Sprintf("operator %q is not supported", op))
+				}
+
+				Ω.Expect(tx.Create("/objects/*", &schema.Object{ID: "doc1", Extra: []byte(`{
+					"title": "Quick brown fox",
+					"body": "The fox jumps over the lazy dog",
+					"tags": ["animal"]
+				}`)})).To(Ω.Succeed())
+				Ω.Expect(tx.Create("/objects/*", &schema.Object{ID: "doc2", Extra: []byte(`{
+					"title": "Lazy afternoon",
+					"body": "A dog sleeps, another dog barks"
+				}`)})).To(Ω.Succeed())
+
+				search := func(q string, sort ...params.SortOrder) ([]string, error) {
+					return ListScope(tx, storage.ListOptions{
+						Condition: params.Condition{params.SearchFilter(q)},
+						Sort:      sort,
+					})
+				}
+
+				// All fields
+				Ω.Expect(search("fox")).To(Ω.ConsistOf("doc1"))
+				Ω.Expect(search("dog")).To(Ω.ConsistOf("doc1", "doc2"))
+				Ω.Expect(search("LAZY dog")).To(Ω.ConsistOf("doc1", "doc2"))
+				Ω.Expect(search("dog -fox")).To(Ω.ConsistOf("doc2"))
+				Ω.Expect(search("val")).To(Ω.ConsistOf("EPR.ID"))
+				Ω.Expect(search("cat")).To(Ω.BeEmpty())
+
+				// ID
+				Ω.Expect(filter("search_id", "doc1")).To(Ω.ConsistOf("doc1"))
+
+				// Last-Modified - always false
+				Ω.Expect(filter("search_last_modified", etoa(o1.ModTime))).To(Ω.BeEmpty())
+
+				// Fields
+				Ω.Expect(filter("search_title", "lazy")).To(Ω.ConsistOf("doc2"))
+				Ω.Expect(filter("search_body", "fox")).To(Ω.ConsistOf("doc1"))
+				Ω.Expect(filter("search_tags", "animal")).To(Ω.ConsistOf("doc1"))
+				Ω.Expect(filter("search_unk", "fox")).To(Ω.BeEmpty())
+
+				// Relevance
+				Ω.Expect(search("dog", params.SortOrder{Field: params.ScoreField, Descending: true})).To(Ω.Equal([]string{"doc2", "doc1"}))
+				Ω.Expect(search("dog", params.SortOrder{Field: params.ScoreField})).To(Ω.Equal([]string{"doc1", "doc2"}))
+				Ω.Expect(SortScope(tx, "-_score")).To(Ω.HaveLen(4))
+			})
 		})
 	})
 }
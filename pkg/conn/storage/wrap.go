@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+)
+
+// Wrapper wraps a backend, e.g. to add metrics, caching or auditing.
+type Wrapper func(Backend) Backend
+
+var wrappers = make(map[string]Wrapper)
+
+// RegisterWrapper registers a new wrapper by name.
+// It will panic if multiple wrappers are registered under the same name.
+func RegisterWrapper(name string, wrapper Wrapper) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, ok := wrappers[name]; ok {
+		panic("wrapper " + name + " is already registered")
+	}
+	wrappers[name] = wrapper
+}
+
+// Wrap applies the named wrappers to a backend, in order. The first wrapper
+// wraps the backend directly.
+func Wrap(b Backend, names ...string) (Backend, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	ws := make([]Wrapper, 0, len(names))
+	for _, name := range names {
+		w, ok := wrappers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown storage wrapper %q", name)
+		}
+		ws = append(ws, w)
+	}
+
+	for _, w := range ws {
+		b = w(b)
+	}
+	return b, nil
+}
+
+// --------------------------------------------------------------------
+
+// Passthrough passes all calls to the embedded Backend, including calls to
+// optional interfaces. Wrappers can embed it and override methods as needed.
+type Passthrough struct {
+	Backend
+}
+
+// Subscribe implements Notifier interface.
+func (p Passthrough) Subscribe(fn func([]Change)) (func(), error) {
+	ntf, ok := p.Backend.(Notifier)
+	if !ok {
+		return nil, riposo.ErrNotSupported
+	}
+	return ntf.Subscribe(fn)
+}
+
+// PoolStats returns the pool stats of the embedded Backend, if available.
+func (p Passthrough) PoolStats() *schema.PoolStats {
+	if pooled, ok := p.Backend.(interface{ PoolStats() *schema.PoolStats }); ok {
+		return pooled.PoolStats()
+	}
+	return nil
+}
+
+// PassthroughTransaction passes all calls to the embedded Transaction,
+// including calls to optional interfaces. Wrappers can embed it and override
+// methods as needed.
+type PassthroughTransaction struct {
+	Transaction
+}
+
+// Restore implements Restorer interface.
+func (p PassthroughTransaction) Restore(path riposo.Path, obj *schema.Object) error {
+	rs, ok := p.Transaction.(Restorer)
+	if !ok {
+		return riposo.ErrNotSupported
+	}
+	return rs.Restore(path, obj)
+}
+
+// Aggregate implements Aggregator interface.
+func (p PassthroughTransaction) Aggregate(path riposo.Path, opt AggregateOptions) ([]AggregateGroup, error) {
+	ag, ok := p.Transaction.(Aggregator)
+	if !ok {
+		return nil, riposo.ErrNotSupported
+	}
+	return ag.Aggregate(path, opt)
+}
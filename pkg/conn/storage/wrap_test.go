@@ -0,0 +1,106 @@
+package storage_test
+
+import (
+	"context"
+
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	mems "github.com/riposo/riposo/internal/conn/memory/storage"
+)
+
+var _ = Describe("Wrap", func() {
+	var backend storage.Backend
+
+	BeforeEach(func() {
+		backend = mems.New(mock.Clock(), mock.Helpers())
+		trace = trace[:0]
+	})
+
+	AfterEach(func() {
+		Expect(backend.Close()).To(Succeed())
+	})
+
+	It("applies wrappers in order", func() {
+		wrapped, err := storage.Wrap(backend, "test.a", "test.b")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(wrapped).To(BeAssignableToTypeOf(&tracing{}))
+		Expect(wrapped.(*tracing).name).To(Equal("b"))
+		Expect(wrapped.(*tracing).Backend.(*tracing).name).To(Equal("a"))
+
+		tx, err := wrapped.Begin(context.Background(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		Expect(tx.Create("/objects/*", &schema.Object{ID: "x"})).To(Succeed())
+		Expect(trace).To(Equal([]string{"b:/objects/*", "a:/objects/*"}))
+	})
+
+	It("rejects unknown wrappers", func() {
+		_, err := storage.Wrap(backend, "test.a", "unknown")
+		Expect(err).To(MatchError(`unknown storage wrapper "unknown"`))
+	})
+
+	It("passes through optional interfaces", func() {
+		wrapped := storage.Passthrough{Backend: backend}
+		cancel, err := wrapped.Subscribe(func([]storage.Change) {})
+		Expect(err).NotTo(HaveOccurred())
+		cancel()
+		Expect(wrapped.PoolStats()).To(BeNil())
+
+		tx, err := wrapped.Begin(context.Background(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		defer tx.Rollback()
+
+		ptx := storage.PassthroughTransaction{Transaction: tx}
+		Expect(ptx.Restore("/objects/*", &schema.Object{ID: "x", ModTime: 1234})).To(Succeed())
+		Expect(ptx.Aggregate("/objects/*", storage.AggregateOptions{})).To(HaveLen(1))
+
+		ptx = storage.PassthroughTransaction{Transaction: plainTx{tx}}
+		Expect(ptx.Restore("/objects/*", &schema.Object{ID: "y"})).To(MatchError(riposo.ErrNotSupported))
+		_, err = ptx.Aggregate("/objects/*", storage.AggregateOptions{})
+		Expect(err).To(MatchError(riposo.ErrNotSupported))
+	})
+})
+
+var trace []string
+
+func init() {
+	storage.RegisterWrapper("test.a", func(b storage.Backend) storage.Backend {
+		return &tracing{Passthrough: storage.Passthrough{Backend: b}, name: "a"}
+	})
+	storage.RegisterWrapper("test.b", func(b storage.Backend) storage.Backend {
+		return &tracing{Passthrough: storage.Passthrough{Backend: b}, name: "b"}
+	})
+}
+
+type tracing struct {
+	storage.Passthrough
+	name string
+}
+
+func (b *tracing) Begin(ctx context.Context, opt *riposo.TxOptions) (storage.Transaction, error) {
+	tx, err := b.Passthrough.Begin(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &tracingTx{PassthroughTransaction: storage.PassthroughTransaction{Transaction: tx}, name: b.name}, nil
+}
+
+type tracingTx struct {
+	storage.PassthroughTransaction
+	name string
+}
+
+func (t *tracingTx) Create(path riposo.Path, obj *schema.Object) error {
+	trace = append(trace, t.name+":"+path.String())
+	return t.PassthroughTransaction.Create(path, obj)
+}
+
+type plainTx struct {
+	storage.Transaction
+}
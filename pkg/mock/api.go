@@ -13,7 +13,7 @@ import (
 // Txn inits a mock API transaction.
 func Txn() *api.Txn {
 	hlp := Helpers()
-	txn, err := api.NewTxn(context.Background(), Conns(hlp), hlp)
+	txn, err := api.NewTxn(context.Background(), Conns(hlp), hlp, nil)
 	if err != nil {
 		panic(err)
 	}
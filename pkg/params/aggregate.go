@@ -0,0 +1,96 @@
+package params
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/util"
+)
+
+var errGroupByOnly = errors.New("_group_by requires _aggregate")
+
+// AggregateFunc is an enum type.
+type AggregateFunc uint8
+
+// AggregateFunc enum.
+const (
+	AggregateCount AggregateFunc = iota
+	AggregateSum
+	AggregateAvg
+	AggregateMin
+	AggregateMax
+)
+
+func (f AggregateFunc) String() string {
+	switch f {
+	case AggregateCount:
+		return "count"
+	case AggregateSum:
+		return "sum"
+	case AggregateAvg:
+		return "avg"
+	case AggregateMin:
+		return "min"
+	case AggregateMax:
+		return "max"
+	}
+	return "?"
+}
+
+var aggregateFuncs = map[string]AggregateFunc{
+	"count": AggregateCount,
+	"sum":   AggregateSum,
+	"avg":   AggregateAvg,
+	"min":   AggregateMin,
+	"max":   AggregateMax,
+}
+
+// Aggregation describes an aggregate query. With the exception of count,
+// aggregate functions only consider numeric values.
+type Aggregation struct {
+	Func    AggregateFunc // the aggregate function
+	Field   string        // the aggregated field, blank for count
+	GroupBy []string      // fields to group by
+}
+
+// ParseAggregation parses an aggregation from _aggregate and _group_by values,
+// e.g. "sum(amount)" and "status,month". Grouping by a field named like the
+// aggregate function is rejected, as results are keyed by the function name.
+func ParseAggregation(aggregate, groupBy string) (*Aggregation, error) {
+	if aggregate == "" {
+		if groupBy != "" {
+			return nil, errGroupByOnly
+		}
+		return nil, nil
+	}
+
+	name, field := aggregate, ""
+	if pos := strings.IndexByte(aggregate, '('); pos > -1 && strings.HasSuffix(aggregate, ")") {
+		name, field = aggregate[:pos], strings.TrimSpace(aggregate[pos+1:len(aggregate)-1])
+	}
+
+	fn, ok := aggregateFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("_aggregate has unknown function %q", name)
+	} else if fn != AggregateCount && field == "" {
+		return nil, fmt.Errorf("_aggregate function %s requires a field", fn)
+	} else if fn == AggregateCount && field != "" {
+		return nil, fmt.Errorf("_aggregate function %s does not accept a field", fn)
+	}
+
+	agg := &Aggregation{Func: fn, Field: field}
+	util.SplitFunc(groupBy, ",", func(field string) {
+		if field = strings.TrimSpace(field); field != "" {
+			agg.GroupBy = append(agg.GroupBy, field)
+		}
+	})
+
+	// group keys and results share the same namespace
+	for _, field := range agg.GroupBy {
+		if field == fn.String() {
+			return nil, fmt.Errorf("_group_by field %q conflicts with _aggregate function %s", field, fn)
+		}
+	}
+	return agg, nil
+}
@@ -0,0 +1,44 @@
+package params_test
+
+import (
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/params"
+)
+
+var _ = Describe("ParseAggregation", func() {
+	It("parses", func() {
+		Expect(ParseAggregation("", "")).To(BeNil())
+		Expect(ParseAggregation("count", "")).To(Equal(&Aggregation{Func: AggregateCount}))
+		Expect(ParseAggregation("count", "status, ,month")).To(Equal(&Aggregation{
+			Func:    AggregateCount,
+			GroupBy: []string{"status", "month"},
+		}))
+		Expect(ParseAggregation("sum(amount)", "month")).To(Equal(&Aggregation{
+			Func:    AggregateSum,
+			Field:   "amount",
+			GroupBy: []string{"month"},
+		}))
+		Expect(ParseAggregation("max(sub.num)", "")).To(Equal(&Aggregation{
+			Func:  AggregateMax,
+			Field: "sub.num",
+		}))
+	})
+
+	It("fails on invalid input", func() {
+		_, err := ParseAggregation("", "status")
+		Expect(err).To(MatchError("_group_by requires _aggregate"))
+
+		_, err = ParseAggregation("median(amount)", "")
+		Expect(err).To(MatchError(`_aggregate has unknown function "median"`))
+
+		_, err = ParseAggregation("sum", "")
+		Expect(err).To(MatchError(`_aggregate function sum requires a field`))
+
+		_, err = ParseAggregation("count(amount)", "")
+		Expect(err).To(MatchError(`_aggregate function count does not accept a field`))
+
+		_, err = ParseAggregation("count", "status,count")
+		Expect(err).To(MatchError(`_group_by field "count" conflicts with _aggregate function count`))
+	})
+})
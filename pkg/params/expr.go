@@ -0,0 +1,219 @@
+package params
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/schema"
+)
+
+const maxExprDepth = 16
+
+var errExprTooDeep = errors.New("expression is nested too deeply")
+
+// ParseExpr parses a boolean filter expression into a filter tree, e.g.:
+//
+//	or(eq(status,"open"),and(min_priority(3),has_assignee))
+//
+// Expressions are either and(...)/or(...) combinations, operator calls with a
+// field and values, such as in(status,"open","new"), or prefixed field calls
+// as known from query filters, such as min_priority(3). Omitted values default
+// to true, e.g. has_assignee or has(assignee).
+func ParseExpr(s string) (Filter, error) {
+	p := &exprParser{s: s}
+	flt, err := p.expr(0)
+	if err != nil {
+		return Filter{}, err
+	}
+	if p.skipSpace(); p.pos < len(p.s) {
+		return Filter{}, p.unexpected()
+	}
+	return flt, nil
+}
+
+// exprOperators maps operator names to operators.
+var exprOperators = func() map[string]Operator {
+	ops := map[string]Operator{"eq": OperatorEQ}
+	for _, ent := range prefixMap {
+		ops[strings.TrimSuffix(ent.Prefix, "_")] = ent.Operator
+	}
+	return ops
+}()
+
+type exprParser struct {
+	s   string
+	pos int
+}
+
+func (p *exprParser) expr(depth int) (Filter, error) {
+	if depth > maxExprDepth {
+		return Filter{}, errExprTooDeep
+	}
+
+	name, quoted, err := p.token()
+	if err != nil {
+		return Filter{}, err
+	} else if name == "" || quoted {
+		return Filter{}, p.unexpected()
+	}
+
+	// bare field names
+	if !p.accept('(') {
+		field, op := splitPrefix(name)
+		return p.filter(field, op, []schema.Value{schema.ParseValue("true")})
+	}
+
+	// logical combinations
+	switch name {
+	case "and", "or":
+		flt := Filter{Operator: OperatorAND}
+		if name == "or" {
+			flt.Operator = OperatorOR
+		}
+		for {
+			sub, err := p.expr(depth + 1)
+			if err != nil {
+				return Filter{}, err
+			}
+			flt.Nested = append(flt.Nested, sub)
+
+			if !p.accept(',') {
+				break
+			}
+		}
+		if !p.accept(')') {
+			return Filter{}, p.unexpected()
+		}
+		return flt, nil
+	}
+
+	// operator calls
+	if op, ok := exprOperators[name]; ok {
+		field, quoted, err := p.token()
+		if err != nil {
+			return Filter{}, err
+		} else if field == "" || quoted {
+			return Filter{}, p.unexpected()
+		}
+
+		if p.accept(')') {
+			return p.filter(field, op, []schema.Value{schema.ParseValue("true")})
+		} else if !p.accept(',') {
+			return Filter{}, p.unexpected()
+		}
+
+		values, err := p.values(op)
+		if err != nil {
+			return Filter{}, err
+		}
+		return p.filter(field, op, values)
+	}
+
+	// prefixed field calls
+	field, op := splitPrefix(name)
+	values, err := p.values(op)
+	if err != nil {
+		return Filter{}, err
+	}
+	return p.filter(field, op, values)
+}
+
+// values parses a comma-separated list of values, terminated by ')'.
+func (p *exprParser) values(op Operator) ([]schema.Value, error) {
+	var values []schema.Value
+	for {
+		tok, quoted, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case quoted, op == OperatorSEARCH:
+			values = append(values, schema.StringValue(tok))
+		case tok != "":
+			values = append(values, schema.ParseValue(tok))
+		default:
+			return nil, p.unexpected()
+		}
+
+		if !p.accept(',') {
+			break
+		}
+	}
+	if !p.accept(')') {
+		return nil, p.unexpected()
+	}
+	return values, nil
+}
+
+func (p *exprParser) filter(field string, op Operator, values []schema.Value) (Filter, error) {
+	switch op {
+	case OperatorIN, OperatorEXCLUDE, OperatorContainsAny:
+	default:
+		if len(values) != 1 {
+			return Filter{}, fmt.Errorf("operator %s expects a single value", op)
+		}
+	}
+
+	flt := Filter{Field: field, Operator: op, Values: values}
+	if !flt.isValid() {
+		return Filter{}, fmt.Errorf("invalid filter on %q", field)
+	}
+	return flt, nil
+}
+
+// token reads the next identifier or quoted string.
+func (p *exprParser) token() (string, bool, error) {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == '"' {
+		return p.quoted()
+	}
+
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(`(),"`, rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return strings.TrimSpace(p.s[start:p.pos]), false, nil
+}
+
+// quoted reads a JSON-encoded string.
+func (p *exprParser) quoted() (string, bool, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		if c := p.s[p.pos]; c == '\\' {
+			p.pos++
+		} else if c == '"' {
+			p.pos++
+
+			var str string
+			if err := json.Unmarshal([]byte(p.s[start:p.pos]), &str); err != nil {
+				return "", true, fmt.Errorf("invalid string at position %d", start)
+			}
+			return str, true, nil
+		}
+	}
+	return "", true, fmt.Errorf("unterminated string at position %d", start)
+}
+
+func (p *exprParser) accept(c byte) bool {
+	if p.skipSpace(); p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func (p *exprParser) unexpected() error {
+	if p.pos >= len(p.s) {
+		return errors.New("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at position %d", p.s[p.pos], p.pos)
+}
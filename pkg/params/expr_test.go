@@ -0,0 +1,82 @@
+package params_test
+
+import (
+	"github.com/riposo/riposo/pkg/schema"
+	"github.com/tidwall/gjson"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/params"
+)
+
+var _ = Describe("ParseExpr", func() {
+	str := func(s string) schema.Value { return schema.StringValue(s) }
+	yes := schema.Value{Type: gjson.True, Raw: "true"}
+
+	It("parses", func() {
+		Expect(ParseExpr(`or(eq(status,"open"),and(min_priority(3),has_assignee))`)).To(Equal(Filter{
+			Operator: OperatorOR,
+			Nested: []Filter{
+				{Field: "status", Operator: OperatorEQ, Values: []schema.Value{str("open")}},
+				{Operator: OperatorAND, Nested: []Filter{
+					{Field: "priority", Operator: OperatorMIN, Values: []schema.Value{{Type: gjson.Number, Raw: "3", Num: 3}}},
+					{Field: "assignee", Operator: OperatorHAS, Values: []schema.Value{yes}},
+				}},
+			},
+		}))
+	})
+
+	It("parses operator calls", func() {
+		Expect(ParseExpr(`in(status, "open", new, 1)`)).To(Equal(Filter{
+			Field:    "status",
+			Operator: OperatorIN,
+			Values:   []schema.Value{str("open"), str("new"), {Type: gjson.Number, Raw: "1", Num: 1}},
+		}))
+		Expect(ParseExpr(`has(assignee)`)).To(Equal(Filter{
+			Field:    "assignee",
+			Operator: OperatorHAS,
+			Values:   []schema.Value{yes},
+		}))
+		Expect(ParseExpr(`like(title, "a,b(c)")`)).To(Equal(Filter{
+			Field:    "title",
+			Operator: OperatorLIKE,
+			Values:   []schema.Value{str("a,b(c)")},
+		}))
+		Expect(ParseExpr(`search_title(42)`)).To(Equal(Filter{
+			Field:    "title",
+			Operator: OperatorSEARCH,
+			Values:   []schema.Value{str("42")},
+		}))
+		Expect(ParseExpr(`done`)).To(Equal(Filter{
+			Field:    "done",
+			Operator: OperatorEQ,
+			Values:   []schema.Value{yes},
+		}))
+	})
+
+	It("fails on invalid expressions", func() {
+		_, err := ParseExpr(``)
+		Expect(err).To(MatchError(`unexpected end of expression`))
+
+		_, err = ParseExpr(`or(a,b`)
+		Expect(err).To(MatchError(`unexpected end of expression`))
+
+		_, err = ParseExpr(`or(a,b))`)
+		Expect(err).To(MatchError(`unexpected ')' at position 7`))
+
+		_, err = ParseExpr(`eq(status)x`)
+		Expect(err).To(MatchError(`unexpected 'x' at position 10`))
+
+		_, err = ParseExpr(`eq(status,"open)`)
+		Expect(err).To(MatchError(`unterminated string at position 10`))
+
+		_, err = ParseExpr(`gt(num,1,2)`)
+		Expect(err).To(MatchError(`operator > expects a single value`))
+
+		_, err = ParseExpr(`and()`)
+		Expect(err).To(MatchError(`unexpected ')' at position 4`))
+
+		_, err = ParseExpr(`or(or(or(or(or(or(or(or(or(or(or(or(or(or(or(or(or(a)))))))))))))))))`)
+		Expect(err).To(MatchError(`expression is nested too deeply`))
+	})
+})
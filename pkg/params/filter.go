@@ -26,23 +26,18 @@ func (cs ConditionSet) Compact() ConditionSet {
 	return rs
 }
 
-// Filter expresses a filterable condition.
+// Filter expresses a filterable condition. Filters with an AND or OR operator
+// form an expression tree and combine their Nested filters instead.
 type Filter struct {
 	Field    string         // the field name
 	Operator Operator       // the comparison operator
 	Values   []schema.Value // slice of parsed values
+	Nested   []Filter       // nested filters, for AND and OR only
 }
 
 // ParseFilter parses a filter from a field-value string pair.
 func ParseFilter(field, value string) Filter {
-	operator := OperatorEQ
-	for _, ent := range prefixMap {
-		if strings.HasPrefix(field, ent.Prefix) {
-			field = strings.TrimPrefix(field, ent.Prefix)
-			operator = ent.Operator
-			break
-		}
-	}
+	field, operator := splitPrefix(field)
 
 	values := make([]schema.Value, 0, 1)
 	switch operator {
@@ -50,6 +45,10 @@ func ParseFilter(field, value string) Filter {
 		util.SplitFunc(value, ",", func(val string) {
 			values = append(values, schema.ParseValue(val))
 		})
+	case OperatorSEARCH:
+		if value != "" {
+			values = append(values, schema.StringValue(value))
+		}
 	default:
 		values = append(values, schema.ParseValue(value))
 	}
@@ -57,8 +56,31 @@ func ParseFilter(field, value string) Filter {
 	return Filter{Field: field, Operator: operator, Values: values}
 }
 
+// SearchFilter creates a full-text search filter across all fields.
+func SearchFilter(query string) Filter {
+	var values []schema.Value
+	if query != "" {
+		values = append(values, schema.StringValue(query))
+	}
+	return Filter{Operator: OperatorSEARCH, Values: values}
+}
+
+// IsSearch returns true if the filter is a full-text search. Search filters
+// with a blank field apply to all fields.
+func (f Filter) IsSearch() bool {
+	return f.Operator == OperatorSEARCH
+}
+
+// IsNested returns true if the filter combines nested filters.
+func (f Filter) IsNested() bool {
+	return f.Operator == OperatorAND || f.Operator == OperatorOR
+}
+
 func (f Filter) isValid() bool {
-	return f.Field != "" && len(f.Values) > 0
+	if f.IsNested() {
+		return len(f.Nested) > 0
+	}
+	return (f.Field != "" || f.IsSearch()) && len(f.Values) > 0
 }
 
 // Value returns the value at index.
@@ -68,3 +90,13 @@ func (f Filter) Value(index int) schema.Value {
 	}
 	return schema.Value{}
 }
+
+// splitPrefix separates the operator prefix from a field name.
+func splitPrefix(field string) (string, Operator) {
+	for _, ent := range prefixMap {
+		if strings.HasPrefix(field, ent.Prefix) {
+			return strings.TrimPrefix(field, ent.Prefix), ent.Operator
+		}
+	}
+	return field, OperatorEQ
+}
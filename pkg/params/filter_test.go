@@ -90,5 +90,25 @@ var _ = Describe("Filter", func() {
 				{Type: gjson.String, Raw: `"z"`, Str: "z"},
 			},
 		}))
+
+		Expect(ParseFilter("search_title", `hello, world`)).To(Equal(Filter{
+			Field:    "title",
+			Operator: OperatorSEARCH,
+			Values:   []schema.Value{{Type: gjson.String, Raw: `"hello, world"`, Str: "hello, world"}},
+		}))
+
+		Expect(ParseFilter("search_title", `3`)).To(Equal(Filter{
+			Field:    "title",
+			Operator: OperatorSEARCH,
+			Values:   []schema.Value{{Type: gjson.String, Raw: `"3"`, Str: "3"}},
+		}))
+	})
+
+	It("creates search filters", func() {
+		Expect(SearchFilter("hello world")).To(Equal(Filter{
+			Operator: OperatorSEARCH,
+			Values:   []schema.Value{{Type: gjson.String, Raw: `"hello world"`, Str: "hello world"}},
+		}))
+		Expect(SearchFilter("").Values).To(BeEmpty())
 	})
 })
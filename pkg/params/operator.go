@@ -29,6 +29,12 @@ func (o Operator) String() string {
 		return "CONTAINS"
 	case OperatorContainsAny:
 		return "CONTAINS ANY"
+	case OperatorSEARCH:
+		return "SEARCH"
+	case OperatorAND:
+		return "AND"
+	case OperatorOR:
+		return "OR"
 	}
 	return "?"
 }
@@ -47,6 +53,9 @@ const (
 	OperatorHAS
 	OperatorContainsAny
 	OperatorContains
+	OperatorSEARCH
+	OperatorAND // nested filters must all match
+	OperatorOR  // any of the nested filters must match
 )
 
 var prefixMap = []struct {
@@ -65,4 +74,12 @@ var prefixMap = []struct {
 	{Operator: OperatorEXCLUDE, Prefix: "exclude_"},
 	{Operator: OperatorContainsAny, Prefix: "contains_any_"},
 	{Operator: OperatorContains, Prefix: "contains_"},
+	{Operator: OperatorSEARCH, Prefix: "search_"},
+}
+
+// EachPrefix iterates over all supported filter prefixes.
+func EachPrefix(iter func(Operator, string)) {
+	for _, ent := range prefixMap {
+		iter(ent.Operator, ent.Prefix)
+	}
 }
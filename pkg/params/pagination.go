@@ -1,43 +1,72 @@
 package params
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"net/url"
+	"strings"
 
 	"github.com/riposo/riposo/pkg/schema"
 )
 
-var ErrNoToken = errors.New("no pagination token")
+var (
+	// ErrNoToken is returned when there is no pagination token.
+	ErrNoToken = errors.New("no pagination token")
+	// ErrInvalidToken is returned when a pagination token cannot be decoded.
+	ErrInvalidToken = errors.New("_token has invalid content")
+	// ErrTokenSignature is returned when a pagination token has been tampered with.
+	ErrTokenSignature = errors.New("_token has an invalid signature")
+	// ErrTokenQuery is returned when a pagination token was issued for a different query.
+	ErrTokenQuery = errors.New("_token was issued for a different query")
+	// ErrTokenScore is returned when a pagination token is combined with relevance sorting.
+	ErrTokenScore = errors.New("_token cannot be combined with _sort by _score")
+)
 
 // Pagination is a decoded token.
 type Pagination struct {
 	Nonce   string                  `json:"nonce,omitempty"`
 	LastObj map[string]schema.Value `json:"last_object,omitempty"`
+	Query   string                  `json:"query,omitempty"`
 }
 
-// ParseToken parses a token from a string.
+// ParseToken parses a token from a string and verifies its signature.
 // It may return ErrNoToken if there is no pagination token.
-func ParseToken(s string) (*Pagination, error) {
+func ParseToken(s string, secret []byte) (*Pagination, error) {
 	if s == "" {
 		return nil, ErrNoToken
 	}
 
-	raw, err := base64.RawURLEncoding.DecodeString(s)
+	pos := strings.LastIndexByte(s, '.')
+	if pos < 0 {
+		return nil, ErrInvalidToken
+	}
+
+	payload, sig := s[:pos], s[pos+1:]
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
 	if err != nil {
-		return nil, err
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal(mac, sign(payload, secret)) {
+		return nil, ErrTokenSignature
 	}
 
-	var t *Pagination
-	if err := json.Unmarshal(raw, &t); err != nil {
-		return nil, err
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidToken
 	}
 
+	var t *Pagination
+	if err := json.Unmarshal(raw, &t); err != nil || t == nil {
+		return nil, ErrInvalidToken
+	}
 	return t, nil
 }
 
-// Encode encodes a pagination token as an URL-safe base64 string.
-func (t *Pagination) Encode() (string, error) {
+// Encode encodes a pagination token as an URL-safe base64 string and signs it.
+func (t *Pagination) Encode(secret []byte) (string, error) {
 	if t == nil {
 		return "", nil
 	}
@@ -46,49 +75,86 @@ func (t *Pagination) Encode() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return base64.RawURLEncoding.EncodeToString(raw), nil
+
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(sign(payload, secret)), nil
 }
 
-// Conditions constructs a ConditionSet from the received
-// Pagination token.
-func (t *Pagination) Conditions() ConditionSet {
+// Conditions constructs a ConditionSet from the received Pagination token.
+// The sort order must include a unique tiebreaker, see KeysetSort, and must
+// not include relevance scores, see Params.SortsByScore. For sort
+// fields a, b and last values x, y, it expands to:
+//
+//	(a > x) OR (a = x AND b > y)
+func (t *Pagination) Conditions(sort []SortOrder) ConditionSet {
 	if t == nil || len(t.LastObj) == 0 {
 		return nil
 	}
 
-	fields := make([]field, 0, len(t.LastObj))
-	for name, val := range t.LastObj {
-		fields = append(fields, field{Name: name, Value: val})
-	}
-
-	conds := make(ConditionSet, 0, len(fields))
-	for p := 0; p < len(fields); p++ {
-		cond := make(Condition, 0, len(fields))
-		for i, fv := range fields {
-			op := OperatorEQ
-			if i == p {
-				op = OperatorGT
-			}
+	conds := make(ConditionSet, 0, len(sort))
+	for p, so := range sort {
+		cond := make(Condition, 0, p+1)
+		for _, prev := range sort[:p] {
 			cond = append(cond, Filter{
-				Field:    fv.Name,
-				Operator: op,
-				Values:   []schema.Value{fv.Value},
+				Field:    prev.Field,
+				Operator: OperatorEQ,
+				Values:   []schema.Value{t.lastValue(prev.Field)},
 			})
 		}
+
+		op := OperatorGT
+		if so.Descending {
+			op = OperatorLT
+		}
+		cond = append(cond, Filter{
+			Field:    so.Field,
+			Operator: op,
+			Values:   []schema.Value{t.lastValue(so.Field)},
+		})
 		conds = append(conds, cond)
 	}
 	return conds
 }
 
-func newPagination(nonce string, lastObj *schema.Object, sort []SortOrder) *Pagination {
+func (t *Pagination) lastValue(field string) schema.Value {
+	if v, ok := t.LastObj[field]; ok {
+		return v
+	}
+	return schema.ParseValue("")
+}
+
+func newPagination(nonce string, lastObj *schema.Object, sort []SortOrder, query url.Values) *Pagination {
 	t := &Pagination{
 		Nonce:   nonce,
 		LastObj: make(map[string]schema.Value, len(sort)),
+		Query:   queryFingerprint(query),
 	}
 	for _, s := range sort {
-		if v := lastObj.Get(s.Field); !v.IsNull() {
+		if v := lastObj.Get(s.Field); v.Exists() {
 			t.LastObj[s.Field] = v
+		} else {
+			t.LastObj[s.Field] = schema.ParseValue("")
 		}
 	}
 	return t
 }
+
+// queryFingerprint returns a fingerprint of the query, ignoring pagination
+// parameters.
+func queryFingerprint(query url.Values) string {
+	norm := make(url.Values, len(query))
+	for key, vals := range query {
+		if key != "_token" && key != "_limit" {
+			norm[key] = vals
+		}
+	}
+
+	sum := sha256.Sum256([]byte(norm.Encode()))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+func sign(payload string, secret []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	_, _ = h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -1,6 +1,8 @@
 package params_test
 
 import (
+	"strings"
+
 	"github.com/riposo/riposo/pkg/schema"
 	"github.com/tidwall/gjson"
 
@@ -10,48 +12,85 @@ import (
 )
 
 var _ = Describe("Pagination", func() {
+	secret := []byte("secret")
+
 	It("parses blank", func() {
-		_, err := ParseToken("")
+		_, err := ParseToken("", secret)
 		Expect(err).To(MatchError(ErrNoToken))
 	})
 
 	It("encodes/parses", func() {
 		var t *Pagination
-		Expect(t.Encode()).To(Equal(""))
+		Expect(t.Encode(secret)).To(Equal(""))
 
 		t = &Pagination{
 			Nonce:   "x",
 			LastObj: map[string]schema.Value{"field": {Type: gjson.Number, Raw: "33", Num: 33.0}},
 		}
-		s, err := t.Encode()
+		s, err := t.Encode(secret)
 		Expect(err).NotTo(HaveOccurred())
-		Expect(len(s)).To(BeNumerically("~", 54, 10))
-		Expect(ParseToken(s)).To(Equal(t))
+		Expect(len(s)).To(BeNumerically("~", 98, 10))
+		Expect(ParseToken(s, secret)).To(Equal(t))
+	})
+
+	It("verifies signatures", func() {
+		s, err := (&Pagination{Nonce: "x"}).Encode(secret)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = ParseToken(s, []byte("other"))
+		Expect(err).To(MatchError(ErrTokenSignature))
+
+		forged, err := (&Pagination{Nonce: "y"}).Encode(secret)
+		Expect(err).NotTo(HaveOccurred())
+		tampered := forged[:strings.IndexByte(forged, '.')] + s[strings.IndexByte(s, '.'):]
+		_, err = ParseToken(tampered, secret)
+		Expect(err).To(MatchError(ErrTokenSignature))
+
+		_, err = ParseToken("bad", secret)
+		Expect(err).To(MatchError(ErrInvalidToken))
+		_, err = ParseToken("bad.!!!", secret)
+		Expect(err).To(MatchError(ErrInvalidToken))
 	})
 
 	It("generates conditions", func() {
 		var t *Pagination
-		Expect(t.Conditions()).To(BeNil())
+		Expect(t.Conditions(nil)).To(BeNil())
 
 		t = &Pagination{}
-		Expect(t.Conditions()).To(BeNil())
+		Expect(t.Conditions(nil)).To(BeNil())
 
 		t = &Pagination{
 			LastObj: map[string]schema.Value{
 				"field": schema.ParseValue("33"),
 				"other": schema.StringValue("foo"),
+				"id":    schema.StringValue("x"),
 			},
 		}
 
-		Expect(t.Conditions()).To(ConsistOf(
-			ConsistOf(
-				Filter{Field: "field", Operator: OperatorGT, Values: []schema.Value{schema.ParseValue("33")}},
-				Filter{Field: "other", Operator: OperatorEQ, Values: []schema.Value{schema.StringValue("foo")}},
-			),
-			ConsistOf(
-				Filter{Field: "field", Operator: OperatorEQ, Values: []schema.Value{schema.ParseValue("33")}},
-				Filter{Field: "other", Operator: OperatorGT, Values: []schema.Value{schema.StringValue("foo")}},
-			),
-		))
+		Expect(t.Conditions(KeysetSort([]SortOrder{{Field: "field"}, {Field: "other", Descending: true}}))).To(Equal(ConditionSet{
+			{
+				{Field: "field", Operator: OperatorGT, Values: []schema.Value{schema.ParseValue("33")}},
+			},
+			{
+				{Field: "field", Operator: OperatorEQ, Values: []schema.Value{schema.ParseValue("33")}},
+				{Field: "other", Operator: OperatorLT, Values: []schema.Value{schema.StringValue("foo")}},
+			},
+			{
+				{Field: "field", Operator: OperatorEQ, Values: []schema.Value{schema.ParseValue("33")}},
+				{Field: "other", Operator: OperatorEQ, Values: []schema.Value{schema.StringValue("foo")}},
+				{Field: "id", Operator: OperatorGT, Values: []schema.Value{schema.StringValue("x")}},
+			},
+		}))
+
+		// missing values are treated as null
+		Expect(t.Conditions([]SortOrder{{Field: "unknown"}, {Field: "id", Descending: true}})).To(Equal(ConditionSet{
+			{
+				{Field: "unknown", Operator: OperatorGT, Values: []schema.Value{schema.ParseValue("")}},
+			},
+			{
+				{Field: "unknown", Operator: OperatorEQ, Values: []schema.Value{schema.ParseValue("")}},
+				{Field: "id", Operator: OperatorLT, Values: []schema.Value{schema.StringValue("x")}},
+			},
+		}))
 	})
 })
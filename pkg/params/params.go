@@ -2,14 +2,13 @@ package params
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"strconv"
 
 	"github.com/riposo/riposo/pkg/schema"
 )
 
-var errInvalidToken = errors.New("_token has invalid content")
-
 type field struct {
 	Name string
 	schema.Value
@@ -21,10 +20,11 @@ type Params struct {
 	Sort      []SortOrder
 	Limit     int
 	Token     *Pagination
+	Aggregate *Aggregation
 }
 
-// Parse parses query params.
-func Parse(query url.Values, maxLimit int) (*Params, error) {
+// Parse parses query params. Pagination tokens are verified using the secret.
+func Parse(query url.Values, maxLimit int, secret []byte) (*Params, error) {
 	pms := &Params{Limit: maxLimit}
 	for key := range query {
 		switch key {
@@ -33,10 +33,12 @@ func Parse(query url.Values, maxLimit int) (*Params, error) {
 		case "_sort":
 			pms.Sort = ParseSort(query.Get(key))
 		case "_token":
-			if token, err := ParseToken(query.Get(key)); errors.Is(err, ErrNoToken) {
+			if token, err := ParseToken(query.Get(key), secret); errors.Is(err, ErrNoToken) {
 				// skip
 			} else if err != nil {
-				return nil, errInvalidToken
+				return nil, err
+			} else if token.Query != queryFingerprint(query) {
+				return nil, ErrTokenQuery
 			} else {
 				pms.Token = token
 			}
@@ -48,6 +50,20 @@ func Parse(query url.Values, maxLimit int) (*Params, error) {
 			if filter := ParseFilter("gt_last_modified", query.Get(key)); filter.isValid() {
 				pms.Condition = append(pms.Condition, filter)
 			}
+		case "q":
+			if filter := SearchFilter(query.Get(key)); filter.isValid() {
+				pms.Condition = append(pms.Condition, filter)
+			}
+		case "_filter":
+			if value := query.Get(key); value != "" {
+				filter, err := ParseExpr(value)
+				if err != nil {
+					return nil, fmt.Errorf("_filter is invalid: %w", err)
+				}
+				pms.Condition = append(pms.Condition, filter)
+			}
+		case "_aggregate", "_group_by":
+			// parsed below
 		case "_fields":
 			// TODO: respect field limitation, eventually
 		default:
@@ -56,17 +72,46 @@ func Parse(query url.Values, maxLimit int) (*Params, error) {
 			}
 		}
 	}
+
+	// relevance scores are not stable across pages
+	if pms.Token != nil && pms.SortsByScore() {
+		return nil, ErrTokenScore
+	}
+
+	agg, err := ParseAggregation(query.Get("_aggregate"), query.Get("_group_by"))
+	if err != nil {
+		return nil, err
+	}
+	pms.Aggregate = agg
+
 	return pms, nil
 }
 
-// NextPageURL generates a next-page paginated URL.
-func (p *Params) NextPageURL(u *url.URL, nonce string, lastObj *schema.Object) (*url.URL, error) {
-	token, err := newPagination(nonce, lastObj, p.Sort).Encode()
+// KeysetSort returns the sort order, including a unique tiebreaker.
+func (p *Params) KeysetSort() []SortOrder {
+	return KeysetSort(p.Sort)
+}
+
+// SortsByScore returns true if results are sorted by relevance. Such results
+// cannot be paginated.
+func (p *Params) SortsByScore() bool {
+	for _, so := range p.Sort {
+		if so.Field == ScoreField {
+			return true
+		}
+	}
+	return false
+}
+
+// NextPageURL generates a next-page paginated URL with a token, signed using
+// the secret.
+func (p *Params) NextPageURL(u *url.URL, nonce string, lastObj *schema.Object, secret []byte) (*url.URL, error) {
+	q := u.Query()
+	token, err := newPagination(nonce, lastObj, p.KeysetSort(), q).Encode(secret)
 	if err != nil {
 		return nil, err
 	}
 
-	q := u.Query()
 	q.Set("_limit", strconv.Itoa(p.Limit))
 	q.Set("_token", token)
 
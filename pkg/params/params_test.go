@@ -14,9 +14,10 @@ import (
 
 var _ = Describe("Params", func() {
 	sampleURL := mustURL("https://example.com:8888/v1/buckets?_sort=field")
+	secret := []byte("secret")
 
 	It("parses", func() {
-		Expect(Parse(sampleURL.Query(), 25)).To(Equal(&Params{
+		Expect(Parse(sampleURL.Query(), 25, secret)).To(Equal(&Params{
 			Limit: 25,
 			Sort: []SortOrder{
 				{Field: "field"},
@@ -25,7 +26,7 @@ var _ = Describe("Params", func() {
 	})
 
 	It("parses _before and _since", func() {
-		pms, err := Parse(url.Values{"_since": {"1515151515000"}, "_before": {"1616161616000"}}, 25)
+		pms, err := Parse(url.Values{"_since": {"1515151515000"}, "_before": {"1616161616000"}}, 25, secret)
 		Expect(err).NotTo(HaveOccurred())
 		Expect(pms.Condition).To(ConsistOf(
 			Filter{
@@ -42,25 +43,95 @@ var _ = Describe("Params", func() {
 	})
 
 	It("parses filters", func() {
-		Expect(Parse(nil, 25)).To(Equal(&Params{
+		Expect(Parse(nil, 25, secret)).To(Equal(&Params{
 			Limit: 25,
 		}))
 	})
 
+	It("parses q", func() {
+		pms, err := Parse(url.Values{"q": {"hello world"}, "_sort": {"-_score"}}, 25, secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pms.Condition).To(ConsistOf(
+			Filter{
+				Operator: OperatorSEARCH, Values: []schema.Value{
+					{Type: gjson.String, Raw: `"hello world"`, Str: "hello world"},
+				},
+			},
+		))
+		Expect(pms.Sort).To(Equal([]SortOrder{{Field: ScoreField, Descending: true}}))
+
+		pms, err = Parse(url.Values{"q": {""}}, 25, secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pms.Condition).To(BeEmpty())
+	})
+
+	It("parses _filter", func() {
+		pms, err := Parse(url.Values{"_filter": {`or(a,gt_b(1))`}, "c": {"2"}}, 25, secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pms.Condition).To(ConsistOf(
+			Filter{Operator: OperatorOR, Nested: []Filter{
+				{Field: "a", Operator: OperatorEQ, Values: []schema.Value{{Type: gjson.True, Raw: "true"}}},
+				{Field: "b", Operator: OperatorGT, Values: []schema.Value{{Type: gjson.Number, Raw: "1", Num: 1}}},
+			}},
+			Filter{Field: "c", Operator: OperatorEQ, Values: []schema.Value{{Type: gjson.Number, Raw: "2", Num: 2}}},
+		))
+
+		_, err = Parse(url.Values{"_filter": {`or(a`}}, 25, secret)
+		Expect(err).To(MatchError("_filter is invalid: unexpected end of expression"))
+	})
+
 	It("fails on bad tokens", func() {
-		_, err := Parse(url.Values{"_token": {"bad"}}, 25)
+		_, err := Parse(url.Values{"_token": {"bad"}}, 25, secret)
 		Expect(err).To(MatchError("_token has invalid content"))
 	})
 
 	It("generates NextPageURL", func() {
-		pp, err := Parse(sampleURL.Query(), 20)
+		pp, err := Parse(sampleURL.Query(), 20, secret)
 		Expect(err).NotTo(HaveOccurred())
 
 		nu, err := pp.NextPageURL(sampleURL, "x", &schema.Object{
+			ID:    "id1",
 			Extra: []byte(`{"field": 33}`),
-		})
+		}, secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nu.Query().Get("_limit")).To(Equal("20"))
+		Expect(nu.Query().Get("_sort")).To(Equal("field"))
+
+		np, err := Parse(nu.Query(), 20, secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(np.Token.Nonce).To(Equal("x"))
+		Expect(np.Token.LastObj).To(Equal(map[string]schema.Value{
+			"field": {Type: gjson.Number, Raw: "33", Num: 33},
+			"id":    {Type: gjson.String, Raw: `"id1"`, Str: "id1"},
+		}))
+
+		// different limits are permitted
+		q := nu.Query()
+		q.Set("_limit", "10")
+		_, err = Parse(q, 20, secret)
+		Expect(err).NotTo(HaveOccurred())
+
+		// but the query must match
+		q.Set("_sort", "-field")
+		_, err = Parse(q, 20, secret)
+		Expect(err).To(MatchError(ErrTokenQuery))
+
+		// and the token must be signed with the same secret
+		_, err = Parse(nu.Query(), 20, []byte("other"))
+		Expect(err).To(MatchError(ErrTokenSignature))
+	})
+
+	It("rejects tokens for relevance sorting", func() {
+		u, _ := url.Parse("/?q=fox&_sort=-_score")
+		pp, err := Parse(u.Query(), 20, secret)
 		Expect(err).NotTo(HaveOccurred())
-		Expect(nu.String()).To(Equal("https://example.com:8888/v1/buckets?_limit=20&_sort=field&_token=eyJub25jZSI6IngiLCJsYXN0X29iamVjdCI6eyJmaWVsZCI6MzN9fQ"))
+		Expect(pp.SortsByScore()).To(BeTrue())
+
+		nu, err := pp.NextPageURL(u, "", &schema.Object{ID: "id1"}, secret)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = Parse(nu.Query(), 20, secret)
+		Expect(err).To(MatchError(ErrTokenScore))
 	})
 })
 
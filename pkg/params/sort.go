@@ -2,6 +2,10 @@ package params
 
 import "strings"
 
+// ScoreField is a virtual field which allows to sort full-text search results
+// by relevance.
+const ScoreField = "_score"
+
 // SortOrder determines sorting order.
 type SortOrder struct {
 	Field      string
@@ -26,6 +30,20 @@ func ParseSort(value string) (sort []SortOrder) {
 	return
 }
 
+// KeysetSort appends the id as a unique tiebreaker to the sort order, unless
+// already included. Keyset pagination requires a deterministic order.
+func KeysetSort(sort []SortOrder) []SortOrder {
+	for _, so := range sort {
+		if so.Field == "id" {
+			return sort
+		}
+	}
+
+	res := make([]SortOrder, 0, len(sort)+1)
+	res = append(res, sort...)
+	return append(res, SortOrder{Field: "id"})
+}
+
 func appendSO(t []SortOrder, so SortOrder) []SortOrder {
 	for _, x := range t {
 		if x.Field == so.Field {
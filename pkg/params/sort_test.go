@@ -24,3 +24,17 @@ var _ = Describe("ParseSort", func() {
 		}))
 	})
 })
+
+var _ = Describe("KeysetSort", func() {
+	It("appends id", func() {
+		Expect(KeysetSort(nil)).To(Equal([]SortOrder{{Field: "id"}}))
+		Expect(KeysetSort([]SortOrder{{Field: "a", Descending: true}})).To(Equal([]SortOrder{
+			{Field: "a", Descending: true},
+			{Field: "id"},
+		}))
+		Expect(KeysetSort([]SortOrder{{Field: "id", Descending: true}, {Field: "a"}})).To(Equal([]SortOrder{
+			{Field: "id", Descending: true},
+			{Field: "a"},
+		}))
+	})
+})
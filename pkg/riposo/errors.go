@@ -1,5 +1,7 @@
 package riposo
 
+import "errors"
+
 // ErrCode is a specific internal error code.
 type ErrCode int
 
@@ -25,3 +27,6 @@ const (
 	ErrCodeServiceDeprecated     ErrCode = 202
 	ErrCodeUndefined             ErrCode = 999
 )
+
+// ErrNotSupported is returned when a backend does not support a feature.
+var ErrNotSupported = errors.New("not supported by backend")
@@ -0,0 +1,27 @@
+package riposo
+
+// IsolationLevel is the isolation level of a transaction.
+type IsolationLevel int
+
+// Isolation levels.
+const (
+	IsolationDefault IsolationLevel = iota
+	IsolationReadCommitted
+	IsolationRepeatableRead
+	IsolationSerializable
+)
+
+// TxOptions contain options for new backend transactions.
+type TxOptions struct {
+	// ReadOnly marks transactions which do not modify data. Backends may
+	// reject writes within read-only transactions and route them to replicas.
+	ReadOnly bool
+	// Isolation is the requested isolation level. Backends may use a stricter
+	// level than requested.
+	Isolation IsolationLevel
+}
+
+// IsReadOnly returns true if options mark read-only transactions.
+func (o *TxOptions) IsReadOnly() bool {
+	return o != nil && o.ReadOnly
+}
@@ -43,6 +43,17 @@ func InternalError(err error) *Error {
 	}
 }
 
+// BackendError generates an Error from a temporary backend failure, such as a
+// timeout.
+func BackendError(err error) *Error {
+	return &Error{
+		StatusCode: http.StatusServiceUnavailable,
+		ErrCode:    riposo.ErrCodeBackend,
+		Text:       http.StatusText(http.StatusServiceUnavailable),
+		Message:    err.Error(),
+	}
+}
+
 // BadRequest generates an Error.
 //
 //nolint:errorlint
@@ -163,6 +174,28 @@ var NotModified = &Error{
 	StatusCode: http.StatusNotModified,
 }
 
+// StatusClientClosedRequest is a non-standard status code, used when clients
+// close the connection before a response could be sent.
+const StatusClientClosedRequest = 499
+
+// RequestTimeout is a standard error response for requests that exceed their
+// deadline.
+var RequestTimeout = &Error{
+	StatusCode: http.StatusServiceUnavailable,
+	ErrCode:    riposo.ErrCodeBackend,
+	Text:       http.StatusText(http.StatusServiceUnavailable),
+	Message:    "Request timed out.",
+}
+
+// ClientClosedRequest is a standard error response for requests that were
+// cancelled by the client.
+var ClientClosedRequest = &Error{
+	StatusCode: StatusClientClosedRequest,
+	ErrCode:    riposo.ErrCodeUndefined,
+	Text:       "Client Closed Request",
+	Message:    "Client closed the request.",
+}
+
 // InvalidResource generates a specific resource not found Error.
 func InvalidResource(path riposo.Path) *Error {
 	return &Error{
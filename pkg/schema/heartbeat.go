@@ -2,9 +2,20 @@ package schema
 
 // Heartbeat response object.
 type Heartbeat struct {
-	Storage    bool `json:"storage"`
-	Permission bool `json:"permission"`
-	Cache      bool `json:"cache"`
+	Storage    bool                  `json:"storage"`
+	Permission bool                  `json:"permission"`
+	Cache      bool                  `json:"cache"`
+	Pools      map[string]*PoolStats `json:"pools,omitempty"`
+}
+
+// PoolStats contains connection pool statistics.
+type PoolStats struct {
+	MaxOpen      int   `json:"max_open"`
+	Open         int   `json:"open"`
+	InUse        int   `json:"in_use"`
+	Idle         int   `json:"idle"`
+	WaitCount    int64 `json:"wait_count"`
+	WaitDuration int64 `json:"wait_duration_ms"`
 }
 
 // Hello response object.
@@ -21,3 +32,8 @@ type Hello struct {
 	} `json:"settings"`
 	Capabilities interface{} `json:"capabilities"`
 }
+
+// Purged response object.
+type Purged struct {
+	Purged int64 `json:"purged"`
+}
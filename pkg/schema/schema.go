@@ -49,6 +49,12 @@ type Objects struct {
 	Data []*Object `json:"data"`
 }
 
+// Aggregates contains aggregation results. Each entry contains the values of
+// the grouped fields and the aggregated value, keyed by function name.
+type Aggregates struct {
+	Data []map[string]Value `json:"data"`
+}
+
 // Resource contain a combination of object and permissions.
 type Resource struct {
 	StatusCode  int           `json:"-"`
@@ -0,0 +1,142 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+)
+
+// Canonicalize returns the canonical JSON serialization of a collection, which
+// consists of its live objects, sorted by ID, and its last modified epoch:
+//
+//	{"data":[{"id":"a","last_modified":1,...}],"last_modified":"1"}
+//
+// Object keys are sorted, insignificant whitespace is removed, strings are
+// re-encoded and numbers are formatted in their shortest form, so the output
+// does not depend on the way objects were stored by the backend.
+func Canonicalize(objs []*schema.Object, modTime riposo.Epoch) ([]byte, error) {
+	live := make([]*schema.Object, 0, len(objs))
+	for _, obj := range objs {
+		if !obj.Deleted {
+			live = append(live, obj)
+		}
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].ID < live[j].ID })
+
+	buf := new(bytes.Buffer)
+	buf.WriteString(`{"data":[`)
+	for i, obj := range live {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+
+		raw, err := obj.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		if err := writeCanonical(buf, v); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteString(`],"last_modified":`)
+	if err := writeString(buf, strconv.FormatInt(int64(modTime), 10)); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, v interface{}) error {
+	switch vv := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(vv))
+	case json.Number:
+		f, err := vv.Float64()
+		if err != nil {
+			return err
+		}
+		buf.WriteString(formatNumber(f))
+	case string:
+		return writeString(buf, vv)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, x := range vv {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, x); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(vv))
+		for key := range vv {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeString(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, vv[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	}
+	return nil
+}
+
+func writeString(buf *bytes.Buffer, s string) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	buf.Truncate(buf.Len() - 1) // strip trailing newline
+	return nil
+}
+
+// formatNumber formats numbers like ECMAScript's Number.prototype.toString.
+func formatNumber(f float64) string {
+	if f == 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return "0"
+	}
+
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	// use exponent notation without leading zeros, e.g. 1e-7, 1.5e+21
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+	if pos := strings.IndexByte(s, 'e'); pos > -1 {
+		mant, exp := s[:pos], s[pos+1:]
+		sign := exp[:1]
+		exp = strings.TrimLeft(exp[1:], "0")
+		s = mant + "e" + sign + exp
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+package signer_test
+
+import (
+	"github.com/riposo/riposo/pkg/schema"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/signer"
+)
+
+var _ = Describe("Canonicalize", func() {
+	It("serializes collections", func() {
+		Expect(Canonicalize(nil, 1515151515678)).To(Equal([]byte(`{"data":[],"last_modified":"1515151515678"}`)))
+
+		Expect(Canonicalize([]*schema.Object{
+			{ID: "b", ModTime: 1515151515677, Extra: []byte(`{"z": 1.0, "a": "é<>", "n": null, "sub": {"y": [1e2, 0.5, 1e-7, 1.5e21], "x": true}}`)},
+			{ID: "c", ModTime: 1515151515678, Deleted: true},
+			{ID: "a", ModTime: 1515151515676},
+		}, 1515151515678)).To(Equal([]byte(
+			`{"data":[` +
+				`{"id":"a","last_modified":1515151515676},` +
+				`{"a":"é<>","id":"b","last_modified":1515151515677,"n":null,"sub":{"x":true,"y":[100,0.5,1e-7,1.5e+21]},"z":1}` +
+				`],"last_modified":"1515151515678"}`,
+		)))
+	})
+
+	It("is independent of the stored representation", func() {
+		a, err := Canonicalize([]*schema.Object{
+			{ID: "x", ModTime: 1, Extra: []byte(`{"b":1.0,"a":"é","c":{"e":2e0,"d":[]}}`)},
+		}, 1)
+		Expect(err).NotTo(HaveOccurred())
+
+		b, err := Canonicalize([]*schema.Object{
+			{ID: "x", ModTime: 1, Extra: []byte(`{"a": "é", "c": {"d": [], "e": 2}, "b": 1}`)},
+		}, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a).To(Equal(b))
+	})
+})
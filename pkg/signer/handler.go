@@ -0,0 +1,156 @@
+package signer
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+)
+
+const (
+	// Pattern is the route pattern of the collection signing endpoint.
+	Pattern = "/buckets/{bucket_id}/collections/{collection_id}/signature"
+	// ChainPattern is the route pattern of the certificate chain endpoint.
+	ChainPattern = "/__signer__/x5u"
+)
+
+// Install installs signer routes and callbacks. Collections matching any of
+// the given patterns are re-signed once per transaction, before changes to
+// their records are committed. Install must be called before record resources
+// are registered.
+func Install(rts *api.Routes, signer *Signer, patterns []string) {
+	h := &handler{rts: rts, signer: signer}
+	tags := []string{"signer"}
+
+	if len(patterns) != 0 {
+		rts.Callbacks(&callbacks{signer: signer, patterns: patterns})
+	}
+	rts.Method(http.MethodPost, Pattern, api.HandlerFunc(h.Sign))
+	rts.Method(http.MethodGet, ChainPattern, http.HandlerFunc(h.Chain))
+	rts.Describe(http.MethodPost, Pattern, &api.Operation{Summary: "Sign a collection", Tags: tags})
+	rts.Describe(http.MethodGet, ChainPattern, &api.Operation{Summary: "Retrieve the signer certificate chain", Tags: tags})
+}
+
+type handler struct {
+	rts    *api.Routes
+	signer *Signer
+}
+
+func (h *handler) Sign(_ http.Header, r *http.Request) interface{} {
+	txn := api.GetTxn(r)
+	path := riposo.NormPath(strings.TrimSuffix(r.URL.Path, "/"+Field))
+
+	// ensure user has write permission
+	if err := h.rts.CheckPermission(txn, path, "write"); err != nil {
+		return err
+	}
+
+	sig, err := h.signer.SignCollection(txn, path)
+	if errors.Is(err, storage.ErrNotFound) {
+		return schema.InvalidResource(path)
+	} else if err != nil {
+		return err
+	}
+	return &struct {
+		Data *Signature `json:"data"`
+	}{Data: sig}
+}
+
+func (h *handler) Chain(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(h.signer.Chain())
+}
+
+// --------------------------------------------------------------------
+
+type callbacks struct {
+	signer   *Signer
+	patterns []string
+}
+
+func (c *callbacks) OnGet(_ *api.Txn, _ riposo.Path) api.GetCallback {
+	return nil
+}
+
+func (c *callbacks) OnList(_ *api.Txn, _ riposo.Path) api.ListCallback {
+	return nil
+}
+
+func (c *callbacks) OnCreate(txn *api.Txn, path riposo.Path) api.CreateCallback {
+	return c.callback(txn, path)
+}
+
+func (c *callbacks) OnUpdate(txn *api.Txn, path riposo.Path) api.UpdateCallback {
+	return c.callback(txn, path)
+}
+
+func (c *callbacks) OnPatch(txn *api.Txn, path riposo.Path) api.PatchCallback {
+	return c.callback(txn, path)
+}
+
+func (c *callbacks) OnDelete(txn *api.Txn, path riposo.Path) api.DeleteCallback {
+	return c.callback(txn, path)
+}
+
+func (c *callbacks) OnDeleteAll(txn *api.Txn, path riposo.Path) api.DeleteAllCallback {
+	return c.callback(txn, path)
+}
+
+// recordCallback combines all record callbacks.
+type recordCallback interface {
+	api.CreateCallback
+	api.UpdateCallback
+	api.PatchCallback
+	api.DeleteCallback
+	api.DeleteAllCallback
+}
+
+func (c *callbacks) callback(txn *api.Txn, path riposo.Path) recordCallback {
+	if path.ResourceName() != "record" {
+		return nil
+	}
+
+	collPath := path.Parent()
+	if !collPath.Match(c.patterns...) {
+		return nil
+	}
+	return &signCallback{txn: txn, signer: c.signer, path: collPath}
+}
+
+type signCallback struct {
+	txn    *api.Txn
+	signer *Signer
+	path   riposo.Path
+}
+
+func (c *signCallback) BeforeCreate(_ *schema.Resource) error                   { return nil }
+func (c *signCallback) BeforeUpdate(_ *schema.Object, _ *schema.Resource) error { return nil }
+func (c *signCallback) BeforePatch(_ *schema.Object, _ *schema.Resource) error  { return nil }
+func (c *signCallback) BeforeDelete(_ *schema.Object) error                     { return nil }
+func (c *signCallback) BeforeDeleteAll(_ []*schema.Object) error                { return nil }
+
+func (c *signCallback) AfterCreate(_ *schema.Resource) error                 { return c.schedule() }
+func (c *signCallback) AfterUpdate(_ *schema.Resource) error                 { return c.schedule() }
+func (c *signCallback) AfterPatch(_ *schema.Resource) error                  { return c.schedule() }
+func (c *signCallback) AfterDelete(_ *schema.Object) error                   { return c.schedule() }
+func (c *signCallback) AfterDeleteAll(_ riposo.Epoch, _ []riposo.Path) error { return c.schedule() }
+
+// schedule signs the collection once, before the transaction is committed.
+func (c *signCallback) schedule() error {
+	key := "signer:" + c.path.String()
+	if _, ok := c.txn.Data[key]; ok {
+		return nil
+	}
+	c.txn.Data[key] = true
+
+	c.txn.OnBeforeCommit(func() error {
+		_, err := c.signer.SignCollection(c.txn, c.path)
+		return err
+	})
+	return nil
+}
@@ -0,0 +1,164 @@
+package signer_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/mock"
+	"github.com/riposo/riposo/pkg/riposo"
+	"github.com/riposo/riposo/pkg/schema"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/signer"
+)
+
+var _ = Describe("Install", func() {
+	var rts *api.Routes
+	var txn *api.Txn
+	var key *ecdsa.PrivateKey
+	var cns *conn.Set
+	var hlp riposo.Helpers
+
+	begin := func() {
+		var err error
+		txn, err = api.NewTxn(context.Background(), cns, hlp, nil)
+		Expect(err).NotTo(HaveOccurred())
+		txn.User = mock.User("account:alice")
+	}
+
+	commit := func() {
+		Expect(txn.Commit()).To(Succeed())
+		begin()
+	}
+
+	handle := func(method, path, payload string) *httptest.ResponseRecorder {
+		var req *http.Request
+		if payload != "" {
+			req = mock.Request(txn, method, path, strings.NewReader(payload))
+		} else {
+			req = mock.Request(txn, method, path, nil)
+		}
+
+		w := httptest.NewRecorder()
+		rts.Mux().ServeHTTP(w, req)
+		return w
+	}
+
+	signature := func(path string) *Signature {
+		obj, err := txn.Store.Get(riposo.Path(path), false)
+		Expect(err).NotTo(HaveOccurred())
+
+		val := obj.Get(Field)
+		if !val.Exists() {
+			return nil
+		}
+
+		var sig *Signature
+		Expect(json.Unmarshal([]byte(val.Raw), &sig)).To(Succeed())
+		return sig
+	}
+
+	verify := func(path string) bool {
+		sig := signature(path)
+		if sig == nil {
+			return false
+		}
+
+		recordsPath := riposo.Path(path + "/records/*")
+		modTime, err := txn.Store.ModTime(recordsPath)
+		Expect(err).NotTo(HaveOccurred())
+		objs, err := txn.Store.ListAll(recordsPath, storage.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		payload, err := Canonicalize(objs, modTime)
+		Expect(err).NotTo(HaveOccurred())
+		return Verify(&key.PublicKey, payload, sig)
+	}
+
+	BeforeEach(func() {
+		var err error
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		sgn, err := New(key, []byte("CHAIN"), "/v1/__signer__/x5u")
+		Expect(err).NotTo(HaveOccurred())
+
+		hlp = mock.Helpers()
+		cns = mock.Conns(hlp)
+		begin()
+
+		Expect(txn.Store.Create("/buckets/b/collections/*", &schema.Object{ID: "main"})).To(Succeed())
+		Expect(txn.Store.Create("/buckets/b/collections/*", &schema.Object{ID: "other"})).To(Succeed())
+		Expect(txn.Perms.CreatePermissions("/buckets/b", schema.PermissionSet{"write": {"account:alice"}})).To(Succeed())
+
+		rts = api.NewRoutes(nil)
+		Install(rts, sgn, []string{"/buckets/b/collections/main"})
+		rts.Resource("/buckets/{bucket_id}/collections/{collection_id}/records", nil)
+	})
+
+	AfterEach(func() {
+		Expect(txn.Rollback()).To(Succeed())
+	})
+
+	It("signs collections on request", func() {
+		Expect(handle(http.MethodPut, "/buckets/b/collections/other/records/r", `{"data":{"n":1}}`).Code).To(Equal(http.StatusCreated))
+		Expect(signature("/buckets/b/collections/other")).To(BeNil())
+
+		w := handle(http.MethodPost, "/buckets/b/collections/other/signature", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring(`"mode":"p384ecdsa"`))
+		Expect(verify("/buckets/b/collections/other")).To(BeTrue())
+
+		Expect(handle(http.MethodPost, "/buckets/b/collections/missing/signature", "").Code).To(Equal(http.StatusNotFound))
+
+		txn.User = mock.User("account:bob")
+		Expect(handle(http.MethodPost, "/buckets/b/collections/other/signature", "").Code).To(Equal(http.StatusForbidden))
+	})
+
+	It("re-signs collections after changes", func() {
+		Expect(handle(http.MethodPut, "/buckets/b/collections/main/records/r", `{"data":{"n":1}}`).Code).To(Equal(http.StatusCreated))
+		commit()
+		Expect(verify("/buckets/b/collections/main")).To(BeTrue())
+
+		Expect(handle(http.MethodPatch, "/buckets/b/collections/main/records/r", `{"data":{"n":2}}`).Code).To(Equal(http.StatusOK))
+		commit()
+		Expect(verify("/buckets/b/collections/main")).To(BeTrue())
+
+		Expect(handle(http.MethodPost, "/buckets/b/collections/main/records", `{"data":{"n":3}}`).Code).To(Equal(http.StatusCreated))
+		commit()
+		Expect(verify("/buckets/b/collections/main")).To(BeTrue())
+
+		Expect(handle(http.MethodDelete, "/buckets/b/collections/main/records/r", "").Code).To(Equal(http.StatusOK))
+		commit()
+		Expect(verify("/buckets/b/collections/main")).To(BeTrue())
+
+		Expect(handle(http.MethodDelete, "/buckets/b/collections/main/records", "").Code).To(Equal(http.StatusOK))
+		commit()
+		Expect(verify("/buckets/b/collections/main")).To(BeTrue())
+	})
+
+	It("signs collections once per transaction", func() {
+		Expect(handle(http.MethodPut, "/buckets/b/collections/main/records/r", `{"data":{"n":1}}`).Code).To(Equal(http.StatusCreated))
+		Expect(handle(http.MethodPost, "/buckets/b/collections/main/records", `{"data":{"n":2}}`).Code).To(Equal(http.StatusCreated))
+		Expect(signature("/buckets/b/collections/main")).To(BeNil())
+		Expect(txn.Data).To(HaveLen(1))
+
+		commit()
+		Expect(verify("/buckets/b/collections/main")).To(BeTrue())
+	})
+
+	It("serves the certificate chain", func() {
+		w := handle(http.MethodGet, "/__signer__/x5u", "")
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(Equal("CHAIN"))
+	})
+})
@@ -0,0 +1,176 @@
+// Package signer implements content signatures for collections.
+package signer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/riposo/riposo/pkg/api"
+	"github.com/riposo/riposo/pkg/conn/storage"
+	"github.com/riposo/riposo/pkg/params"
+	"github.com/riposo/riposo/pkg/riposo"
+)
+
+// Field is the collection field which holds the signature.
+const Field = "signature"
+
+// signaturePrefix is prepended to the payload before signing, as expected by
+// content signature verifiers.
+const signaturePrefix = "Content-Signature:\x00"
+
+// Signature contains signature metadata.
+type Signature struct {
+	Signature         string `json:"signature"`
+	HashAlgorithm     string `json:"hash_algorithm"`
+	SignatureEncoding string `json:"signature_encoding"`
+	Mode              string `json:"mode"`
+	X5U               string `json:"x5u,omitempty"`
+}
+
+// Signer signs collections using an ECDSA P-384 key.
+type Signer struct {
+	key   *ecdsa.PrivateKey
+	chain []byte
+	x5u   string
+}
+
+// New inits a new signer with a private key, a PEM-encoded certificate chain
+// and the public URL of the chain.
+func New(key *ecdsa.PrivateKey, chain []byte, x5u string) (*Signer, error) {
+	if key.Curve != elliptic.P384() {
+		return nil, errors.New("signer key must use the P-384 curve")
+	}
+	return &Signer{key: key, chain: chain, x5u: x5u}, nil
+}
+
+// Load inits a new signer from a PEM-encoded private key file and a
+// PEM-encoded certificate chain file.
+func Load(keyFile, chainFile, x5u string) (*Signer, error) {
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read signer key: %w", err)
+	}
+
+	key, err := parseKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	chain, err := os.ReadFile(chainFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read signer certificate chain: %w", err)
+	}
+
+	return New(key, chain, x5u)
+}
+
+// Chain returns the PEM-encoded certificate chain.
+func (s *Signer) Chain() []byte {
+	return s.chain
+}
+
+// Sign signs a payload.
+func (s *Signer) Sign(payload []byte) (*Signature, error) {
+	digest := sha512.Sum384(append([]byte(signaturePrefix), payload...))
+	r, ss, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// encode as fixed-size r || s
+	sig := make([]byte, 96)
+	r.FillBytes(sig[:48])
+	ss.FillBytes(sig[48:])
+
+	return &Signature{
+		Signature:         base64.URLEncoding.EncodeToString(sig),
+		HashAlgorithm:     "sha384",
+		SignatureEncoding: "rs_base64url",
+		Mode:              "p384ecdsa",
+		X5U:               s.x5u,
+	}, nil
+}
+
+// Verify verifies a signature.
+func Verify(pub *ecdsa.PublicKey, payload []byte, sig *Signature) bool {
+	raw, err := base64.URLEncoding.DecodeString(sig.Signature)
+	if err != nil || len(raw) != 96 {
+		return false
+	}
+
+	digest := sha512.Sum384(append([]byte(signaturePrefix), payload...))
+	r := new(big.Int).SetBytes(raw[:48])
+	s := new(big.Int).SetBytes(raw[48:])
+	return ecdsa.Verify(pub, digest[:], r, s)
+}
+
+// SignCollection signs the live records of a collection and stores the
+// signature in the collection metadata.
+func (s *Signer) SignCollection(txn *api.Txn, path riposo.Path) (*Signature, error) {
+	coll, err := txn.Store.Get(path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	recordsPath := path + "/records/*"
+	modTime, err := txn.Store.ModTime(recordsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	objs, err := txn.Store.ListAll(recordsPath, storage.ListOptions{
+		Sort: []params.SortOrder{{Field: "id"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := Canonicalize(objs, modTime)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := s.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := coll.Set(Field, sig); err != nil {
+		return nil, err
+	}
+	if err := txn.Store.Update(path, coll); err != nil {
+		return nil, err
+	}
+	return sig, nil
+}
+
+func parseKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signer key is not PEM-encoded")
+	}
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if ecKey, ok := key.(*ecdsa.PrivateKey); ok {
+			return ecKey, nil
+		}
+		return nil, errors.New("signer key is not an ECDSA key")
+	}
+	return nil, fmt.Errorf("signer key has unsupported type %q", block.Type)
+}
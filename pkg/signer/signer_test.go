@@ -0,0 +1,78 @@
+package signer_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/bsm/ginkgo/v2"
+	. "github.com/bsm/gomega"
+	. "github.com/riposo/riposo/pkg/signer"
+)
+
+var _ = Describe("Signer", func() {
+	var subject *Signer
+	var key *ecdsa.PrivateKey
+
+	BeforeEach(func() {
+		var err error
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		subject, err = New(key, []byte("CHAIN"), "https://example.com/x5u")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("signs payloads", func() {
+		sig, err := subject.Sign([]byte(`{"data":[],"last_modified":"1"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sig.Signature).To(HaveLen(128))
+		Expect(sig.HashAlgorithm).To(Equal("sha384"))
+		Expect(sig.SignatureEncoding).To(Equal("rs_base64url"))
+		Expect(sig.Mode).To(Equal("p384ecdsa"))
+		Expect(sig.X5U).To(Equal("https://example.com/x5u"))
+
+		Expect(Verify(&key.PublicKey, []byte(`{"data":[],"last_modified":"1"}`), sig)).To(BeTrue())
+		Expect(Verify(&key.PublicKey, []byte(`{"data":[],"last_modified":"2"}`), sig)).To(BeFalse())
+	})
+
+	It("rejects other curves", func() {
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = New(other, nil, "")
+		Expect(err).To(MatchError("signer key must use the P-384 curve"))
+	})
+
+	It("loads keys from files", func() {
+		dir := GinkgoT().TempDir()
+		der, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+
+		keyFile := filepath.Join(dir, "key.pem")
+		chainFile := filepath.Join(dir, "chain.pem")
+		Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600)).To(Succeed())
+		Expect(os.WriteFile(chainFile, []byte("CHAIN"), 0o600)).To(Succeed())
+
+		loaded, err := Load(keyFile, chainFile, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Chain()).To(Equal([]byte("CHAIN")))
+
+		sig, err := loaded.Sign([]byte("x"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Verify(&key.PublicKey, []byte("x"), sig)).To(BeTrue())
+
+		_, err = Load(chainFile, chainFile, "")
+		Expect(err).To(MatchError("signer key is not PEM-encoded"))
+	})
+})
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "pkg/signer")
+}